@@ -25,7 +25,7 @@ import (
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"urulink.com/models"
+	"urulink.com/auth_service/models"
 )
 
 // Database struct to hold the GORM DB instance for database operations.
@@ -19,10 +19,7 @@
 
 package env
 
-import (
-	"log"
-	"os"
-)
+import "urulink.com/platform/config"
 
 // EnvManger holds the environment variables required for application configuration.
 type EnvManger struct {
@@ -39,23 +36,13 @@ type EnvManger struct {
 func NewEnv() *EnvManger {
 	env := &EnvManger{}
 
-	// Helper function to load a single environment variable.
-	// If the variable is not found, logs a fatal error and terminates the program.
-	loadEnv := func(envVar string, target *string) {
-		value, ok := os.LookupEnv(envVar)
-		if !ok {
-			log.Fatalf("Environment variable %s not found", envVar) // Log an error if envVar is missing.
-		}
-		*target = value // Set the value of the environment variable to the target field.
-	}
-
 	// Load each required environment variable into the EnvManger fields.
-	loadEnv("ACCESS_TOKEN_KEY", &env.AccessTokenKey)
-	loadEnv("DB_HOST", &env.DBHost)
-	loadEnv("DB_USER", &env.DBUser)
-	loadEnv("DB_PASSWORD", &env.DBPassword)
-	loadEnv("DB_NAME", &env.DBName)
-	loadEnv("DB_PORT", &env.DBPort)
+	config.Required("ACCESS_TOKEN_KEY", &env.AccessTokenKey)
+	config.Required("DB_HOST", &env.DBHost)
+	config.Required("DB_USER", &env.DBUser)
+	config.Required("DB_PASSWORD", &env.DBPassword)
+	config.Required("DB_NAME", &env.DBName)
+	config.Required("DB_PORT", &env.DBPort)
 
 	return env // Return the populated EnvManger instance.
 }
@@ -23,9 +23,10 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"urulink.com/helper"
-	"urulink.com/models"
-	"urulink.com/response"
+	"urulink.com/auth_service/helper"
+	"urulink.com/auth_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
 )
 
 // Register handles user registration by validating input, checking username availability,
@@ -34,27 +35,27 @@ func (h Handler) Register(c *fiber.Ctx) error {
 	var userInfoInput models.UsersInfoInput
 	// Parse JSON request body into userInfoInput struct
 	if err := c.BodyParser(&userInfoInput); err != nil {
-		helper.LogError(c, "Failed to parse request body in Register", err)
+		logs.Error(c, "Failed to parse request body in Register", err)
 		return c.SendStatus(400) // Bad Request if parsing fails
 	}
 
 	// Check if the username already exists in the database
 	id, err := h.Database.CheckUsername(userInfoInput.Username)
 	if err != nil {
-		helper.LogError(c, "Failed to check username in database", err)
+		logs.Error(c, "Failed to check username in database", err)
 		return c.SendStatus(500) // Internal Server Error if database check fails
 	}
 
 	if id != 0 {
 		// Username is already taken, return a 403 Forbidden status
-		helper.LogInfo(c, "Username already exists", map[string]interface{}{"username": userInfoInput.Username})
+		logs.Info(c, "Username already exists", map[string]interface{}{"username": userInfoInput.Username})
 		return c.SendStatus(403)
 	}
 
 	// Hash the user's password before storing it
 	password, err := helper.HashPassword(userInfoInput.Password)
 	if err != nil {
-		helper.LogError(c, "Failed to hash password", err)
+		logs.Error(c, "Failed to hash password", err)
 		return c.SendStatus(500)
 	}
 
@@ -68,12 +69,12 @@ func (h Handler) Register(c *fiber.Ctx) error {
 
 	// Attempt to create the new user in the database
 	if err = h.Database.CreateNewUser(userInfo); err != nil {
-		helper.LogError(c, "Failed to create new user in database", err)
+		logs.Error(c, "Failed to create new user in database", err)
 		return c.SendStatus(500)
 	}
 
 	// Log success and return 200 OK status
-	helper.LogInfo(c, "User registered successfully", map[string]interface{}{"username": userInfo.Username})
+	logs.Info(c, "User registered successfully", map[string]interface{}{"username": userInfo.Username})
 	return c.SendStatus(200)
 }
 
@@ -82,19 +83,19 @@ func (h Handler) Login(c *fiber.Ctx) error {
 	var userLoginInfo models.UserLoginInfo
 	// Parse JSON request body into userLoginInfo struct
 	if err := c.BodyParser(&userLoginInfo); err != nil {
-		helper.LogError(c, "Failed to parse request body in Login", err)
+		logs.Error(c, "Failed to parse request body in Login", err)
 		return c.SendStatus(400) // Bad Request if parsing fails
 	}
 
 	// Retrieve user info from the database by username
 	userInfo, err := h.Database.GetUserInfoByUsername(userLoginInfo.Username)
 	if err != nil {
-		helper.LogError(c, "Failed to get user info from database", err)
+		logs.Error(c, "Failed to get user info from database", err)
 		return c.SendStatus(500) // Internal Server Error if retrieval fails
 	}
 	if userInfo.Id == 0 {
 		// Username not found, return 403 Forbidden status
-		helper.LogInfo(c, "User not found", map[string]interface{}{"username": userLoginInfo.Username})
+		logs.Info(c, "User not found", map[string]interface{}{"username": userLoginInfo.Username})
 		return c.SendStatus(403)
 	}
 
@@ -102,19 +103,19 @@ func (h Handler) Login(c *fiber.Ctx) error {
 	hashPasswordCheck := helper.CheckPassword(userLoginInfo.Password, userInfo.Password)
 	if !hashPasswordCheck {
 		// Invalid password, return 403 Forbidden status
-		helper.LogInfo(c, "Invalid password", map[string]interface{}{"username": userLoginInfo.Username})
+		logs.Info(c, "Invalid password", map[string]interface{}{"username": userLoginInfo.Username})
 		return c.SendStatus(403)
 	}
 
 	// Generate JWT for the user upon successful login
 	urufiAccessToken, err := h.JWT.CreateUsersJwt(userInfo.Uid, userInfo.Username)
 	if err != nil {
-		helper.LogError(c, "Failed to create JWT", err)
+		logs.Error(c, "Failed to create JWT", err)
 		return c.SendStatus(403) // Return 403 if JWT generation fails
 	}
 
 	// Log success and return the generated JWT
-	helper.LogInfo(c, "User logged in successfully", map[string]interface{}{"username": userInfo.Username})
+	logs.Info(c, "User logged in successfully", map[string]interface{}{"username": userInfo.Username})
 	return response.HandleInformation(c, 200, urufiAccessToken)
 }
 
@@ -128,7 +129,7 @@ func (h Handler) CheckLogin(c *fiber.Ctx) error {
 	uid, username, err := h.JWT.CheckAccessToken(accessToken)
 	if err != nil || uid == "" || username == "" {
 		// Invalid or expired token, return 401 Unauthorized status
-		helper.LogError(c, "Invalid access token", err)
+		logs.Error(c, "Invalid access token", err)
 		return c.SendStatus(401)
 	}
 
@@ -139,6 +140,6 @@ func (h Handler) CheckLogin(c *fiber.Ctx) error {
 	}
 
 	// Log success and return the user info
-	helper.LogInfo(c, "User access token validated", map[string]interface{}{"uid": uid})
+	logs.Info(c, "User access token validated", map[string]interface{}{"uid": uid})
 	return response.HandleInformation(c, 200, jwtUserInfo)
 }
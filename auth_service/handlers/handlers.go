@@ -22,9 +22,9 @@ package handlers
 import (
 	"fmt"
 
-	"urulink.com/db"
-	"urulink.com/env"
-	"urulink.com/jwt"
+	"urulink.com/auth_service/db"
+	"urulink.com/auth_service/env"
+	"urulink.com/auth_service/jwt"
 )
 
 // Handler struct aggregates dependencies including Database, JWT manager, and environment variables manager
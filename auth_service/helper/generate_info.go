@@ -19,34 +19,10 @@
 
 package helper
 
-import (
-	"math/rand"
-	"time"
-)
-
-// Define the set of characters to be used for generating numeric strings
-const (
-	number = "0123456789"
-)
-
-// generateRandomString creates a random string of specified length from the provided character set
-func generateRandomString(chars string, length int) string {
-	// Seed the random generator with current time to ensure randomness
-	source := rand.NewSource(time.Now().UnixNano())
-	randomGenerator := rand.New(source)
-
-	// Initialize the result string
-	var result string
-	for i := 0; i < length; i++ {
-		// Select a random index from the character set and append it to the result
-		index := randomGenerator.Intn(len(chars))
-		result += string(chars[index])
-	}
-	return result
-}
+import "urulink.com/platform/ids"
 
 // GenerateUid generates a unique identifier of fixed length using numeric characters
 func GenerateUid() string {
 	const uidLength = 10 // Define the length of the UID
-	return generateRandomString(number, uidLength)
+	return ids.New(ids.Digits, uidLength)
 }
@@ -24,7 +24,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
-	"urulink.com/env"
+	"urulink.com/auth_service/env"
 )
 
 // JWTManager is responsible for managing JWT creation and verification.
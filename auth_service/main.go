@@ -23,11 +23,15 @@ import (
 	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"urulink.com/routes"
+	"urulink.com/auth_service/routes"
+	"urulink.com/platform/logs"
 )
 
 // main function is the entry point of the application.
 func main() {
+	// Write the log to service.log as well as stdout.
+	logs.Setup("service.log")
+
 	// Create a new Fiber application instance.
 	app := fiber.New()
 
@@ -19,10 +19,10 @@
 
 package models
 
-type ClientsLoginResponse struct {
-	Uid      string `json:"uid"`
-	Username string `json:"username"`
-}
+import "urulink.com/platform/auth"
+
+// ClientsLoginResponse is the user an access token belongs to, as reported by /check-login
+type ClientsLoginResponse = auth.User
 
 type UserLoginInfo struct {
 	Username string `json:"username"`
@@ -21,7 +21,7 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"urulink.com/handlers"
+	"urulink.com/auth_service/handlers"
 )
 
 func SetRoutes(app *fiber.App) {
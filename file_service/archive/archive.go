@@ -0,0 +1,182 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/models"
+)
+
+// Errors returned for archives that are not stored
+var (
+	ErrInvalid = errors.New("file is not a valid zip archive")
+	ErrBomb    = errors.New("archive expands beyond the allowed limits")
+	ErrDenied  = errors.New("archive contains a file type that is not allowed")
+	ErrNested  = errors.New("archive contains another archive")
+)
+
+// Listing settings
+const (
+	MaxListedEntries = 1000    // Entries kept in the listing of an archive
+	minRatioSize     = 1 << 20 // Smaller entries are not checked against the compression ratio
+	sniffLength      = 512     // Leading bytes of an entry checked for the magic number of an archive
+)
+
+// Extensions of archive and compressed formats, refused inside archives as their contents could not
+// be checked against the limits
+var nestedExtensions = []string{
+	".zip", ".jar", ".war", ".apk", ".7z", ".rar", ".tar", ".gz", ".tgz", ".bz2", ".tbz2", ".xz", ".txz",
+	".zst", ".lz", ".lzma", ".z", ".cab", ".iso",
+}
+
+// Magic numbers of archive and compressed formats, to find nested archives whatever their name
+var nestedMagics = [][]byte{
+	[]byte("PK\x03\x04"),               // zip
+	[]byte("PK\x05\x06"),               // empty zip
+	{0x1F, 0x8B},                       // gzip
+	{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, // 7-Zip
+	[]byte("Rar!\x1A\x07"),             // RAR
+	[]byte("BZh"),                      // bzip2
+	{0xFD, '7', 'z', 'X', 'Z', 0x00},   // xz
+	{0x28, 0xB5, 0x2F, 0xFD},           // zstd
+	[]byte("MSCF"),                     // cabinet
+}
+
+// tarMagicOffset is the offset of the "ustar" magic in the header of a tar entry
+const tarMagicOffset = 257
+
+// Limits bounds what an archive may expand to
+type Limits struct {
+	MaxEntries       int      `json:"max_entries"`       // Files and directories in an archive
+	MaxTotalSize     int64    `json:"max_total_size"`    // Uncompressed size of all entries in bytes
+	MaxRatio         int      `json:"max_ratio"`         // Largest uncompressed to compressed size ratio of an entry
+	DeniedExtensions []string `json:"denied_extensions"` // Extensions of entries refused, e.g. executables
+}
+
+// Supported reports whether files of a sniffed MIME type are inspected as archives
+func Supported(sniffedMime string) bool {
+	return sniffedMime == "application/zip"
+}
+
+// Inspect lists the entries of a zip archive from its central directory and checks them against
+// the limits. The sizes are the declared ones: zip readers, including the malware scanner, refuse
+// entries expanding beyond them. Archives nested in the archive are refused.
+func Inspect(r io.ReaderAt, size int64, limits Limits) (models.ArchiveListing, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return models.ArchiveListing{}, invalid(err)
+	}
+	if len(reader.File) > limits.MaxEntries {
+		return models.ArchiveListing{}, fmt.Errorf("%w: more than %d entries", ErrBomb, limits.MaxEntries)
+	}
+
+	listing := models.ArchiveListing{EntryCount: len(reader.File)}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if slices.Contains(limits.DeniedExtensions, helper.FileExt(file.Name)) {
+			return models.ArchiveListing{}, fmt.Errorf("%w: %s", ErrDenied, file.Name)
+		}
+
+		entry := models.ArchiveEntry{
+			Name:           file.Name,
+			Size:           int64(file.UncompressedSize64),
+			CompressedSize: int64(file.CompressedSize64),
+			Encrypted:      file.Flags&0x1 != 0,
+		}
+		if entry.Size < 0 || entry.CompressedSize < 0 {
+			return models.ArchiveListing{}, ErrInvalid
+		}
+		if entry.CompressedSize > 0 {
+			entry.Ratio = float64(entry.Size) / float64(entry.CompressedSize)
+		}
+		if entry.Size >= minRatioSize && (entry.CompressedSize == 0 || entry.Ratio > float64(limits.MaxRatio)) {
+			return models.ArchiveListing{}, fmt.Errorf("%w: %s is compressed more than %d times", ErrBomb, file.Name, limits.MaxRatio)
+		}
+
+		if nested, err := isArchive(file); err != nil {
+			return models.ArchiveListing{}, err
+		} else if nested {
+			return models.ArchiveListing{}, fmt.Errorf("%w: %s", ErrNested, file.Name)
+		}
+
+		listing.TotalSize += entry.Size
+		if listing.TotalSize > limits.MaxTotalSize || listing.TotalSize < 0 {
+			return models.ArchiveListing{}, fmt.Errorf("%w: more than %d bytes uncompressed", ErrBomb, limits.MaxTotalSize)
+		}
+		if len(listing.Entries) < MaxListedEntries {
+			listing.Entries = append(listing.Entries, entry)
+		} else {
+			listing.Truncated = true
+		}
+	}
+	return listing, nil
+}
+
+// isArchive reports whether an entry is an archive or compressed file, by its extension or by the
+// magic number at its start. Encrypted entries and compression methods the zip reader does not
+// support are judged by their extension only.
+func isArchive(file *zip.File) (bool, error) {
+	if slices.Contains(nestedExtensions, helper.FileExt(file.Name)) {
+		return true, nil
+	}
+	if file.Flags&0x1 != 0 {
+		return false, nil
+	}
+	entry, err := file.Open()
+	if errors.Is(err, zip.ErrAlgorithm) {
+		return false, nil
+	}
+	if err != nil {
+		return false, invalid(err)
+	}
+	defer entry.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(entry, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, invalid(err)
+	}
+	head = head[:n]
+	for _, magic := range nestedMagics {
+		if bytes.HasPrefix(head, magic) {
+			return true, nil
+		}
+	}
+	return len(head) >= tarMagicOffset+5 && bytes.Equal(head[tarMagicOffset:tarMagicOffset+5], []byte("ustar")), nil
+}
+
+// invalid maps the errors of zip readers on malformed archives to ErrInvalid
+func invalid(err error) error {
+	var corrupt flate.CorruptInputError
+	if errors.Is(err, zip.ErrFormat) || errors.Is(err, zip.ErrAlgorithm) || errors.Is(err, zip.ErrChecksum) || errors.As(err, &corrupt) {
+		return ErrInvalid
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var testLimits = Limits{MaxEntries: 10, MaxTotalSize: 4 << 20, MaxRatio: 100, DeniedExtensions: []string{".exe", ".sh"}}
+
+// testEntry is a file of a zip archive written by a test
+type testEntry struct {
+	name    string
+	content []byte
+	stored  bool // Stored without compression
+}
+
+func zipOf(t *testing.T, entries ...testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.stored {
+			header.Method = zip.Store
+		}
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(entry.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipOf(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(content)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarOf(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(content)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func manyEntries(n int) []testEntry {
+	entries := make([]testEntry, n)
+	for i := range entries {
+		entries[i] = testEntry{name: fmt.Sprintf("file%d.txt", i), content: []byte("x")}
+	}
+	return entries
+}
+
+func TestInspect(t *testing.T) {
+	valid := zipOf(t,
+		testEntry{name: "docs/"},
+		testEntry{name: "docs/readme.txt", content: []byte("hello archive")},
+		testEntry{name: "photo.jpg", content: bytes.Repeat([]byte{0xAB}, 1000), stored: true},
+	)
+	truncated := valid[:len(valid)-10]
+	corruptEntry := zipOf(t, testEntry{name: "data.bin", content: []byte("some data that gets corrupted")})
+	// Flip the first byte of the deflated data behind the local header (30 bytes) and the name
+	corruptEntry[30+len("data.bin")] ^= 0xFF
+
+	for _, test := range []struct {
+		name    string
+		archive []byte
+		err     error
+	}{
+		{"valid", valid, nil},
+		{"empty", zipOf(t), nil},
+		{"too many entries", zipOf(t, manyEntries(11)...), ErrBomb},
+		{"entries at the limit", zipOf(t, manyEntries(10)...), nil},
+		{"too large", zipOf(t, testEntry{name: "big.bin", content: bytes.Repeat([]byte("0123456789abcdef"), 5<<16), stored: true}), ErrBomb},
+		{"compressed too much", zipOf(t, testEntry{name: "zeros.bin", content: make([]byte, 2<<20)}), ErrBomb},
+		{"small entries are not checked for ratio", zipOf(t, testEntry{name: "zeros.bin", content: make([]byte, 64<<10)}), nil},
+		{"denied extension", zipOf(t, testEntry{name: "tools/setup.exe", content: []byte("MZ")}), ErrDenied},
+		{"denied extension in capitals", zipOf(t, testEntry{name: "RUN.SH", content: []byte("#!/bin/sh")}), ErrDenied},
+		{"nested by extension", zipOf(t, testEntry{name: "inner.ZIP", content: []byte("not really a zip")}), ErrNested},
+		{"nested zip renamed", zipOf(t, testEntry{name: "photo.jpg", content: valid}), ErrNested},
+		{"nested gzip", zipOf(t, testEntry{name: "notes.txt", content: gzipOf(t, []byte("hidden"))}), ErrNested},
+		{"nested tar", zipOf(t, testEntry{name: "notes.txt", content: tarOf(t, "hidden.exe", []byte("MZ"))}), ErrNested},
+		{"not a zip", []byte("plain text, not an archive"), ErrInvalid},
+		{"truncated", truncated, ErrInvalid},
+		{"corrupt entry", corruptEntry, ErrInvalid},
+	} {
+		_, err := Inspect(bytes.NewReader(test.archive), int64(len(test.archive)), testLimits)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: Inspect = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestInspectListing(t *testing.T) {
+	data := zipOf(t,
+		testEntry{name: "docs/"},
+		testEntry{name: "docs/readme.txt", content: bytes.Repeat([]byte("a"), 4000)},
+		testEntry{name: "photo.jpg", content: bytes.Repeat([]byte{0xAB}, 1000), stored: true},
+	)
+	listing, err := Inspect(bytes.NewReader(data), int64(len(data)), testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.EntryCount != 3 || len(listing.Entries) != 2 || listing.TotalSize != 5000 || listing.Truncated {
+		t.Fatalf("listing = %+v", listing)
+	}
+	readme, photo := listing.Entries[0], listing.Entries[1]
+	if readme.Name != "docs/readme.txt" || readme.Size != 4000 || readme.CompressedSize >= 4000 || readme.Ratio <= 1 {
+		t.Errorf("readme entry = %+v", readme)
+	}
+	if photo.Name != "photo.jpg" || photo.Size != 1000 || photo.CompressedSize != 1000 || photo.Ratio != 1 || photo.Encrypted {
+		t.Errorf("photo entry = %+v", photo)
+	}
+
+	// Only the first entries are listed; all of them count
+	limits := testLimits
+	limits.MaxEntries = MaxListedEntries + 1
+	data = zipOf(t, manyEntries(MaxListedEntries+1)...)
+	listing, err = Inspect(bytes.NewReader(data), int64(len(data)), limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Entries) != MaxListedEntries || !listing.Truncated || listing.EntryCount != MaxListedEntries+1 || listing.TotalSize != MaxListedEntries+1 {
+		t.Errorf("listing of %d entries has %d entries, truncated %v, count %d, size %d", MaxListedEntries+1, len(listing.Entries), listing.Truncated, listing.EntryCount, listing.TotalSize)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// WaveformBars is the number of amplitude values of a waveform
+const WaveformBars = 64
+
+// ErrUnsupported is returned by Analyze for MIME types it cannot read
+var ErrUnsupported = errors.New("unsupported audio format")
+
+// Info describes the playback of an audio file
+type Info struct {
+	DurationMs int64
+	Waveform   []byte // WaveformBars peak amplitudes scaled to 0-255, nil when the codec cannot be decoded
+}
+
+// Supported reports whether Analyze can read files of the sniffed MIME type
+func Supported(mimeType string) bool {
+	switch mimeType {
+	case "audio/wav", "audio/ogg", "audio/mpeg", "audio/aac", "audio/mp4":
+		return true
+	}
+	return false
+}
+
+// Analyze reads an audio file of the given sniffed MIME type and returns its duration. A waveform is
+// only computed for uncompressed WAV files. Compressed recordings deliberately get their duration
+// only: decoding Opus means implementing both of its SILK and CELT layers, Vorbis, MP3 and AAC are
+// similar undertakings, and there are no complete decoders in pure Go. Binding libopus instead
+// would need cgo, which the Alpine build image has no C toolchain for. The duration is read from
+// the container, which needs no decoding at all.
+func Analyze(r io.Reader, mimeType string) (Info, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	switch mimeType {
+	case "audio/wav":
+		return analyzeWAV(br)
+	case "audio/ogg":
+		return analyzeOgg(br)
+	case "audio/mpeg":
+		return analyzeFrames(br, mp3Frame)
+	case "audio/aac":
+		return analyzeFrames(br, adtsFrame)
+	case "audio/mp4":
+		return analyzeMP4(br)
+	}
+	return Info{}, ErrUnsupported
+}
+
+// waveform collects the peak amplitude of consecutive frames. The number of frames does not have
+// to be known in advance: the resolution is halved whenever too many segments were collected.
+type waveform struct {
+	peaks   []float64 // Peak amplitude of each completed segment of span frames
+	span    int64
+	current float64 // Peak amplitude of the segment being filled
+	count   int64   // Number of frames in the segment being filled
+}
+
+func newWaveform() *waveform {
+	return &waveform{span: 1}
+}
+
+// add records the amplitude of one frame, between 0 and 1
+func (w *waveform) add(amplitude float64) {
+	if amplitude > w.current {
+		w.current = amplitude
+	}
+	w.count++
+	if w.count < w.span {
+		return
+	}
+
+	w.peaks = append(w.peaks, w.current)
+	w.current, w.count = 0, 0
+	if len(w.peaks) == 4*WaveformBars {
+		for i := 0; i < len(w.peaks)/2; i++ {
+			w.peaks[i] = max(w.peaks[2*i], w.peaks[2*i+1])
+		}
+		w.peaks = w.peaks[:len(w.peaks)/2]
+		w.span *= 2
+	}
+}
+
+// bars returns WaveformBars values relative to the loudest part of the recording
+func (w *waveform) bars() []byte {
+	peaks := w.peaks
+	if w.count > 0 {
+		peaks = append(peaks, w.current)
+	}
+	if len(peaks) == 0 {
+		return nil
+	}
+
+	bars := make([]float64, WaveformBars)
+	loudest := 0.0
+	for i := range bars {
+		start := i * len(peaks) / WaveformBars
+		end := max((i+1)*len(peaks)/WaveformBars, start+1)
+		for _, peak := range peaks[start:end] {
+			bars[i] = max(bars[i], peak)
+		}
+		loudest = max(loudest, bars[i])
+	}
+
+	values := make([]byte, WaveformBars)
+	if loudest == 0 {
+		return values
+	}
+	for i, bar := range bars {
+		values[i] = byte(bar/loudest*255 + 0.5)
+	}
+	return values
+}
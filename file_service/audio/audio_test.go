@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeDurations(t *testing.T) {
+	for _, test := range []struct {
+		file       string
+		mimeType   string
+		durationMs int64
+	}{
+		{"voice.wav", "audio/wav", 1500},
+		{"voice.opus", "audio/ogg", 2500},
+		{"vorbis.ogg", "audio/ogg", 3000},
+		{"voice.m4a", "audio/mp4", 4321},
+		{"voice_v1.m4a", "audio/mp4", 2000},
+		{"voice.mp3", "audio/mpeg", 2612},
+		{"voice.aac", "audio/aac", 1996},
+	} {
+		data, err := os.ReadFile(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Supported(test.mimeType) {
+			t.Errorf("%s: %s not supported", test.file, test.mimeType)
+		}
+		info, err := Analyze(bytes.NewReader(data), test.mimeType)
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if info.DurationMs != test.durationMs {
+			t.Errorf("%s: duration %d ms, want %d ms", test.file, info.DurationMs, test.durationMs)
+		}
+		// Only uncompressed recordings get a waveform
+		if (info.Waveform != nil) != (test.mimeType == "audio/wav") {
+			t.Errorf("%s: waveform %v", test.file, info.Waveform)
+		}
+	}
+}
+
+func TestWAVWaveform(t *testing.T) {
+	data, err := os.ReadFile("testdata/voice.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Analyze(bytes.NewReader(data), "audio/wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Waveform) != WaveformBars {
+		t.Fatalf("waveform has %d bars, want %d", len(info.Waveform), WaveformBars)
+	}
+	// The recording is silent for its first half and then a tone at a constant level
+	for i, bar := range info.Waveform {
+		if i < WaveformBars/2-1 && bar != 0 {
+			t.Errorf("bar %d = %d during silence", i, bar)
+		}
+		if i > WaveformBars/2 && bar < 250 {
+			t.Errorf("bar %d = %d during the tone, want the loudest level", i, bar)
+		}
+	}
+}
+
+func TestAnalyzeTruncatedFiles(t *testing.T) {
+	wav, err := os.ReadFile("testdata/voice.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The duration of a truncated WAV file is measured from the samples present
+	info, err := Analyze(bytes.NewReader(wav[:len(wav)-8000]), "audio/wav")
+	if err != nil || info.DurationMs != 1000 {
+		t.Errorf("truncated wav: %d ms, %v; want 1000 ms", info.DurationMs, err)
+	}
+
+	mp3, err := os.ReadFile("testdata/voice.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A frame cut off at the end is not counted
+	info, err = Analyze(bytes.NewReader(mp3[:len(mp3)-128-200]), "audio/mpeg")
+	if err != nil || info.DurationMs != 2586 {
+		t.Errorf("truncated mp3: %d ms, %v; want 2586 ms", info.DurationMs, err)
+	}
+}
+
+func TestAnalyzeRejectsInvalidFiles(t *testing.T) {
+	opus, err := os.ReadFile("testdata/voice.opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m4a, err := os.ReadFile("testdata/voice.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		data     []byte
+		mimeType string
+	}{
+		{"empty wav", nil, "audio/wav"},
+		{"not riff", []byte("RIFX\x00\x00\x00\x00WAVE"), "audio/wav"},
+		{"no ogg sync", append([]byte("junk"), opus...), "audio/ogg"},
+		{"ogg headers only", opus[:100], "audio/ogg"},
+		{"unknown ogg codec", bytes.Replace(opus, []byte("OpusHead"), []byte("Speex   "), 1), "audio/ogg"},
+		{"mp4 without movie", m4a[:len(m4a)-120], "audio/mp4"},
+		{"no mp3 frames", bytes.Repeat([]byte{0x00}, 4096), "audio/mpeg"},
+		{"flac", []byte("fLaC"), "audio/flac"},
+	} {
+		if info, err := Analyze(bytes.NewReader(test.data), test.mimeType); err == nil {
+			t.Errorf("%s: analyzed as %+v", test.name, info)
+		}
+	}
+	if _, err := Analyze(bytes.NewReader(nil), "audio/flac"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("flac error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxMovieBoxSize bounds the moov box read into memory; it holds the sample tables of the tracks
+const maxMovieBoxSize = 32 << 20
+
+// analyzeMP4 reads the duration of an MPEG-4 audio file from the movie header, skipping over
+// the media data wherever it is placed
+func analyzeMP4(r *bufio.Reader) (Info, error) {
+	for {
+		boxType, size, err := readBoxHeader(r)
+		if err == io.EOF {
+			return Info{}, errors.New("mp4: movie box not found")
+		}
+		if err != nil {
+			return Info{}, err
+		}
+
+		if boxType == "moov" {
+			if size < 0 || size > maxMovieBoxSize {
+				return Info{}, fmt.Errorf("mp4: unsupported movie box size %d", size)
+			}
+			movie := make([]byte, size)
+			if _, err := io.ReadFull(r, movie); err != nil {
+				return Info{}, err
+			}
+			return movieDuration(movie)
+		}
+		// A box without size extends to the end of the file
+		if size < 0 {
+			return Info{}, errors.New("mp4: movie box not found")
+		}
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return Info{}, err
+		}
+	}
+}
+
+// readBoxHeader reads the type and the payload size of the next box; the size is -1 for a box
+// extending to the end of the file
+func readBoxHeader(r io.Reader) (string, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", 0, err
+	}
+	boxType := string(header[4:])
+
+	switch size := int64(binary.BigEndian.Uint32(header[:4])); size {
+	case 0:
+		return boxType, -1, nil
+	case 1:
+		var large [8]byte
+		if _, err := io.ReadFull(r, large[:]); err != nil {
+			return "", 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(large[:]))
+		if size < 16 {
+			return "", 0, fmt.Errorf("mp4: invalid size of box %q", boxType)
+		}
+		return boxType, size - 16, nil
+	default:
+		if size < 8 {
+			return "", 0, fmt.Errorf("mp4: invalid size of box %q", boxType)
+		}
+		return boxType, size - 8, nil
+	}
+}
+
+// movieDuration finds the movie header among the children of the moov box and converts its
+// duration from the movie time scale
+func movieDuration(movie []byte) (Info, error) {
+	for len(movie) >= 8 {
+		size := int(binary.BigEndian.Uint32(movie[:4]))
+		if size < 8 || size > len(movie) {
+			break
+		}
+		if string(movie[4:8]) != "mvhd" {
+			movie = movie[size:]
+			continue
+		}
+
+		header := movie[8:size]
+		var timescale, duration uint64
+		switch {
+		case len(header) >= 20 && header[0] == 0:
+			timescale = uint64(binary.BigEndian.Uint32(header[12:16]))
+			duration = uint64(binary.BigEndian.Uint32(header[16:20]))
+		case len(header) >= 32 && header[0] == 1:
+			timescale = uint64(binary.BigEndian.Uint32(header[20:24]))
+			duration = binary.BigEndian.Uint64(header[24:32])
+		default:
+			return Info{}, errors.New("mp4: invalid movie header")
+		}
+		if timescale == 0 {
+			return Info{}, errors.New("mp4: invalid time scale")
+		}
+		return Info{DurationMs: int64(duration/timescale*1000 + duration%timescale*1000/timescale)}, nil
+	}
+	return Info{}, errors.New("mp4: movie header not found")
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audio
+
+import (
+	"bufio"
+	"errors"
+)
+
+// frameParser reads the header at the start of data and returns the length of the frame,
+// its number of samples and its sample rate. ok is false when data does not start with a frame.
+type frameParser func(data []byte) (length, samples, rate int, ok bool)
+
+// Bitrates in kbit/s of MPEG audio layer III by bitrate index
+var (
+	mpeg1Bitrates = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2Bitrates = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+)
+
+// Sample rates of MPEG audio by version bits (2.5, reserved, 2, 1) and sample rate index
+var mpegRates = [4][3]int{
+	{11025, 12000, 8000},
+	{},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// Sample rates of AAC by sampling frequency index
+var adtsRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// mp3Frame parses the header of an MPEG audio layer III frame
+func mp3Frame(data []byte) (int, int, int, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1]&0xE0 != 0xE0 || data[1]&0x06 != 0x02 {
+		return 0, 0, 0, false
+	}
+	version := data[1] >> 3 & 0x03
+	bitrateIndex := data[2] >> 4
+	rateIndex := data[2] >> 2 & 0x03
+	if version == 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return 0, 0, 0, false
+	}
+
+	rate := mpegRates[version][rateIndex]
+	bitrate, samples := mpeg2Bitrates[bitrateIndex], 576
+	if version == 3 {
+		bitrate, samples = mpeg1Bitrates[bitrateIndex], 1152
+	}
+	padding := int(data[2] >> 1 & 0x01)
+	return samples/8*bitrate*1000/rate + padding, samples, rate, true
+}
+
+// adtsFrame parses the header of an AAC frame in an ADTS stream
+func adtsFrame(data []byte) (int, int, int, bool) {
+	if len(data) < 7 || data[0] != 0xFF || data[1]&0xF6 != 0xF0 {
+		return 0, 0, 0, false
+	}
+	rateIndex := int(data[2] >> 2 & 0x0F)
+	length := int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5]>>5)
+	if rateIndex >= len(adtsRates) || length < 7 {
+		return 0, 0, 0, false
+	}
+	blocks := int(data[6]&0x03) + 1
+	return length, blocks * 1024, adtsRates[rateIndex], true
+}
+
+// analyzeFrames adds up the duration of the frames of an MPEG audio or ADTS stream. Bytes between
+// frames, such as a trailing ID3v1 tag, are skipped until the next frame header.
+func analyzeFrames(r *bufio.Reader, parse frameParser) (Info, error) {
+	if err := skipID3(r); err != nil {
+		return Info{}, err
+	}
+
+	var seconds float64
+	frames := 0
+	for {
+		header, _ := r.Peek(7)
+		if len(header) < 4 {
+			break
+		}
+		length, samples, rate, ok := parse(header)
+		if !ok {
+			if _, err := r.Discard(1); err != nil {
+				return Info{}, err
+			}
+			continue
+		}
+		// A frame cut off at the end of the file is not counted
+		if n, _ := r.Discard(length); n < length {
+			break
+		}
+		seconds += float64(samples) / float64(rate)
+		frames++
+	}
+
+	if frames == 0 {
+		return Info{}, errors.New("no audio frames found")
+	}
+	return Info{DurationMs: int64(seconds * 1000)}, nil
+}
+
+// skipID3 skips the ID3v2 tag that may precede the frames
+func skipID3(r *bufio.Reader) error {
+	header, err := r.Peek(10)
+	if err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	// The tag size is a 28-bit synchsafe integer that excludes the header and the optional footer
+	size := int(header[6]&0x7F)<<21 | int(header[7]&0x7F)<<14 | int(header[8]&0x7F)<<7 | int(header[9]&0x7F)
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	_, err = r.Discard(10 + size)
+	return err
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Identification headers of the codecs found in Ogg voice recordings
+var (
+	opusHead     = []byte("OpusHead")
+	vorbisHeader = []byte("\x01vorbis")
+)
+
+// opusRate is the rate of the granule positions of Opus streams, whatever the input sample rate
+const opusRate = 48000
+
+// analyzeOgg walks the Ogg pages and computes the duration of the first logical stream from the
+// granule position of its last page. The packets are skipped undecoded, see Analyze.
+func analyzeOgg(r *bufio.Reader) (Info, error) {
+	var (
+		serial  uint32
+		rate    int64
+		preSkip int64
+		granule int64 = -1
+	)
+
+	for first := true; ; first = false {
+		var header [27]byte
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF && !first {
+			break
+		} else if err != nil {
+			return Info{}, err
+		}
+		if string(header[:4]) != "OggS" {
+			return Info{}, errors.New("ogg: lost page synchronization")
+		}
+
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return Info{}, err
+		}
+		bodySize := 0
+		for _, segment := range segments {
+			bodySize += int(segment)
+		}
+
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if first {
+			// The first page holds nothing but the identification header of the codec
+			body := make([]byte, bodySize)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Info{}, err
+			}
+			serial = pageSerial
+			switch {
+			case bytes.HasPrefix(body, opusHead) && len(body) >= 19:
+				rate = opusRate
+				preSkip = int64(binary.LittleEndian.Uint16(body[10:12]))
+			case bytes.HasPrefix(body, vorbisHeader) && len(body) >= 16:
+				rate = int64(binary.LittleEndian.Uint32(body[12:16]))
+			default:
+				return Info{}, ErrUnsupported
+			}
+			if rate == 0 {
+				return Info{}, errors.New("ogg: invalid sample rate")
+			}
+			continue
+		}
+
+		if _, err := r.Discard(bodySize); err != nil {
+			return Info{}, err
+		}
+		// Pages on which no packet ends carry a granule position of -1
+		if position := int64(binary.LittleEndian.Uint64(header[6:14])); pageSerial == serial && position != -1 {
+			granule = position
+		}
+	}
+
+	if granule < preSkip {
+		return Info{}, errors.New("ogg: no audio packets")
+	}
+	return Info{DurationMs: (granule - preSkip) * 1000 / rate}, nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAVE format tags
+const (
+	wavPCM        = 1
+	wavFloat      = 3
+	wavExtensible = 0xFFFE
+)
+
+// wavFormat is the content of the fmt chunk of a WAVE file
+type wavFormat struct {
+	tag        uint16
+	channels   int
+	byteRate   int64
+	blockAlign int
+	bits       int
+}
+
+// analyzeWAV walks the RIFF chunks up to the data chunk and measures its samples
+func analyzeWAV(r *bufio.Reader) (Info, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Info{}, err
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return Info{}, errors.New("wav: not a RIFF WAVE file")
+	}
+
+	var format *wavFormat
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return Info{}, fmt.Errorf("wav: data chunk not found: %w", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return Info{}, fmt.Errorf("wav: invalid fmt chunk size %d", size)
+			}
+			data := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return Info{}, err
+			}
+			format = &wavFormat{
+				tag:        binary.LittleEndian.Uint16(data[0:2]),
+				channels:   int(binary.LittleEndian.Uint16(data[2:4])),
+				byteRate:   int64(binary.LittleEndian.Uint32(data[8:12])),
+				blockAlign: int(binary.LittleEndian.Uint16(data[12:14])),
+				bits:       int(binary.LittleEndian.Uint16(data[14:16])),
+			}
+			// The GUID of an extensible format starts with the actual format tag
+			if format.tag == wavExtensible && size >= 26 {
+				format.tag = binary.LittleEndian.Uint16(data[24:26])
+			}
+		case "data":
+			if format == nil {
+				return Info{}, errors.New("wav: data chunk before fmt chunk")
+			}
+			// Recorders streaming to disk may leave the size of the data chunk unset
+			var data io.Reader = r
+			if size != 0 && size != math.MaxUint32 {
+				data = io.LimitReader(r, size)
+			}
+			return format.analyze(data)
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return Info{}, err
+			}
+		}
+	}
+}
+
+// analyze reads the samples of the data chunk. The duration is measured from the bytes present,
+// so truncated files report what can actually be played.
+func (f *wavFormat) analyze(data io.Reader) (Info, error) {
+	if f.channels == 0 || f.byteRate == 0 || f.blockAlign == 0 {
+		return Info{}, errors.New("wav: invalid format")
+	}
+
+	amplitude := f.amplitude()
+	if amplitude == nil {
+		// Compressed WAVE data: only the duration is known
+		read, err := io.Copy(io.Discard, data)
+		if err != nil {
+			return Info{}, err
+		}
+		return Info{DurationMs: read * 1000 / f.byteRate}, nil
+	}
+
+	wave := newWaveform()
+	buf := make([]byte, f.blockAlign*4096)
+	var read int64
+	for {
+		n, err := io.ReadFull(data, buf)
+		n -= n % f.blockAlign
+		for frame := 0; frame < n; frame += f.blockAlign {
+			wave.add(amplitude(buf[frame : frame+f.blockAlign]))
+		}
+		read += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return Info{}, err
+		}
+	}
+	return Info{DurationMs: read * 1000 / f.byteRate, Waveform: wave.bars()}, nil
+}
+
+// amplitude returns the function reading the loudest channel of a frame, between 0 and 1,
+// or nil when the samples are not plain PCM
+func (f *wavFormat) amplitude() func(frame []byte) float64 {
+	sampleSize := f.bits / 8
+	if f.bits%8 != 0 || f.blockAlign != f.channels*sampleSize {
+		return nil
+	}
+
+	var sample func(b []byte) float64
+	switch {
+	case f.tag == wavPCM && f.bits == 8:
+		sample = func(b []byte) float64 { return math.Abs(float64(int(b[0])-128)) / 128 }
+	case f.tag == wavPCM && f.bits == 16:
+		sample = func(b []byte) float64 { return math.Abs(float64(int16(binary.LittleEndian.Uint16(b)))) / 32768 }
+	case f.tag == wavPCM && f.bits == 24:
+		sample = func(b []byte) float64 {
+			value := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return math.Abs(float64(value)) / 8388608
+		}
+	case f.tag == wavPCM && f.bits == 32:
+		sample = func(b []byte) float64 { return math.Abs(float64(int32(binary.LittleEndian.Uint32(b)))) / 2147483648 }
+	case f.tag == wavFloat && f.bits == 32:
+		sample = func(b []byte) float64 {
+			value := math.Abs(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+			if math.IsNaN(value) {
+				return 0
+			}
+			return min(value, 1)
+		}
+	default:
+		return nil
+	}
+
+	return func(frame []byte) float64 {
+		loudest := 0.0
+		for offset := 0; offset < len(frame); offset += sampleSize {
+			loudest = max(loudest, sample(frame[offset:offset+sampleSize]))
+		}
+		return loudest
+	}
+}
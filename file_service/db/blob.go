@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import "urulink.com/file_service/models"
+
+// StoreBlob records objectName, already written and encrypted with the wrapped data key, as the
+// blob holding content with the given SHA-256 hash. When the content is stored already, a reference
+// is added to that blob instead. It returns the object holding the blob and whether it existed.
+// The object is written before the row is added, so the row never points at a missing object and
+// no lock is held while storage is written.
+func (data Database) StoreBlob(hash string, size int64, objectName, keyId string, wrappedKey []byte) (string, bool, error) {
+	// MySQL reports 1 affected row for an insert and 2 for an update of an existing row
+	result := data.Db.Exec(`INSERT INTO file_blob (hash, size, object_name, ref_count, key_id, data_key) VALUES (?, ?, ?, 1, ?, ?)
+		ON DUPLICATE KEY UPDATE ref_count = ref_count + 1`, hash, size, objectName, keyId, wrappedKey)
+	if result.Error != nil {
+		return "", false, result.Error
+	}
+	if result.RowsAffected != 2 {
+		return objectName, false, nil
+	}
+	existing, err := data.blobObject(hash)
+	return existing, true, err
+}
+
+// blobObject returns the object holding the blob with the given hash
+func (data Database) blobObject(hash string) (string, error) {
+	var objectName string
+	err := data.Db.Raw("SELECT object_name FROM file_blob WHERE hash = ?", hash).Scan(&objectName).Error
+	return objectName, err
+}
+
+// GetBlobKey returns the wrapped data key of a blob and the ID of the master key wrapping it.
+// Blobs stored before encryption have an empty key ID.
+func (data Database) GetBlobKey(hash string) (string, []byte, error) {
+	var key models.BlobKey
+	err := data.Db.Raw("SELECT hash, key_id, data_key FROM file_blob WHERE hash = ?", hash).Scan(&key).Error
+	return key.KeyId, key.DataKey, err
+}
+
+// GetBlobKeysToRewrap lists up to limit encrypted blobs whose data key is not wrapped by keyId
+func (data Database) GetBlobKeysToRewrap(keyId string, limit int) ([]models.BlobKey, error) {
+	var keys []models.BlobKey
+	err := data.Db.Raw("SELECT hash, key_id, data_key FROM file_blob WHERE key_id <> '' AND key_id <> ? LIMIT ?", keyId, limit).
+		Scan(&keys).Error
+	return keys, err
+}
+
+// UpdateBlobKey replaces the wrapped data key of a blob, unless it was re-wrapped concurrently
+func (data Database) UpdateBlobKey(hash, oldKeyId, keyId string, wrappedKey []byte) error {
+	return data.Db.Exec("UPDATE file_blob SET key_id = ?, data_key = ? WHERE hash = ? AND key_id = ?",
+		keyId, wrappedKey, hash, oldKeyId).Error
+}
+
+// ReleaseBlob removes a reference to a blob; blobs without references are removed by CollectBlobs
+func (data Database) ReleaseBlob(hash string) error {
+	return data.Db.Exec("UPDATE file_blob SET ref_count = ref_count - 1 WHERE hash = ? AND ref_count > 0", hash).Error
+}
+
+// CollectBlobs removes up to limit blobs without references. Each blob row is locked while
+// deleteObject removes its object, so StoreBlob cannot reference it at the
+// same time. It returns the number of blobs removed.
+func (data Database) CollectBlobs(limit int, deleteObject func(objectName string) error) (int, error) {
+	var hashes []string
+	if err := data.Db.Raw("SELECT hash FROM file_blob WHERE ref_count = 0 LIMIT ?", limit).Scan(&hashes).Error; err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, hash := range hashes {
+		tx := data.Db.Begin()
+		var blob struct {
+			RefCount   int64
+			ObjectName string
+		}
+		result := tx.Raw("SELECT ref_count, object_name FROM file_blob WHERE hash = ? FOR UPDATE", hash).Scan(&blob)
+		if result.Error != nil || result.RowsAffected == 0 || blob.RefCount != 0 {
+			tx.Rollback()
+			continue
+		}
+		if err := deleteObject(blob.ObjectName); err != nil {
+			tx.Rollback()
+			return removed, err
+		}
+		if err := tx.Exec("DELETE FROM file_blob WHERE hash = ?", hash).Error; err != nil {
+			tx.Rollback()
+			return removed, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"urulink.com/file_service/models"
+)
+
+// Database struct holds the GORM DB connection instance
+type Database struct {
+	Db *gorm.DB
+}
+
+// UruLinkInit initializes a database connection with specified settings
+// Takes a DSN (Data Source Name) for MySQL, returns a Database instance or an error
+func UruLinkInit(dsn string) (*Database, error) {
+	// Open a new MySQL connection using the provided DSN
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the generic database object to configure connection pool settings
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure the database connection pool settings
+	sqlDB.SetMaxIdleConns(15)           // Maximum number of idle connections
+	sqlDB.SetMaxOpenConns(30)           // Maximum number of open connections
+	sqlDB.SetConnMaxLifetime(time.Hour) // Lifetime of each connection
+
+	return &Database{
+		Db: db,
+	}, nil
+}
+
+// CreateFile records the metadata of an uploaded file within a transaction
+// Rolls back the transaction if an error occurs or if a panic is recovered
+func (data Database) CreateFile(fileInfo models.FileInfo) error {
+	// Begin a new transaction
+	tx := data.Db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered in CreateFile:", r)
+			tx.Rollback()
+		}
+	}()
+
+	// Attempt to create a new record in the 'files' table
+	result := tx.Table("files").Create(&fileInfo)
+	if result.Error != nil {
+		log.Println("Error creating file:", result.Error)
+		tx.Rollback()
+		return result.Error
+	}
+
+	// Commit the transaction if no error occurs
+	if err := tx.Commit().Error; err != nil {
+		log.Println("Error committing transaction:", err)
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// GetFileById retrieves the metadata of a file; the returned FileInfo has an empty Id when it does not exist
+func (data Database) GetFileById(id string) (models.FileInfo, error) {
+	var fileInfo models.FileInfo
+	result := data.Db.Table("files").Raw("SELECT * FROM files WHERE id = ?", id).Scan(&fileInfo)
+	return fileInfo, result.Error
+}
+
+// GetFilesByOwner lists the files uploaded by a user, newest first
+func (data Database) GetFilesByOwner(ownerId string, limit, offset int) ([]models.FileInfo, error) {
+	var files []models.FileInfo
+	result := data.Db.Table("files").
+		Raw("SELECT * FROM files WHERE owner_id = ? ORDER BY created_at DESC, id ASC LIMIT ? OFFSET ?", ownerId, limit, offset).
+		Scan(&files)
+	return files, result.Error
+}
+
+// DeleteFile removes the metadata row of a file
+func (data Database) DeleteFile(id string) error {
+	return data.Db.Exec("DELETE FROM files WHERE id = ?", id).Error
+}
+
+// UpdateFilePreview records the result of the preview generation of an image
+func (data Database) UpdateFilePreview(id string, width, height int, blurHash, status string) error {
+	return data.Db.Exec("UPDATE files SET width = ?, height = ?, blur_hash = ?, preview_status = ? WHERE id = ?",
+		width, height, blurHash, status, id).Error
+}
+
+// UpdateFileDocument records the preview read from a text file or PDF
+func (data Database) UpdateFileDocument(id, snippet, encoding string, pageCount int, title string) error {
+	return data.Db.Exec("UPDATE files SET snippet = ?, encoding = ?, page_count = ?, title = ? WHERE id = ?",
+		snippet, encoding, pageCount, title, id).Error
+}
+
+// UpdateFileScan records the verdict of the malware scan of a file
+func (data Database) UpdateFileScan(id, status, signature string) error {
+	return data.Db.Exec("UPDATE files SET scan_status = ?, scan_signature = ? WHERE id = ?", status, signature, id).Error
+}
+
+// GetFilesWithScanStatus lists the IDs of up to limit files with the given scan status uploaded before createdBefore
+func (data Database) GetFilesWithScanStatus(status string, createdBefore int64, limit int) ([]string, error) {
+	var ids []string
+	err := data.Db.Raw("SELECT id FROM files WHERE scan_status = ? AND created_at < ? ORDER BY created_at LIMIT ?",
+		status, createdBefore, limit).Scan(&ids).Error
+	return ids, err
+}
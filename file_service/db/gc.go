@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import "urulink.com/file_service/models"
+
+// GetFilesCreatedBefore pages through the files created before createdBefore by ID; pass the last
+// ID of the previous page as afterId, or "" for the first page
+func (data Database) GetFilesCreatedBefore(createdBefore int64, afterId string, limit int) ([]models.FileInfo, error) {
+	var files []models.FileInfo
+	result := data.Db.Table("files").
+		Raw("SELECT * FROM files WHERE created_at < ? AND id > ? ORDER BY id LIMIT ?", createdBefore, afterId, limit).
+		Scan(&files)
+	return files, result.Error
+}
+
+// ExistingFiles returns which of the given IDs belong to a file of the catalog
+func (data Database) ExistingFiles(ids []string) (map[string]bool, error) {
+	return data.existing("SELECT id FROM files WHERE id IN ?", ids)
+}
+
+// ExistingBlobObjects returns which of the given object names hold a stored blob
+func (data Database) ExistingBlobObjects(names []string) (map[string]bool, error) {
+	return data.existing("SELECT object_name FROM file_blob WHERE object_name IN ?", names)
+}
+
+// ExistingUploadObjects returns which of the given object names receive a resumable or direct upload
+// that is still in progress
+func (data Database) ExistingUploadObjects(names []string) (map[string]bool, error) {
+	return data.existing("SELECT object_name FROM upload_session WHERE object_name IN ? "+
+		"UNION SELECT id FROM pending_upload WHERE id IN ?", names, names)
+}
+
+// existing runs a query selecting a single column and returns the values found
+func (data Database) existing(query string, args ...interface{}) (map[string]bool, error) {
+	var values []string
+	if err := data.Db.Raw(query, args...).Scan(&values).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(values))
+	for _, value := range values {
+		found[value] = true
+	}
+	return found, nil
+}
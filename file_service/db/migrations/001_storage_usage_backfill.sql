@@ -0,0 +1,16 @@
+-- Counts the files stored before quotas existed towards the usage of their owners.
+-- schema.sql only creates the missing storage_usage table, so existing databases run this
+-- once before the new file service is started. Usage is recomputed from the files of each
+-- user plus the space reserved by their uploads in progress, the same amounts the service
+-- reserves and releases itself; quotas and groups that were already set are kept.
+
+INSERT INTO storage_usage (user_id, used_bytes)
+SELECT user_id, SUM(bytes) FROM (
+    SELECT owner_id AS user_id, size AS bytes FROM files
+    UNION ALL
+    SELECT owner_id, size FROM pending_upload
+    UNION ALL
+    SELECT owner_id, length FROM upload_session
+) AS reserved
+GROUP BY user_id
+ON DUPLICATE KEY UPDATE used_bytes = VALUES(used_bytes);
@@ -0,0 +1,12 @@
+-- Records the object holding each blob. Blobs used to be stored as blobs/<hash>; new blobs are
+-- uploaded under a random name while their hash is computed and keep that name. Existing
+-- databases run this once before the new file service is started.
+
+ALTER TABLE file_blob
+    ADD COLUMN object_name VARCHAR(128) NOT NULL DEFAULT '' AFTER size;
+
+UPDATE file_blob SET object_name = CONCAT('blobs/', hash);
+
+ALTER TABLE file_blob
+    ALTER COLUMN object_name DROP DEFAULT,
+    ADD INDEX idx_file_blob_object (object_name);
@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"urulink.com/file_service/models"
+)
+
+// CreatePendingUpload stores a presigned direct upload
+func (data Database) CreatePendingUpload(upload models.PendingUpload) error {
+	return data.Db.Table("pending_upload").Create(&upload).Error
+}
+
+// GetPendingUpload retrieves a presigned upload; the returned upload has an empty Id when it does not exist
+func (data Database) GetPendingUpload(id string) (models.PendingUpload, error) {
+	var upload models.PendingUpload
+	result := data.Db.Table("pending_upload").Raw("SELECT * FROM pending_upload WHERE id = ?", id).Scan(&upload)
+	return upload, result.Error
+}
+
+// DeletePendingUpload removes a presigned upload; it reports whether a row was deleted, so two
+// concurrent completions cannot both register the file
+func (data Database) DeletePendingUpload(id string) (bool, error) {
+	result := data.Db.Exec("DELETE FROM pending_upload WHERE id = ?", id)
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetExpiredPendingUploads lists the presigned uploads that were never completed before the given unix time
+func (data Database) GetExpiredPendingUploads(before int64) ([]models.PendingUpload, error) {
+	var uploads []models.PendingUpload
+	result := data.Db.Table("pending_upload").Raw("SELECT * FROM pending_upload WHERE expires_at < ?", before).Scan(&uploads)
+	return uploads, result.Error
+}
@@ -0,0 +1,89 @@
+-- Schema of the tables owned by file_service (MySQL).
+
+-- Catalog of uploaded files. The content is stored in object_name, the blob named after the
+-- checksum; files uploaded before deduplication keep their content under their id.
+CREATE TABLE IF NOT EXISTS files (
+    id             VARCHAR(64)   PRIMARY KEY,
+    owner_id       VARCHAR(64)   NOT NULL,
+    original_name  VARCHAR(255)  NOT NULL,
+    size           BIGINT        NOT NULL,
+    mime_type      VARCHAR(128)  NOT NULL,
+    checksum       CHAR(64)      NOT NULL,
+    created_at     BIGINT        NOT NULL,
+    category       VARCHAR(32)   NOT NULL DEFAULT '',
+    width          INT           NOT NULL DEFAULT 0,
+    height         INT           NOT NULL DEFAULT 0,
+    blur_hash      VARCHAR(64)   NOT NULL DEFAULT '',
+    preview_status VARCHAR(16)   NOT NULL DEFAULT '',
+    object_name    VARCHAR(128)  NOT NULL DEFAULT '',
+    scan_status    VARCHAR(16)   NOT NULL DEFAULT 'pending',
+    scan_signature VARCHAR(128)  NOT NULL DEFAULT '',
+    duration_ms    BIGINT        NOT NULL DEFAULT 0,
+    waveform       VARBINARY(64),
+    snippet        TEXT,
+    encoding       VARCHAR(16)   NOT NULL DEFAULT '',
+    page_count     INT           NOT NULL DEFAULT 0,
+    title          VARCHAR(255)  NOT NULL DEFAULT '',
+    archive        MEDIUMTEXT,
+    INDEX idx_files_owner (owner_id, created_at),
+    INDEX idx_files_scan (scan_status, created_at)
+);
+
+-- Resumable (tus) uploads in progress.
+CREATE TABLE IF NOT EXISTS upload_session (
+    id                VARCHAR(64)   PRIMARY KEY,
+    owner_id          VARCHAR(64)   NOT NULL,
+    object_name       VARCHAR(64)   NOT NULL,
+    original_name     VARCHAR(255)  NOT NULL,
+    mime_type         VARCHAR(128)  NOT NULL,
+    length            BIGINT        NOT NULL,
+    `offset`          BIGINT        NOT NULL,
+    storage_upload_id VARCHAR(255)  NOT NULL,
+    parts             TEXT          NOT NULL,
+    tail              MEDIUMBLOB,
+    hash_state        BLOB,
+    created_at        BIGINT        NOT NULL,
+    expires_at        BIGINT        NOT NULL,
+    locked_until      BIGINT        NOT NULL DEFAULT 0,
+    INDEX idx_upload_session_expiry (expires_at)
+);
+
+-- Direct-to-storage uploads that were presigned but not completed yet; id is the object name.
+CREATE TABLE IF NOT EXISTS pending_upload (
+    id            VARCHAR(64)   PRIMARY KEY,
+    owner_id      VARCHAR(64)   NOT NULL,
+    original_name VARCHAR(255)  NOT NULL,
+    size          BIGINT        NOT NULL,
+    mime_type     VARCHAR(128)  NOT NULL,
+    expires_at    BIGINT        NOT NULL,
+    INDEX idx_pending_upload_expiry (expires_at)
+);
+
+-- Bytes stored per user; quota_bytes overrides the quota of the group, or the default quota.
+CREATE TABLE IF NOT EXISTS storage_usage (
+    user_id     VARCHAR(64)   PRIMARY KEY,
+    used_bytes  BIGINT        NOT NULL DEFAULT 0,
+    quota_bytes BIGINT        NULL,
+    group_name  VARCHAR(64)   NOT NULL DEFAULT ''
+);
+
+-- Quota of each user in a group, for example a paid plan.
+CREATE TABLE IF NOT EXISTS quota_group (
+    name        VARCHAR(64)   PRIMARY KEY,
+    quota_bytes BIGINT        NOT NULL
+);
+
+-- Deduplicated file contents, stored in object_name under blobs/; ref_count is the number of files
+-- using a blob. data_key is the key encrypting the blob and its previews, wrapped by the master key
+-- key_id; blobs stored before encryption have an empty key_id.
+CREATE TABLE IF NOT EXISTS file_blob (
+    hash        CHAR(64)      PRIMARY KEY,
+    size        BIGINT        NOT NULL,
+    object_name VARCHAR(128)  NOT NULL,
+    ref_count   INT           NOT NULL,
+    key_id      VARCHAR(64)   NOT NULL DEFAULT '',
+    data_key    VARBINARY(128),
+    INDEX idx_file_blob_ref_count (ref_count),
+    INDEX idx_file_blob_key_id (key_id),
+    INDEX idx_file_blob_object (object_name)
+);
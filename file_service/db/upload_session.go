@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"urulink.com/file_service/models"
+)
+
+// CreateUploadSession stores a new resumable upload
+func (data Database) CreateUploadSession(session models.UploadSession) error {
+	return data.Db.Table("upload_session").Create(&session).Error
+}
+
+// GetUploadSession retrieves a resumable upload; the returned session has an empty Id when it does not exist
+func (data Database) GetUploadSession(id string) (models.UploadSession, error) {
+	var session models.UploadSession
+	result := data.Db.Table("upload_session").Raw("SELECT * FROM upload_session WHERE id = ?", id).Scan(&session)
+	return session, result.Error
+}
+
+// ClaimUploadSession locks a resumable upload at the given offset until lockedUntil, so a single
+// PATCH request uploads the next part. It fails when the offset moved or another request holds
+// an unexpired lock.
+func (data Database) ClaimUploadSession(id string, offset, now, lockedUntil int64) (bool, error) {
+	result := data.Db.Exec("UPDATE upload_session SET locked_until = ? WHERE id = ? AND `offset` = ? AND locked_until < ?",
+		lockedUntil, id, offset, now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UnlockUploadSession releases the lock taken by ClaimUploadSession without saving progress
+func (data Database) UnlockUploadSession(id string) error {
+	return data.Db.Exec("UPDATE upload_session SET locked_until = 0 WHERE id = ?", id).Error
+}
+
+// AdvanceUploadSession saves the progress of a resumable upload claimed at expectedOffset and
+// stores session.LockedUntil, which releases the lock unless the upload keeps it to complete.
+func (data Database) AdvanceUploadSession(session models.UploadSession, expectedOffset int64) (bool, error) {
+	result := data.Db.Exec("UPDATE upload_session SET `offset` = ?, parts = ?, tail = ?, hash_state = ?, expires_at = ?, locked_until = ? WHERE id = ? AND `offset` = ?",
+		session.Offset, session.Parts, session.Tail, session.HashState, session.ExpiresAt, session.LockedUntil, session.Id, expectedOffset)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteUploadSession removes a resumable upload
+func (data Database) DeleteUploadSession(id string) error {
+	return data.Db.Exec("DELETE FROM upload_session WHERE id = ?", id).Error
+}
+
+// GetExpiredUploadSessions lists the resumable uploads whose expiry time has passed
+func (data Database) GetExpiredUploadSessions(now int64) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	result := data.Db.Table("upload_session").Raw("SELECT * FROM upload_session WHERE expires_at < ?", now).Scan(&sessions)
+	return sessions, result.Error
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"database/sql"
+
+	"urulink.com/file_service/models"
+)
+
+// ReserveStorage adds size bytes to the usage of a user when the result stays within their quota:
+// their own quota, else the quota of their group, else defaultQuota. The check and the update
+// are a single statement, so concurrent uploads cannot exceed the quota together. Empty files
+// take no space and are always accepted: the update would change no row and look refused.
+func (data Database) ReserveStorage(userId string, size, defaultQuota int64) (bool, error) {
+	if size <= 0 {
+		return true, nil
+	}
+	if err := data.Db.Exec("INSERT IGNORE INTO storage_usage (user_id, used_bytes) VALUES (?, 0)", userId).Error; err != nil {
+		return false, err
+	}
+	result := data.Db.Exec(`UPDATE storage_usage u LEFT JOIN quota_group g ON g.name = u.group_name
+		SET u.used_bytes = u.used_bytes + ?
+		WHERE u.user_id = ? AND u.used_bytes + ? <= COALESCE(u.quota_bytes, g.quota_bytes, ?)`,
+		size, userId, size, defaultQuota)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseStorage subtracts size bytes from the usage of a user
+func (data Database) ReleaseStorage(userId string, size int64) error {
+	return data.Db.Exec("UPDATE storage_usage SET used_bytes = GREATEST(used_bytes - ?, 0) WHERE user_id = ?", size, userId).Error
+}
+
+// GetStorageUsage returns the usage and effective quota of a user
+func (data Database) GetStorageUsage(userId string, defaultQuota int64) (models.StorageUsage, error) {
+	usage := models.StorageUsage{UserId: userId, QuotaBytes: defaultQuota}
+	result := data.Db.Table("storage_usage").Raw(`SELECT u.user_id, u.used_bytes, u.group_name,
+		COALESCE(u.quota_bytes, g.quota_bytes, ?) AS quota_bytes
+		FROM storage_usage u LEFT JOIN quota_group g ON g.name = u.group_name
+		WHERE u.user_id = ?`, defaultQuota, userId).Scan(&usage)
+	return usage, result.Error
+}
+
+// SetUserQuota sets the quota of a user. A nil quota falls back to the group or default quota.
+func (data Database) SetUserQuota(userId string, quotaBytes *int64) error {
+	quota := sql.NullInt64{}
+	if quotaBytes != nil {
+		quota = sql.NullInt64{Int64: *quotaBytes, Valid: true}
+	}
+	return data.Db.Exec(`INSERT INTO storage_usage (user_id, used_bytes, quota_bytes) VALUES (?, 0, ?)
+		ON DUPLICATE KEY UPDATE quota_bytes = VALUES(quota_bytes)`, userId, quota).Error
+}
+
+// SetUserGroup puts a user in a quota group; an empty name removes them from their group
+func (data Database) SetUserGroup(userId, groupName string) error {
+	return data.Db.Exec(`INSERT INTO storage_usage (user_id, used_bytes, group_name) VALUES (?, 0, ?)
+		ON DUPLICATE KEY UPDATE group_name = VALUES(group_name)`, userId, groupName).Error
+}
+
+// SetGroupQuota creates or changes the quota of each member of a group
+func (data Database) SetGroupQuota(name string, quotaBytes int64) error {
+	return data.Db.Exec("INSERT INTO quota_group (name, quota_bytes) VALUES (?, ?) ON DUPLICATE KEY UPDATE quota_bytes = VALUES(quota_bytes)",
+		name, quotaBytes).Error
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package document
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned for files whose type is not previewed
+var ErrUnsupported = errors.New("document type is not supported")
+
+// Preview settings
+const (
+	SnippetLength  = 500 // Characters kept from the beginning of a text file
+	maxTitleLength = 255 // Characters kept of a title
+)
+
+// Info describes a document for a preview without downloading it
+type Info struct {
+	Snippet   string // Beginning of a text file
+	Encoding  string // Detected character encoding of a text file
+	PageCount int    // Pages of a PDF
+	Title     string // Title of a PDF from its document information
+}
+
+// Supported reports whether previews can be read from files of a sniffed MIME type
+func Supported(sniffedMime string) bool {
+	return sniffedMime == "text/plain" || sniffedMime == "application/pdf"
+}
+
+// Analyze reads a document of the given sniffed MIME type
+func Analyze(r io.ReaderAt, size int64, sniffedMime string) (Info, error) {
+	switch sniffedMime {
+	case "text/plain":
+		return analyzeText(r, size)
+	case "application/pdf":
+		return analyzePDF(r, size)
+	}
+	return Info{}, ErrUnsupported
+}
+
+// cleanText drops the control characters but line breaks and tabs from text, trims it and keeps
+// its first limit characters
+func cleanText(text string, limit int) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var cleaned strings.Builder
+	count := 0
+	for _, r := range strings.TrimSpace(text) {
+		if count == limit {
+			break
+		}
+		switch {
+		case r == '\r':
+			r = '\n'
+		case r == utf8.RuneError, r == '\uFEFF':
+			continue
+		case unicode.IsControl(r) && r != '\n' && r != '\t':
+			r = ' '
+		}
+		cleaned.WriteRune(r)
+		count++
+	}
+	return strings.TrimSpace(cleaned.String())
+}
@@ -0,0 +1,694 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"unicode/utf16"
+)
+
+// Limits of the PDF reader
+const (
+	pdfTailLength     = 2048     // Bytes at the end of a file searched for startxref
+	shortObjectLength = 4 << 10  // Bytes read first to parse an object outside of a stream
+	maxObjectLength   = 64 << 10 // Bytes read at most to parse an object outside of a stream
+	maxStreamLength   = 16 << 20 // Largest stream, before and after decoding
+	maxInflatedLength = 64 << 20 // Bytes decoded at most from all the streams of one file
+	maxStreamObjects  = 1 << 14  // Objects of one object stream
+	maxScanLength     = 16 << 20 // Largest file whose objects are searched when its xref is broken
+	maxScannedObjects = 1 << 16  // Object headers parsed at most when the xref is broken
+	maxXrefSections   = 64       // Incremental updates followed through Prev
+	maxObjectDepth    = 16       // References followed to resolve one object
+	maxPageCount      = 1 << 20
+)
+
+// Errors of the PDF reader
+var (
+	errNoDocument     = errors.New("no document information found in PDF")
+	errInflateBudget  = errors.New("PDF streams decode to too much data")
+	errTooManyObjects = errors.New("PDF has too many objects to search")
+)
+
+// objectHeader matches the "num gen obj" header of an object, for files with a broken xref
+var objectHeader = regexp.MustCompile(`(?:^|\s)(\d+)\s+(\d+)\s+obj\b`)
+
+// xrefEntry locates an object: at an offset of the file, or as index-th object of an object stream
+type xrefEntry struct {
+	compressed bool
+	free       bool
+	offset     int64 // Offset in the file, or number of the object stream
+	index      int
+}
+
+// pdfStream is a stream object whose data has not been read yet
+type pdfStream struct {
+	dict   pdfDict
+	offset int64 // Offset of the data in the file
+}
+
+// objectStream is the decoded data of an object stream and the numbers and offsets of its objects
+type objectStream struct {
+	numbers []int64
+	offsets []int
+	data    []byte
+}
+
+// pdfReader reads objects of a PDF file through its cross-reference table
+type pdfReader struct {
+	r             io.ReaderAt
+	data          []byte // The whole file once it was read to search its objects
+	size          int64
+	xref          map[int64]xrefEntry
+	trailer       pdfDict
+	objects       map[int64]any           // Resolved objects
+	objectStreams map[int64]*objectStream // Decoded object streams, nil for the unreadable ones
+	inflated      int64                   // Bytes decoded from streams so far, bounded by maxInflatedLength
+	depth         int
+}
+
+// analyzePDF reads the page count and title of a PDF
+func analyzePDF(r io.ReaderAt, size int64) (Info, error) {
+	reader := &pdfReader{r: r, size: size, xref: map[int64]xrefEntry{}, objects: map[int64]any{}, objectStreams: map[int64]*objectStream{}}
+	if err := reader.loadXref(); err != nil || reader.trailer["Root"] == nil {
+		// Files edited by careless tools often point to wrong offsets; find the objects instead.
+		// What was decoded so far still counts against the budget.
+		reader.xref, reader.trailer = map[int64]xrefEntry{}, nil
+		reader.objects, reader.objectStreams = map[int64]any{}, map[int64]*objectStream{}
+		if err := reader.scanObjects(); err != nil {
+			return Info{}, err
+		}
+	}
+
+	var info Info
+	if root, ok := reader.resolve(reader.trailer["Root"]).(pdfDict); ok {
+		if pages, ok := reader.resolve(root["Pages"]).(pdfDict); ok {
+			if count, ok := reader.resolve(pages["Count"]).(int64); ok && count >= 0 && count <= maxPageCount {
+				info.PageCount = int(count)
+			}
+		}
+	}
+	// Strings of encrypted files are encrypted as well
+	if reader.trailer["Encrypt"] == nil {
+		if documentInfo, ok := reader.resolve(reader.trailer["Info"]).(pdfDict); ok {
+			if title, ok := reader.resolve(documentInfo["Title"]).(pdfString); ok {
+				info.Title = cleanText(decodeTextString(title), maxTitleLength)
+			}
+		}
+	}
+	if info.PageCount == 0 && info.Title == "" {
+		return Info{}, errNoDocument
+	}
+	return info, nil
+}
+
+// loadXref reads the cross-reference sections from the last one to the first. Entries of newer
+// sections take precedence.
+func (p *pdfReader) loadXref() error {
+	tailLength := min(p.size, pdfTailLength)
+	tail := make([]byte, tailLength)
+	if _, err := p.r.ReadAt(tail, p.size-tailLength); err != nil && err != io.EOF {
+		return err
+	}
+	index := bytes.LastIndex(tail, []byte("startxref"))
+	if index < 0 {
+		return errors.New("startxref not found")
+	}
+	lexer := &pdfLexer{data: tail, pos: index + len("startxref")}
+	offset, err := lexer.object()
+	if err != nil {
+		return err
+	}
+
+	seen := map[int64]bool{}
+	for section := 0; section < maxXrefSections; section++ {
+		start, ok := offset.(int64)
+		if !ok || seen[start] {
+			break
+		}
+		seen[start] = true
+
+		trailer, err := p.readXrefSection(start)
+		if err != nil {
+			return err
+		}
+		if p.trailer == nil {
+			p.trailer = trailer
+		}
+		// Hybrid files list their compressed objects in an additional xref stream
+		if streamOffset, ok := trailer["XRefStm"].(int64); ok && !seen[streamOffset] {
+			seen[streamOffset] = true
+			if _, err := p.readXrefSection(streamOffset); err != nil {
+				return err
+			}
+		}
+		offset = trailer["Prev"]
+	}
+	return nil
+}
+
+// readXrefSection reads a cross-reference table or stream and returns its trailer dictionary
+func (p *pdfReader) readXrefSection(offset int64) (pdfDict, error) {
+	data, err := p.readAt(offset, maxObjectLength)
+	if err != nil {
+		return nil, err
+	}
+	lexer := &pdfLexer{data: data}
+	lexer.skipSpace()
+	if !bytes.HasPrefix(data[lexer.pos:], []byte("xref")) {
+		stream, err := p.objectAt(offset)
+		if err != nil {
+			return nil, err
+		}
+		xrefStream, ok := stream.(pdfStream)
+		if !ok || xrefStream.dict["Type"] != pdfName("XRef") {
+			return nil, errors.New("xref not found")
+		}
+		return xrefStream.dict, p.readXrefStream(xrefStream)
+	}
+
+	// A table is made of subsections "first count" followed by count entries "offset gen n|f"
+	lexer.pos += len("xref")
+	position := offset + int64(lexer.pos)
+	for {
+		lexer = &pdfLexer{}
+		if lexer.data, err = p.readAt(position, maxObjectLength); err != nil {
+			return nil, err
+		}
+		first, err := lexer.object()
+		if keyword, ok := first.(pdfKeyword); ok && keyword == "trailer" {
+			trailer, err := lexer.object()
+			if dict, ok := trailer.(pdfDict); ok && err == nil {
+				return dict, nil
+			}
+			return nil, errSyntax
+		}
+		count, errCount := lexer.object()
+		firstNum, ok1 := first.(int64)
+		entries, ok2 := count.(int64)
+		if err != nil || errCount != nil || !ok1 || !ok2 || entries < 0 || entries*18 > p.size {
+			return nil, errSyntax
+		}
+
+		// Entries take 20 bytes, or 19 in files with single byte line ends
+		position += int64(lexer.pos)
+		lexer = &pdfLexer{}
+		if lexer.data, err = p.readAt(position, int(entries*20)+2); err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < entries; i++ {
+			entryOffset, err1 := lexer.object()
+			_, err2 := lexer.object()
+			kind, err3 := lexer.object()
+			entryStart, ok := entryOffset.(int64)
+			if err1 != nil || err2 != nil || err3 != nil || !ok {
+				return nil, errSyntax
+			}
+			if _, known := p.xref[firstNum+i]; !known {
+				p.xref[firstNum+i] = xrefEntry{offset: entryStart, free: kind != pdfKeyword("n")}
+			}
+		}
+		position += int64(lexer.pos)
+	}
+}
+
+// readXrefStream reads the entries of a cross-reference stream
+func (p *pdfReader) readXrefStream(stream pdfStream) error {
+	data, err := p.streamData(stream)
+	if err != nil {
+		return err
+	}
+
+	widths, ok := stream.dict["W"].(pdfArray)
+	if !ok || len(widths) != 3 {
+		return errSyntax
+	}
+	var w [3]int
+	for i, width := range widths {
+		value, ok := width.(int64)
+		if !ok || value < 0 || value > 8 {
+			return errSyntax
+		}
+		w[i] = int(value)
+	}
+	entryLength := w[0] + w[1] + w[2]
+	if entryLength == 0 {
+		return errSyntax
+	}
+
+	index, ok := stream.dict["Index"].(pdfArray)
+	if !ok {
+		size, _ := stream.dict["Size"].(int64)
+		index = pdfArray{int64(0), size}
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		first, ok1 := index[i].(int64)
+		count, ok2 := index[i+1].(int64)
+		if !ok1 || !ok2 {
+			return errSyntax
+		}
+		for num := first; num < first+count && len(data) >= entryLength; num++ {
+			kind := int64(1)
+			if w[0] > 0 {
+				kind = field(data[:w[0]])
+			}
+			second, third := field(data[w[0]:w[0]+w[1]]), field(data[w[0]+w[1]:entryLength])
+			data = data[entryLength:]
+			if _, known := p.xref[num]; known {
+				continue
+			}
+			switch kind {
+			case 0:
+				p.xref[num] = xrefEntry{free: true}
+			case 1:
+				p.xref[num] = xrefEntry{offset: second}
+			case 2:
+				p.xref[num] = xrefEntry{compressed: true, offset: second, index: int(third)}
+			}
+		}
+	}
+	return nil
+}
+
+// field decodes a big-endian number of an xref stream entry
+func field(data []byte) int64 {
+	var value int64
+	for _, b := range data {
+		value = value<<8 | int64(b)
+	}
+	return value
+}
+
+// scanObjects rebuilds the cross-reference table of a small file by searching its object headers.
+// The last definition of an object wins, as it does in incremental updates.
+func (p *pdfReader) scanObjects() error {
+	if p.size > maxScanLength {
+		return errors.New("PDF cross-reference table is broken")
+	}
+	data, err := p.readAt(0, int(p.size))
+	if err != nil {
+		return err
+	}
+	// Objects are parsed from the file in memory from now on, without copying them
+	p.data, p.size = data, int64(len(data))
+
+	matches := objectHeader.FindAllSubmatchIndex(data, maxScannedObjects+1)
+	if len(matches) > maxScannedObjects {
+		return errTooManyObjects
+	}
+	var objectStreams []int64
+	for _, match := range matches {
+		num, ok := parseInt(data[match[2]:match[3]])
+		if !ok {
+			continue
+		}
+		p.xref[num] = xrefEntry{offset: int64(match[2])}
+	}
+	for num := range p.xref {
+		object := p.object(num)
+		if stream, ok := object.(pdfStream); ok {
+			switch stream.dict["Type"] {
+			case pdfName("ObjStm"):
+				objectStreams = append(objectStreams, num)
+			case pdfName("XRef"):
+				p.trailer = stream.dict
+			}
+		}
+		if dict, ok := object.(pdfDict); ok && dict["Type"] == pdfName("Catalog") && p.trailer == nil {
+			p.trailer = pdfDict{"Root": pdfRef{num: num, gen: 0}}
+		}
+	}
+	// Objects of object streams are only known from the stream itself
+	for _, streamNum := range objectStreams {
+		p.registerObjectStream(streamNum)
+	}
+	if index := bytes.LastIndex(data, []byte("trailer")); index >= 0 {
+		lexer := &pdfLexer{data: data, pos: index + len("trailer")}
+		if trailer, err := lexer.object(); err == nil {
+			if dict, ok := trailer.(pdfDict); ok {
+				p.trailer = dict
+			}
+		}
+	}
+	if p.trailer == nil {
+		return errNoDocument
+	}
+	return nil
+}
+
+// registerObjectStream adds the objects of an object stream to the xref of a scanned file
+func (p *pdfReader) registerObjectStream(streamNum int64) {
+	stream, err := p.objectStream(streamNum)
+	if err != nil {
+		return
+	}
+	for index, num := range stream.numbers {
+		if _, known := p.xref[num]; !known {
+			p.xref[num] = xrefEntry{compressed: true, offset: streamNum, index: index}
+		}
+	}
+}
+
+// resolve follows references until it reaches a direct object; objects that cannot be read are nil
+func (p *pdfReader) resolve(value any) any {
+	for depth := 0; depth < maxObjectDepth; depth++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = p.object(ref.num)
+	}
+	return nil
+}
+
+// object returns an object by number, nil when it cannot be read
+func (p *pdfReader) object(num int64) any {
+	if object, ok := p.objects[num]; ok {
+		return object
+	}
+	entry, ok := p.xref[num]
+	if !ok || entry.free || p.depth >= maxObjectDepth {
+		return nil
+	}
+
+	// Guard against objects referring to themselves while they are read, e.g. through Length
+	p.objects[num] = nil
+	p.depth++
+	defer func() { p.depth-- }()
+
+	var object any
+	var err error
+	if entry.compressed {
+		object, err = p.compressedObject(entry.offset, entry.index)
+	} else {
+		object, err = p.objectAt(entry.offset)
+	}
+	if err != nil {
+		return nil
+	}
+	p.objects[num] = object
+	return object
+}
+
+// objectAt parses the "num gen obj" object at an offset of the file. Most objects are small, so a
+// short read is tried before a long one.
+func (p *pdfReader) objectAt(offset int64) (any, error) {
+	object, err := p.parseObjectAt(offset, shortObjectLength)
+	if errors.Is(err, errSyntax) {
+		object, err = p.parseObjectAt(offset, maxObjectLength)
+	}
+	return object, err
+}
+
+func (p *pdfReader) parseObjectAt(offset int64, length int) (any, error) {
+	data, err := p.readAt(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	lexer := &pdfLexer{data: data}
+	if _, err := lexer.object(); err != nil {
+		return nil, err
+	}
+	if _, err := lexer.object(); err != nil {
+		return nil, err
+	}
+	if keyword, err := lexer.object(); err != nil || keyword != pdfKeyword("obj") {
+		return nil, errSyntax
+	}
+	object, err := lexer.object()
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := object.(pdfDict)
+	if !ok {
+		return object, nil
+	}
+	lexer.skipSpace()
+	if !bytes.HasPrefix(data[lexer.pos:], []byte("stream")) {
+		if len(data) == length && len(data)-lexer.pos < len("stream\r\n") {
+			// The keyword may have been cut off
+			return nil, errSyntax
+		}
+		return dict, nil
+	}
+	// The data starts after the end of line following the keyword
+	lexer.pos += len("stream")
+	if lexer.pos < len(data) && data[lexer.pos] == '\r' {
+		lexer.pos++
+	}
+	if lexer.pos < len(data) && data[lexer.pos] == '\n' {
+		lexer.pos++
+	}
+	return pdfStream{dict: dict, offset: offset + int64(lexer.pos)}, nil
+}
+
+// compressedObject parses the index-th object of an object stream
+func (p *pdfReader) compressedObject(streamNum int64, index int) (any, error) {
+	stream, err := p.objectStream(streamNum)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(stream.offsets) || stream.offsets[index] >= len(stream.data) {
+		return nil, errSyntax
+	}
+	lexer := &pdfLexer{data: stream.data, pos: stream.offsets[index]}
+	return lexer.object()
+}
+
+// objectStream reads an object stream: the numbers of its objects, their offsets in its data, and
+// the data. Each stream is decoded once however many of its objects are read.
+func (p *pdfReader) objectStream(streamNum int64) (*objectStream, error) {
+	if stream, ok := p.objectStreams[streamNum]; ok {
+		if stream == nil {
+			return nil, errSyntax
+		}
+		return stream, nil
+	}
+	// Also guards against a stream whose Length is stored in itself
+	p.objectStreams[streamNum] = nil
+
+	stream, ok := p.object(streamNum).(pdfStream)
+	if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+		return nil, errSyntax
+	}
+	count, ok1 := stream.dict["N"].(int64)
+	first, ok2 := stream.dict["First"].(int64)
+	if !ok1 || !ok2 || count < 0 || count > maxStreamObjects || first < 0 {
+		return nil, errSyntax
+	}
+	data, err := p.streamData(stream)
+	if err != nil {
+		return nil, err
+	}
+	// Each object takes two numbers and their separators in the header
+	if first > int64(len(data)) || count*4 > first+1 {
+		return nil, errSyntax
+	}
+
+	lexer := &pdfLexer{data: data[:first]}
+	decoded := &objectStream{numbers: make([]int64, count), offsets: make([]int, count), data: data}
+	for i := range decoded.numbers {
+		num, err1 := lexer.object()
+		offset, err2 := lexer.object()
+		numValue, ok1 := num.(int64)
+		offsetValue, ok2 := offset.(int64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 || offsetValue < 0 || offsetValue > int64(len(data)) {
+			return nil, errSyntax
+		}
+		decoded.numbers[i], decoded.offsets[i] = numValue, int(first+offsetValue)
+	}
+	p.objectStreams[streamNum] = decoded
+	return decoded, nil
+}
+
+// streamData reads and decodes the data of a stream. Only Flate compression, with or without a
+// PNG predictor, is supported, which is what xref and object streams use.
+func (p *pdfReader) streamData(stream pdfStream) ([]byte, error) {
+	length, ok := p.resolve(stream.dict["Length"]).(int64)
+	if !ok || length < 0 || length > maxStreamLength {
+		return nil, errors.New("invalid PDF stream length")
+	}
+	data, err := p.readAt(stream.offset, int(length))
+	if err != nil {
+		return nil, err
+	}
+
+	filter := p.resolve(stream.dict["Filter"])
+	params, _ := p.resolve(stream.dict["DecodeParms"]).(pdfDict)
+	if filters, ok := filter.(pdfArray); ok && len(filters) == 1 {
+		filter = filters[0]
+		if paramsList, ok := p.resolve(stream.dict["DecodeParms"]).(pdfArray); ok && len(paramsList) == 1 {
+			params, _ = p.resolve(paramsList[0]).(pdfDict)
+		}
+	}
+	switch filter {
+	case nil:
+		return data, nil
+	case pdfName("FlateDecode"):
+	default:
+		return nil, fmt.Errorf("unsupported PDF filter %v", filter)
+	}
+
+	// Every stream of the file draws from one budget, so many small streams cannot add up to more
+	// work than a few large ones
+	remaining := maxInflatedLength - p.inflated
+	if remaining <= 0 {
+		return nil, errInflateBudget
+	}
+	inflater, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer inflater.Close()
+	limit := min(maxStreamLength, remaining)
+	decoded, err := io.ReadAll(io.LimitReader(inflater, limit+1))
+	p.inflated += int64(len(decoded))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if int64(len(decoded)) > limit {
+		if limit < maxStreamLength {
+			return nil, errInflateBudget
+		}
+		return nil, errors.New("PDF stream is too large")
+	}
+
+	if predictor, _ := params["Predictor"].(int64); predictor >= 10 {
+		columns, ok := params["Columns"].(int64)
+		if !ok {
+			columns = 1
+		}
+		return unpredictPNG(decoded, int(columns))
+	}
+	return decoded, nil
+}
+
+// unpredictPNG reverses the PNG predictors of rows of one byte per column, the layout of xref streams
+func unpredictPNG(data []byte, columns int) ([]byte, error) {
+	if columns <= 0 || columns > maxObjectLength {
+		return nil, errSyntax
+	}
+	rowLength := columns + 1
+	previous := make([]byte, columns)
+	decoded := make([]byte, 0, len(data)/rowLength*columns)
+	for ; len(data) >= rowLength; data = data[rowLength:] {
+		row := data[1:rowLength]
+		for i := range row {
+			var left, upLeft byte
+			if i > 0 {
+				left, upLeft = row[i-1], previous[i-1]
+			}
+			up := previous[i]
+			switch data[0] {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		decoded = append(decoded, row...)
+		copy(previous, row)
+	}
+	return decoded, nil
+}
+
+// paeth is the Paeth predictor of PNG
+func paeth(left, up, upLeft byte) byte {
+	estimate := int(left) + int(up) - int(upLeft)
+	distanceLeft, distanceUp, distanceUpLeft := abs(estimate-int(left)), abs(estimate-int(up)), abs(estimate-int(upLeft))
+	switch {
+	case distanceLeft <= distanceUp && distanceLeft <= distanceUpLeft:
+		return left
+	case distanceUp <= distanceUpLeft:
+		return up
+	}
+	return upLeft
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// readAt reads up to length bytes at an offset, fewer at the end of the file. The bytes returned
+// must not be modified: they may be part of the file in memory.
+func (p *pdfReader) readAt(offset int64, length int) ([]byte, error) {
+	if offset < 0 || offset >= p.size {
+		return nil, errSyntax
+	}
+	if p.data != nil {
+		return p.data[offset:min(offset+int64(length), p.size)], nil
+	}
+	data := make([]byte, min(int64(length), p.size-offset))
+	n, err := p.r.ReadAt(data, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+// parseInt parses the digits of an object number
+func parseInt(digits []byte) (int64, bool) {
+	lexer := &pdfLexer{data: digits}
+	value, err := lexer.object()
+	number, ok := value.(int64)
+	return number, err == nil && ok
+}
+
+// decodeTextString decodes a PDF text string: UTF-16BE or UTF-8 with a byte order mark, or
+// PDFDocEncoding
+func decodeTextString(value pdfString) string {
+	switch {
+	case bytes.HasPrefix(value, []byte{0xFE, 0xFF}):
+		units := make([]uint16, 0, len(value)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			units = append(units, uint16(value[i])<<8|uint16(value[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(value, []byte{0xEF, 0xBB, 0xBF}):
+		return string(value[3:])
+	}
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+		if b >= 0x80 && b <= 0xA0 {
+			runes[i] = pdfDocEncoding[b-0x80]
+		}
+	}
+	return string(runes)
+}
+
+// pdfDocEncoding maps the bytes 0x80 to 0xA0 of PDFDocEncoding, which differ from Latin-1
+var pdfDocEncoding = [33]rune{
+	'•', '†', '‡', '…', '—', '–', 'ƒ', '⁄', '‹', '›', '−', '‰', '„', '“', '”', '‘',
+	'’', '‚', '™', 'ﬁ', 'ﬂ', 'Ł', 'Œ', 'Š', 'Ÿ', 'Ž', 'ı', 'ł', 'œ', 'š', 'ž', '�',
+	'€',
+}
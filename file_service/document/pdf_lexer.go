@@ -0,0 +1,295 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package document
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// Objects of a PDF file; integers are int64, reals float64, booleans bool and null nil
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int64 }
+)
+
+// errSyntax is returned for objects that cannot be parsed
+var errSyntax = errors.New("invalid PDF syntax")
+
+// maxNesting bounds the depth of nested arrays and dictionaries
+const maxNesting = 32
+
+// pdfLexer parses PDF objects from a buffer
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+// isDelimiter reports whether c ends a name, number or keyword
+func isDelimiter(c byte) bool {
+	return isSpace(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+// skipSpace moves past white space and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object parses the next object; an integer followed by a generation and R is a reference
+func (l *pdfLexer) object() (any, error) {
+	return l.nested(0)
+}
+
+func (l *pdfLexer) nested(depth int) (any, error) {
+	if depth > maxNesting {
+		return nil, errSyntax
+	}
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errSyntax
+	}
+
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		return l.dict(depth)
+	case c == '<':
+		return l.hexString()
+	case c == '[':
+		l.pos++
+		array := pdfArray{}
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, errSyntax
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return array, nil
+			}
+			value, err := l.nested(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	case c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9':
+		return l.number()
+	case isDelimiter(c):
+		return nil, errSyntax
+	}
+
+	switch keyword := l.keyword(); keyword {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return keyword, nil
+	}
+}
+
+// keyword reads a bare word such as obj, stream or R
+func (l *pdfLexer) keyword() pdfKeyword {
+	start := l.pos
+	for l.pos < len(l.data) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return pdfKeyword(l.data[start:l.pos])
+}
+
+// number reads an integer, a real, or a reference starting with an integer
+func (l *pdfLexer) number() (any, error) {
+	word := string(l.keyword())
+	if integer, err := strconv.ParseInt(word, 10, 64); err == nil {
+		// A reference is "num gen R"
+		saved := l.pos
+		l.skipSpace()
+		genStart := l.pos
+		for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+			l.pos++
+		}
+		if l.pos > genStart {
+			gen, _ := strconv.ParseInt(string(l.data[genStart:l.pos]), 10, 64)
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isDelimiter(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{num: integer, gen: gen}, nil
+			}
+		}
+		l.pos = saved
+		return integer, nil
+	}
+	value, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, errSyntax
+	}
+	return value, nil
+}
+
+// name reads a name, decoding #xx escapes
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	var name []byte
+	for l.pos < len(l.data) && !isDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if value, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(value))
+				l.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		l.pos++
+	}
+	return pdfName(name)
+}
+
+// literalString reads a string in parentheses, which may hold balanced parentheses and escapes
+func (l *pdfLexer) literalString() (pdfString, error) {
+	l.pos++
+	var value []byte
+	for depth := 1; l.pos < len(l.data); {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return value, nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return nil, errSyntax
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					octal := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						octal = octal*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(octal)
+				}
+			}
+		}
+		value = append(value, c)
+	}
+	return nil, errSyntax
+}
+
+// hexString reads a string of hexadecimal digits in angle brackets
+func (l *pdfLexer) hexString() (pdfString, error) {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.data) {
+		return nil, errSyntax
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	value := make([]byte, len(digits)/2)
+	for i := range value {
+		b, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, errSyntax
+		}
+		value[i] = byte(b)
+	}
+	return value, nil
+}
+
+// dict reads a dictionary; keys without a value are ignored
+func (l *pdfLexer) dict(depth int) (pdfDict, error) {
+	l.pos += 2
+	dict := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		if l.pos >= len(l.data) || l.data[l.pos] != '/' {
+			return nil, errSyntax
+		}
+		key := l.name()
+		value, err := l.nested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
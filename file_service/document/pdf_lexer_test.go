@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package document
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexerObjects(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  any
+	}{
+		{"42", int64(42)},
+		{"-17", int64(-17)},
+		{"+3", int64(3)},
+		{"3.5", 3.5},
+		{"-.25", -0.25},
+		{"true", true},
+		{"false", false},
+		{"null", nil},
+		{"obj", pdfKeyword("obj")},
+		{"12 0 R", pdfRef{num: 12, gen: 0}},
+		{"12 0", int64(12)},
+		{"12 0 Rx", int64(12)},
+		{"/Name", pdfName("Name")},
+		{"/A#20B#2", pdfName("A B#2")},
+		{"/", pdfName("")},
+		{"(plain)", pdfString("plain")},
+		{"(a (nested) string)", pdfString("a (nested) string")},
+		{`(\n\r\t\b\f\(\)\\)`, pdfString("\n\r\t\b\f()\\")},
+		{`(\101\60\0063)`, pdfString("A0\x063")},
+		{"(line \\\ncontinued)", pdfString("line continued")},
+		{"(line \\\r\ncontinued)", pdfString("line continued")},
+		{"<48 65 6C6c6F>", pdfString("Hello")},
+		{"<414>", pdfString("A@")},
+		{"<>", pdfString{}},
+		{"[1 (two) /Three [4]]", pdfArray{int64(1), pdfString("two"), pdfName("Three"), pdfArray{int64(4)}}},
+		{"[1 0 R 2]", pdfArray{pdfRef{num: 1}, int64(2)}},
+		{"<< /Type /Page /Kids [3 0 R] /Count 1 >>", pdfDict{"Type": pdfName("Page"), "Kids": pdfArray{pdfRef{num: 3}}, "Count": int64(1)}},
+		{"<</A<</B 1>>>>", pdfDict{"A": pdfDict{"B": int64(1)}}},
+		{"% comment\n  /AfterComment", pdfName("AfterComment")},
+	} {
+		lexer := &pdfLexer{data: []byte(test.input)}
+		got, err := lexer.object()
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q = %#v, want %#v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	deep := ""
+	for i := 0; i <= maxNesting+1; i++ {
+		deep += "["
+	}
+	for _, input := range []string{
+		"",
+		"   % only a comment",
+		"(unterminated",
+		"(escape at the end\\",
+		"<4142",
+		"<4G>",
+		"[1 2",
+		"<< /Key 1",
+		"<< 1 2 >>",
+		")",
+		"{",
+		"1.2.3",
+		deep,
+	} {
+		lexer := &pdfLexer{data: []byte(input)}
+		if got, err := lexer.object(); err == nil {
+			t.Errorf("%q parsed as %#v", input, got)
+		}
+	}
+}
+
+func TestLexerSequence(t *testing.T) {
+	lexer := &pdfLexer{data: []byte("1 0 obj\n<< /Length 5 >>\nstream")}
+	var got []any
+	for {
+		object, err := lexer.object()
+		if err != nil {
+			break
+		}
+		got = append(got, object)
+	}
+	want := []any{int64(1), int64(0), pdfKeyword("obj"), pdfDict{"Length": int64(5)}, pdfKeyword("stream")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("objects = %#v, want %#v", got, want)
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, seed := range []string{
+		"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj",
+		"[(a\\(b) <414243> /N#41me 3.14 -2 true null]",
+		"<< /A << /B [1 2 3] >> >>",
+		"% comment\n(\\101\\\n)",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lexer := &pdfLexer{data: data}
+		for {
+			position := lexer.pos
+			if _, err := lexer.object(); err != nil {
+				return
+			}
+			// Every object parsed consumes input, so parsing a file ends
+			if lexer.pos <= position || lexer.pos > len(data) {
+				t.Fatalf("lexer moved from %d to %d of %d", position, lexer.pos, len(data))
+			}
+		}
+	})
+}
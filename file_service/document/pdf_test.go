@@ -0,0 +1,363 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfBuilder writes PDF files object by object and remembers their offsets for the xref
+type pdfBuilder struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func newPDFBuilder() *pdfBuilder {
+	b := &pdfBuilder{offsets: map[int]int{}}
+	b.buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	return b
+}
+
+func (b *pdfBuilder) object(num int, body string) {
+	b.offsets[num] = b.buf.Len()
+	fmt.Fprintf(&b.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+func (b *pdfBuilder) stream(num int, dict string, data []byte) {
+	b.offsets[num] = b.buf.Len()
+	fmt.Fprintf(&b.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", num, dict, len(data))
+	b.buf.Write(data)
+	b.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// xrefTable ends the file with a cross-reference table of the objects written so far
+func (b *pdfBuilder) xrefTable(trailer string) []byte {
+	size := 0
+	for num := range b.offsets {
+		size = max(size, num+1)
+	}
+	start := b.buf.Len()
+	fmt.Fprintf(&b.buf, "xref\n0 %d\n", size)
+	for num := 0; num < size; num++ {
+		if offset, ok := b.offsets[num]; ok {
+			fmt.Fprintf(&b.buf, "%010d 00000 n\r\n", offset)
+		} else {
+			b.buf.WriteString("0000000000 65535 f\r\n")
+		}
+	}
+	fmt.Fprintf(&b.buf, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", size, trailer, start)
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// objectStreamData lays out objects for an object stream and returns the data and its First
+func objectStreamData(nums []int, bodies []string) ([]byte, int) {
+	var header, objects strings.Builder
+	for i, num := range nums {
+		fmt.Fprintf(&header, "%d %d ", num, objects.Len())
+		objects.WriteString(bodies[i] + "\n")
+	}
+	return []byte(header.String() + objects.String()), header.Len()
+}
+
+func analyzeBytes(data []byte) (Info, error) {
+	return analyzePDF(bytes.NewReader(data), int64(len(data)))
+}
+
+// simplePDF has three pages and a title in its document information
+func simplePDF(title string) []byte {
+	b := newPDFBuilder()
+	b.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	b.object(2, "<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 >>")
+	for num := 3; num <= 5; num++ {
+		b.object(num, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>")
+	}
+	b.object(6, "<< /Title "+title+" /Producer (test) >>")
+	return b.xrefTable("/Root 1 0 R /Info 6 0 R")
+}
+
+func TestAnalyzePDF(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"literal title", "(Quarterly report)", "Quarterly report"},
+		{"escaped title", `(Report \(draft\)\t\101)`, "Report (draft)\tA"},
+		{"utf-16 title", "<FEFF004200E4007200200444>", "Bär ф"},
+		{"utf-8 title", "<EFBBBF4ec3a9>", "Né"},
+		{"pdfdoc title", "(\x8dquoted\x8e \x80)", "“quoted” •"},
+		{"hex title with odd digits", "<48692>", "Hi"},
+	} {
+		info, err := analyzeBytes(simplePDF(test.title))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if info.PageCount != 3 || info.Title != test.want {
+			t.Errorf("%s: %+v, want 3 pages and title %q", test.name, info, test.want)
+		}
+	}
+}
+
+func TestAnalyzePDFEncrypted(t *testing.T) {
+	b := newPDFBuilder()
+	b.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	b.object(2, "<< /Type /Pages /Kids [] /Count 7 >>")
+	b.object(3, "<< /Title (\x8f\x12\xa7) >>")
+	b.object(4, "<< /Filter /Standard /V 2 /R 3 >>")
+	info, err := analyzeBytes(b.xrefTable("/Root 1 0 R /Info 3 0 R /Encrypt 4 0 R"))
+	if err != nil || info.PageCount != 7 || info.Title != "" {
+		t.Fatalf("encrypted PDF: %+v, %v; want 7 pages without title", info, err)
+	}
+}
+
+func TestAnalyzePDFIncrementalUpdate(t *testing.T) {
+	original := simplePDF("(First)")
+	b := &pdfBuilder{offsets: map[int]int{}}
+	b.buf.Write(original)
+	b.object(6, "<< /Title (Second) >>")
+	// The update section only lists the changed object and points to the previous section
+	start := b.buf.Len()
+	fmt.Fprintf(&b.buf, "xref\n6 1\n%010d 00000 n\r\ntrailer\n<< /Size 7 /Root 1 0 R /Info 6 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n",
+		b.offsets[6], bytes.LastIndex(original, []byte("xref\n")), start)
+
+	info, err := analyzeBytes(b.buf.Bytes())
+	if err != nil || info.PageCount != 3 || info.Title != "Second" {
+		t.Fatalf("updated PDF: %+v, %v; want the newer title", info, err)
+	}
+}
+
+// compressedPDF stores its catalog, pages and information in an object stream listed by an xref
+// stream with a PNG predictor, as PDF 1.5 writers do
+func compressedPDF(t *testing.T) []byte {
+	t.Helper()
+	b := newPDFBuilder()
+	data, first := objectStreamData([]int{1, 2, 3, 4}, []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Title (Compressed) >>",
+	})
+	b.stream(5, fmt.Sprintf("/Type /ObjStm /N 4 /First %d /Filter /FlateDecode", first), deflate(data))
+
+	// Entries of W [1 2 1]: object 0 free, 1-4 in stream 5, 5 and 6 in the file
+	xrefOffset := b.buf.Len()
+	entries := [][]byte{
+		{0, 0, 0, 0},
+		{2, 0, 5, 0}, {2, 0, 5, 1}, {2, 0, 5, 2}, {2, 0, 5, 3},
+		{1, byte(b.offsets[5] >> 8), byte(b.offsets[5]), 0},
+		{1, byte(xrefOffset >> 8), byte(xrefOffset), 0},
+	}
+	// Predictor 12 encodes each row as the difference to the row above (PNG Up)
+	var rows []byte
+	previous := make([]byte, 4)
+	for _, entry := range entries {
+		rows = append(rows, 2)
+		for i, value := range entry {
+			rows = append(rows, value-previous[i])
+		}
+		previous = entry
+	}
+	b.stream(6, "/Type /XRef /Size 7 /W [1 2 1] /Root 1 0 R /Info 4 0 R /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 4 >>", deflate(rows))
+	fmt.Fprintf(&b.buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return b.buf.Bytes()
+}
+
+func TestAnalyzePDFXrefStream(t *testing.T) {
+	info, err := analyzeBytes(compressedPDF(t))
+	if err != nil || info.PageCount != 1 || info.Title != "Compressed" {
+		t.Fatalf("compressed PDF: %+v, %v", info, err)
+	}
+}
+
+func TestAnalyzePDFBrokenXref(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"wrong startxref":    bytes.Replace(simplePDF("(Recovered)"), []byte("startxref\n"), []byte("startxref\n1"), 1),
+		"no xref":            simplePDF("(Recovered)")[:bytes.Index(simplePDF("(Recovered)"), []byte("xref\n0"))],
+		"compressed objects": bytes.Replace(compressedPDF(t), []byte("startxref\n"), []byte("startxref\n9"), 1),
+	} {
+		info, err := analyzeBytes(data)
+		if err != nil || info.PageCount == 0 {
+			t.Errorf("%s: %+v, %v", name, info, err)
+		}
+	}
+}
+
+func TestAnalyzePDFInvalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":        nil,
+		"header only":  []byte("%PDF-1.4\n"),
+		"no catalog":   []byte("%PDF-1.4\n1 0 obj\n<< /Foo 1 >>\nendobj\ntrailer\n<< /Size 2 >>\n"),
+		"looping refs": []byte("%PDF-1.4\n1 0 obj\n2 0 R\nendobj\n2 0 obj\n1 0 R\nendobj\ntrailer\n<< /Root 1 0 R /Info 2 0 R >>\n"),
+	} {
+		if info, err := analyzeBytes(data); err == nil {
+			t.Errorf("%s: analyzed as %+v", name, info)
+		}
+	}
+}
+
+func TestObjectStreamsAreDecodedOnce(t *testing.T) {
+	// One object stream holding many objects, all of them read
+	var nums []int
+	var bodies []string
+	for num := 10; num < 60; num++ {
+		nums = append(nums, num)
+		bodies = append(bodies, fmt.Sprintf("<< /Value %d /Padding (%s) >>", num, strings.Repeat("x", 1000)))
+	}
+	data, first := objectStreamData(nums, bodies)
+	b := newPDFBuilder()
+	b.stream(1, fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(nums), first), deflate(data))
+	file := b.xrefTable("")
+
+	reader := &pdfReader{r: bytes.NewReader(file), size: int64(len(file)), xref: map[int64]xrefEntry{}, objects: map[int64]any{}, objectStreams: map[int64]*objectStream{}}
+	if err := reader.loadXref(); err != nil {
+		t.Fatal(err)
+	}
+	for index, num := range nums {
+		reader.xref[int64(num)] = xrefEntry{compressed: true, offset: 1, index: index}
+	}
+	for _, num := range nums {
+		dict, ok := reader.object(int64(num)).(pdfDict)
+		if !ok || dict["Value"] != int64(num) {
+			t.Fatalf("object %d = %v", num, reader.object(int64(num)))
+		}
+	}
+	if reader.inflated != int64(len(data)) {
+		t.Fatalf("decoded %d bytes for a stream of %d", reader.inflated, len(data))
+	}
+}
+
+func TestObjectStreamLimits(t *testing.T) {
+	for name, dict := range map[string]string{
+		"huge N":             "/Type /ObjStm /N 16000000 /First 4",
+		"N beyond the index": "/Type /ObjStm /N 100 /First 4",
+		"negative First":     "/Type /ObjStm /N 1 /First -1",
+	} {
+		b := newPDFBuilder()
+		b.stream(1, dict, []byte("5 0 << /A 1 >>"))
+		file := b.xrefTable("")
+		reader := &pdfReader{r: bytes.NewReader(file), size: int64(len(file)), xref: map[int64]xrefEntry{}, objects: map[int64]any{}, objectStreams: map[int64]*objectStream{}}
+		if err := reader.loadXref(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reader.objectStream(1); err == nil {
+			t.Errorf("%s: object stream accepted", name)
+		}
+	}
+}
+
+// Many object streams inflating to a lot of data in a file whose xref is broken
+func TestAnalyzePDFInflateBudget(t *testing.T) {
+	b := newPDFBuilder()
+	b.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	b.object(2, "<< /Type /Pages /Count 1 >>")
+	padding := deflate(make([]byte, 4<<20))
+	for num := 3; num < 60; num++ {
+		b.stream(num, "/Type /ObjStm /N 1 /First 4 /Filter /FlateDecode", padding)
+	}
+	data := b.buf.Bytes()
+
+	reader := &pdfReader{r: bytes.NewReader(data), size: int64(len(data)), xref: map[int64]xrefEntry{}, objects: map[int64]any{}, objectStreams: map[int64]*objectStream{}}
+	start := time.Now()
+	if err := reader.scanObjects(); err != nil {
+		t.Fatal(err)
+	}
+	if reader.inflated > maxInflatedLength {
+		t.Fatalf("decoded %d bytes, more than the budget of %d", reader.inflated, maxInflatedLength)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("scan took %v", elapsed)
+	}
+	stream, ok := reader.object(3).(pdfStream)
+	if !ok {
+		t.Fatal("object stream 3 not found")
+	}
+	if _, err := reader.streamData(stream); !errors.Is(err, errInflateBudget) {
+		t.Fatalf("streamData after the budget = %v, want errInflateBudget", err)
+	}
+
+	info, err := analyzeBytes(data)
+	if err != nil || info.PageCount != 1 {
+		t.Fatalf("analyzePDF = %+v, %v", info, err)
+	}
+}
+
+func TestScanObjectsLimit(t *testing.T) {
+	data := []byte("%PDF-1.4\n" + strings.Repeat("1 0 obj\nnull\nendobj\n", maxScannedObjects+1))
+	if _, err := analyzeBytes(data); !errors.Is(err, errTooManyObjects) {
+		t.Fatalf("analyzePDF = %v, want errTooManyObjects", err)
+	}
+}
+
+func TestUnpredictPNG(t *testing.T) {
+	// Rows of 3 columns with the None, Sub, Up, Average and Paeth predictors
+	encoded := []byte{
+		0, 10, 20, 30,
+		1, 5, 5, 5,
+		2, 1, 1, 1,
+		3, 3, 4, 5,
+		4, 1, 2, 3,
+	}
+	decoded, err := unpredictPNG(encoded, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{10, 20, 30, 5, 10, 15, 6, 11, 16, 6, 12, 19, 7, 14, 22}
+	if !bytes.Equal(decoded, want) {
+		t.Fatalf("unpredictPNG = %v, want %v", decoded, want)
+	}
+	if _, err := unpredictPNG(encoded, 0); err == nil {
+		t.Fatal("unpredictPNG accepted 0 columns")
+	}
+}
+
+func FuzzAnalyzePDF(f *testing.F) {
+	f.Add(simplePDF("(Title)"))
+	f.Add(simplePDF("<FEFF0041>"))
+	f.Add(bytes.Replace(simplePDF("(Title)"), []byte("startxref\n"), []byte("startxref\n1"), 1))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n"))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 2 /First 8 /Length 20 >>\nstream\n2 0 3 5 null 7 0 R\nendstream\nendobj\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := &pdfReader{r: bytes.NewReader(data), size: int64(len(data)), xref: map[int64]xrefEntry{}, objects: map[int64]any{}, objectStreams: map[int64]*objectStream{}}
+		reader.loadXref()
+		reader.scanObjects()
+		if reader.inflated > 2*maxInflatedLength {
+			t.Fatalf("decoded %d bytes", reader.inflated)
+		}
+		info, err := analyzeBytes(data)
+		if err == nil && info.PageCount == 0 && info.Title == "" {
+			t.Fatal("analyzePDF succeeded without information")
+		}
+	})
+}
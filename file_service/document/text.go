@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package document
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// analyzeText reads the snippet of a text file after detecting its encoding
+func analyzeText(r io.ReaderAt, size int64) (Info, error) {
+	// Enough bytes for SnippetLength characters of any supported encoding, after a byte order mark
+	head := make([]byte, min(size, SnippetLength*utf8.UTFMax+3))
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return Info{}, err
+	}
+	head = head[:n]
+
+	name, textEncoding := detectEncoding(head, int64(n) < size)
+	decoded, err := textEncoding.NewDecoder().Bytes(head)
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{Snippet: cleanText(string(decoded), SnippetLength), Encoding: name}, nil
+}
+
+// detectEncoding picks the encoding of text from its byte order mark, falling back to Windows-1252
+// when it is not UTF-8. cut tells whether head is followed by more text.
+func detectEncoding(head []byte, cut bool) (string, encoding.Encoding) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8", unicode.UTF8BOM
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return "utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return "utf-16be", unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case utf8.Valid(head), cut && validUTF8Prefix(head):
+		return "utf-8", unicode.UTF8
+	}
+	return "windows-1252", charmap.Windows1252
+}
+
+// validUTF8Prefix reports whether head is UTF-8 up to a character cut off at its end
+func validUTF8Prefix(head []byte) bool {
+	for cut := 1; cut < utf8.UTFMax && cut <= len(head); cut++ {
+		if utf8.Valid(head[:len(head)-cut]) {
+			return !utf8.FullRune(head[len(head)-cut:])
+		}
+	}
+	return false
+}
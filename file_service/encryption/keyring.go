@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DataKeySize is the size of the AES-256 keys encrypting file contents and of the master keys
+const DataKeySize = 32
+
+// ErrUnknownKey is returned when a data key was wrapped by a master key that is not configured
+var ErrUnknownKey = errors.New("unknown master key")
+
+// Keyring holds the master keys wrapping the data keys, by key ID. New data keys are wrapped by
+// the current key; the others are kept to unwrap data keys until they are re-wrapped.
+type Keyring struct {
+	CurrentId string
+	keys      map[string]cipher.AEAD
+}
+
+// ParseKeyring reads master keys from "id:base64key" pairs separated by commas, e.g.
+// "2024-01:...,2024-07:...". currentId must be one of them.
+func ParseKeyring(masterKeys, currentId string) (*Keyring, error) {
+	keyring := &Keyring{CurrentId: currentId, keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(masterKeys, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("invalid master key entry %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != DataKeySize {
+			return nil, fmt.Errorf("master key %s must be %d base64 encoded bytes", id, DataKeySize)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[id] = aead
+	}
+	if _, ok := keyring.keys[currentId]; !ok {
+		return nil, fmt.Errorf("current master key %s is not configured", currentId)
+	}
+	return keyring, nil
+}
+
+// NewDataKey generates a data key and returns it along with its form wrapped by the current master key
+func (k *Keyring) NewDataKey() (dataKey []byte, keyId string, wrapped []byte, err error) {
+	dataKey = make([]byte, DataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, "", nil, err
+	}
+	wrapped, err = k.wrap(k.CurrentId, dataKey)
+	return dataKey, k.CurrentId, wrapped, err
+}
+
+// Unwrap decrypts a data key wrapped by the master key keyId
+func (k *Keyring) Unwrap(keyId string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, keyId)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped data key is too short")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(keyId))
+}
+
+// Rewrap wraps a data key wrapped by keyId with the current master key instead
+func (k *Keyring) Rewrap(keyId string, wrapped []byte) (string, []byte, error) {
+	dataKey, err := k.Unwrap(keyId, wrapped)
+	if err != nil {
+		return "", nil, err
+	}
+	rewrapped, err := k.wrap(k.CurrentId, dataKey)
+	return k.CurrentId, rewrapped, err
+}
+
+// wrap encrypts a data key with a master key; the key ID is authenticated so wrapped keys cannot be swapped
+func (k *Keyring) wrap(keyId string, dataKey []byte) ([]byte, error) {
+	aead := k.keys[keyId]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyId)), nil
+}
+
+// newGCM creates an AES-GCM cipher from a 256-bit key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
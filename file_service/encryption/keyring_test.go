@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func masterKey(t *testing.T, id string) string {
+	t.Helper()
+	key := make([]byte, DataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+func TestParseKeyring(t *testing.T) {
+	valid := masterKey(t, "2024-01")
+	for name, test := range map[string]struct{ masterKeys, currentId string }{
+		"missing id":          {":" + base64.StdEncoding.EncodeToString(make([]byte, DataKeySize)), ""},
+		"missing separator":   {"2024-01", "2024-01"},
+		"not base64":          {"2024-01:***", "2024-01"},
+		"short key":           {"2024-01:" + base64.StdEncoding.EncodeToString(make([]byte, 16)), "2024-01"},
+		"unknown current key": {valid, "2024-07"},
+	} {
+		if _, err := ParseKeyring(test.masterKeys, test.currentId); err == nil {
+			t.Errorf("%s: keyring was accepted", name)
+		}
+	}
+	if _, err := ParseKeyring(valid+", "+masterKey(t, "2024-07"), "2024-07"); err != nil {
+		t.Errorf("valid keyring: %v", err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	oldKey, newKey := masterKey(t, "old"), masterKey(t, "new")
+	before, err := ParseKeyring(oldKey, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, keyId, wrapped, err := before.NewDataKey()
+	if err != nil || keyId != "old" {
+		t.Fatalf("NewDataKey = %s, %v", keyId, err)
+	}
+
+	// After the rotation the data key is wrapped by the new master key alone
+	rotated, err := ParseKeyring(oldKey+","+newKey, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyId, rewrapped, err := rotated.Rewrap(keyId, wrapped)
+	if err != nil || keyId != "new" {
+		t.Fatalf("Rewrap = %s, %v", keyId, err)
+	}
+	after, err := ParseKeyring(newKey, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwrapped, err := after.Unwrap(keyId, rewrapped)
+	if err != nil || !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("unwrapping the rewrapped key = %v", err)
+	}
+	if _, err := after.Unwrap("old", wrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unwrapping with a retired master key = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestUnwrapChecksKeyId(t *testing.T) {
+	keyring, err := ParseKeyring(masterKey(t, "a")+","+masterKey(t, "b"), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, keyId, wrapped, err := keyring.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := keyring.Unwrap("c", wrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown key ID: %v, want %v", err, ErrUnknownKey)
+	}
+	if _, _, err := keyring.Rewrap("c", wrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("rewrapping with an unknown key ID: %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err := keyring.Unwrap("b", wrapped); err == nil {
+		t.Error("a data key was unwrapped with the wrong master key")
+	}
+	tampered := bytes.Clone(wrapped)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := keyring.Unwrap(keyId, tampered); err == nil {
+		t.Error("a tampered data key was unwrapped")
+	}
+	if _, err := keyring.Unwrap(keyId, wrapped[:4]); err == nil {
+		t.Error("a truncated data key was unwrapped")
+	}
+}
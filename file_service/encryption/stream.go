@@ -0,0 +1,209 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Layout of an encrypted object: a header followed by the content in chunks of ChunkSize bytes,
+// each sealed with AES-GCM. Every object gets its own key, derived from the data key and the
+// random salt of its header. The nonce holds the chunk index and marks the last chunk, so chunks
+// cannot be reordered and the content cannot be truncated unnoticed.
+const (
+	ChunkSize  = 64 * 1024
+	HeaderSize = len(magic) + saltSize
+
+	saltSize = 16
+	tagSize  = 16
+)
+
+// magic identifies the format version of encrypted objects
+const magic = "UEC1"
+
+// EncryptedSize is the size of the encrypted object holding plainSize bytes
+func EncryptedSize(plainSize int64) int64 {
+	return int64(HeaderSize) + plainSize + chunkCount(plainSize)*tagSize
+}
+
+// PlainSize is the size of the content of an encrypted object of encryptedSize bytes
+func PlainSize(encryptedSize int64) int64 {
+	sealed := encryptedSize - int64(HeaderSize)
+	chunks := (sealed + ChunkSize + tagSize - 1) / (ChunkSize + tagSize)
+	return sealed - chunks*tagSize
+}
+
+// ChunkRange is the byte range of the encrypted object holding the chunks of plaintext bytes
+// start to end, both inclusive. Decrypting it needs the header too.
+func ChunkRange(start, end, plainSize int64) (int64, int64) {
+	first, last := start/ChunkSize, end/ChunkSize
+	from := int64(HeaderSize) + first*(ChunkSize+tagSize)
+	to := int64(HeaderSize) + last*(ChunkSize+tagSize) + chunkLength(last, plainSize) + tagSize - 1
+	return from, to
+}
+
+// NewEncrypter returns a reader producing the encrypted object of the plainSize bytes of plaintext
+func NewEncrypter(dataKey []byte, plaintext io.Reader, plainSize int64) (io.Reader, error) {
+	header := make([]byte, HeaderSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, err
+	}
+	aead, err := objectCipher(dataKey, header)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypter{aead: aead, source: plaintext, plainSize: plainSize, pending: header}, nil
+}
+
+// NewDecrypter returns a reader producing plaintext bytes start to end of an encrypted object.
+// header is the start of the object and chunks its ChunkRange(start, end, plainSize).
+func NewDecrypter(dataKey, header []byte, chunks io.Reader, start, end, plainSize int64) (io.Reader, error) {
+	if len(header) != HeaderSize || string(header[:len(magic)]) != magic {
+		return nil, errors.New("not an encrypted object")
+	}
+	aead, err := objectCipher(dataKey, header)
+	if err != nil {
+		return nil, err
+	}
+	return &decrypter{
+		aead:      aead,
+		source:    chunks,
+		plainSize: plainSize,
+		index:     start / ChunkSize,
+		skip:      start % ChunkSize,
+		remaining: end - start + 1,
+	}, nil
+}
+
+// encrypter seals the plaintext chunk by chunk while it is read
+type encrypter struct {
+	aead      cipher.AEAD
+	source    io.Reader
+	plainSize int64
+	index     int64
+	pending   []byte // Encrypted bytes not returned yet
+	done      bool
+}
+
+func (e *encrypter) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		length := chunkLength(e.index, e.plainSize)
+		chunk := make([]byte, length, length+tagSize)
+		if _, err := io.ReadFull(e.source, chunk); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		last := e.index == chunkCount(e.plainSize)-1
+		e.pending = e.aead.Seal(chunk[:0], chunkNonce(e.index, last), chunk, nil)
+		e.index++
+		e.done = last
+	}
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// decrypter opens the chunks of a range one at a time, checking each before returning any of it
+type decrypter struct {
+	aead      cipher.AEAD
+	source    io.Reader
+	plainSize int64
+	index     int64
+	skip      int64 // Bytes of the first chunk before the range
+	remaining int64 // Bytes of the range not returned yet
+	pending   []byte
+}
+
+func (d *decrypter) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.remaining <= 0 {
+			return 0, io.EOF
+		}
+		sealed := make([]byte, chunkLength(d.index, d.plainSize)+tagSize)
+		if _, err := io.ReadFull(d.source, sealed); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		last := d.index == chunkCount(d.plainSize)-1
+		chunk, err := d.aead.Open(sealed[:0], chunkNonce(d.index, last), sealed, nil)
+		if err != nil {
+			return 0, fmt.Errorf("chunk %d of encrypted object: %w", d.index, err)
+		}
+		chunk = chunk[d.skip:]
+		if int64(len(chunk)) > d.remaining {
+			chunk = chunk[:d.remaining]
+		}
+		d.pending = chunk
+		d.remaining -= int64(len(chunk))
+		d.skip = 0
+		d.index++
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// objectCipher derives the key of one object from the data key and the salt of its header
+func objectCipher(dataKey, header []byte) (cipher.AEAD, error) {
+	objectKey := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, dataKey, header[len(magic):], []byte(magic)), objectKey); err != nil {
+		return nil, err
+	}
+	return newGCM(objectKey)
+}
+
+// chunkNonce is the 96-bit nonce of a chunk: its index followed by a flag marking the last chunk
+func chunkNonce(index int64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, uint64(index))
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// chunkCount is the number of chunks of plainSize bytes; empty content still has one empty chunk
+func chunkCount(plainSize int64) int64 {
+	if plainSize == 0 {
+		return 1
+	}
+	return (plainSize + ChunkSize - 1) / ChunkSize
+}
+
+// chunkLength is the plaintext length of chunk index
+func chunkLength(index, plainSize int64) int64 {
+	return min(ChunkSize, plainSize-index*ChunkSize)
+}
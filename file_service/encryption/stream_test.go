@@ -0,0 +1,182 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+// Plaintext sizes around the chunk boundaries
+var testSizes = []int64{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 100}
+
+func randomBytes(t *testing.T, n int64) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func encrypt(t *testing.T, dataKey, plaintext []byte) []byte {
+	t.Helper()
+	encrypter, err := NewEncrypter(dataKey, bytes.NewReader(plaintext), int64(len(plaintext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, err := io.ReadAll(encrypter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+// decryptRange decrypts plaintext bytes start to end of an object the way downloads read it:
+// the header and the chunks of the range only
+func decryptRange(dataKey, object []byte, start, end, plainSize int64) ([]byte, error) {
+	from, to := ChunkRange(start, end, plainSize)
+	chunks := object[min(from, int64(len(object))):min(to+1, int64(len(object)))]
+	decrypter, err := NewDecrypter(dataKey, object[:HeaderSize], bytes.NewReader(chunks), start, end, plainSize)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(decrypter)
+}
+
+func TestRoundTrip(t *testing.T) {
+	dataKey := randomBytes(t, DataKeySize)
+	for _, size := range testSizes {
+		plaintext := randomBytes(t, size)
+		object := encrypt(t, dataKey, plaintext)
+		if int64(len(object)) != EncryptedSize(size) {
+			t.Errorf("%d bytes: encrypted to %d bytes, EncryptedSize = %d", size, len(object), EncryptedSize(size))
+		}
+		if got := PlainSize(int64(len(object))); got != size {
+			t.Errorf("%d bytes: PlainSize = %d", size, got)
+		}
+		if size == 0 {
+			continue
+		}
+		decrypted, err := decryptRange(dataKey, object, 0, size-1, size)
+		if err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%d bytes: round trip failed: %v", size, err)
+		}
+	}
+
+	// Every object has its own key: the same content encrypts differently
+	plaintext := randomBytes(t, 100)
+	if bytes.Equal(encrypt(t, dataKey, plaintext), encrypt(t, dataKey, plaintext)) {
+		t.Error("the same content was encrypted to the same object twice")
+	}
+}
+
+func TestRangedDecryption(t *testing.T) {
+	dataKey := randomBytes(t, DataKeySize)
+	size := int64(3*ChunkSize + 100)
+	plaintext := randomBytes(t, size)
+	object := encrypt(t, dataKey, plaintext)
+
+	for _, test := range []struct{ start, end int64 }{
+		{0, 0},
+		{0, ChunkSize - 1},
+		{ChunkSize - 1, ChunkSize},
+		{ChunkSize, 2*ChunkSize - 1},
+		{12345, 12345 + 3},
+		{100, 3*ChunkSize + 50},
+		{3 * ChunkSize, size - 1},
+		{size - 1, size - 1},
+		{0, size - 1},
+	} {
+		decrypted, err := decryptRange(dataKey, object, test.start, test.end, size)
+		if err != nil {
+			t.Errorf("bytes %d-%d: %v", test.start, test.end, err)
+			continue
+		}
+		if !bytes.Equal(decrypted, plaintext[test.start:test.end+1]) {
+			t.Errorf("bytes %d-%d: decrypted %d bytes that differ from the plaintext", test.start, test.end, len(decrypted))
+		}
+	}
+}
+
+func TestTamperingIsDetected(t *testing.T) {
+	dataKey := randomBytes(t, DataKeySize)
+	size := int64(3*ChunkSize + 100)
+	plaintext := randomBytes(t, size)
+	object := encrypt(t, dataKey, plaintext)
+	chunk := func(index int) []byte {
+		from := HeaderSize + index*(ChunkSize+tagSize)
+		return object[from:min(from+ChunkSize+tagSize, len(object))]
+	}
+
+	flipped := bytes.Clone(object)
+	flipped[HeaderSize+ChunkSize+tagSize+10] ^= 1
+	badSalt := bytes.Clone(object)
+	badSalt[len(magic)] ^= 1
+	swapped := slices.Concat(object[:HeaderSize], chunk(1), chunk(0), chunk(2), chunk(3))
+	lastDropped := object[:HeaderSize+3*(ChunkSize+tagSize)]
+	cut := object[:len(object)-10]
+
+	for _, test := range []struct {
+		name      string
+		object    []byte
+		plainSize int64
+		dataKey   []byte
+	}{
+		{"tampered chunk", flipped, size, dataKey},
+		{"tampered salt", badSalt, size, dataKey},
+		{"reordered chunks", swapped, size, dataKey},
+		// A download trusting the object size sees a shorter file whose last chunk is not marked as last
+		{"dropped final chunk", lastDropped, PlainSize(int64(len(lastDropped))), dataKey},
+		{"truncated final chunk", cut, size, dataKey},
+		{"wrong key", object, size, randomBytes(t, DataKeySize)},
+	} {
+		decrypted, err := decryptRange(test.dataKey, test.object, 0, test.plainSize-1, test.plainSize)
+		if err == nil {
+			t.Errorf("%s: decrypted %d bytes", test.name, len(decrypted))
+		}
+	}
+
+	// Chunks are checked on their own: ranges before the tampered chunk still decrypt
+	decrypted, err := decryptRange(dataKey, flipped, 10, ChunkSize-1, size)
+	if err != nil || !bytes.Equal(decrypted, plaintext[10:ChunkSize]) {
+		t.Errorf("range before the tampered chunk: %v", err)
+	}
+	if _, err := decryptRange(dataKey, flipped, ChunkSize+5, ChunkSize+6, size); err == nil {
+		t.Error("range in the tampered chunk was decrypted")
+	}
+	if _, err := NewDecrypter(dataKey, []byte("not a header at all!"), bytes.NewReader(nil), 0, 0, 1); err == nil {
+		t.Error("an object without header was accepted")
+	}
+}
+
+func TestEncrypterNeedsTheDeclaredSize(t *testing.T) {
+	encrypter, err := NewEncrypter(randomBytes(t, DataKeySize), bytes.NewReader(make([]byte, ChunkSize+10)), ChunkSize+11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(encrypter); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("encrypting a short plaintext = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -22,15 +22,49 @@ package env
 import (
 	"log"
 	"os"
+
+	"urulink.com/platform/config"
 )
 
 // EnvManger is a struct that stores environment configurations
-// for connecting to MinIO storage service.
+// for connecting to the storage backend and the other urulink services.
 type EnvManger struct {
-	MinioHost   string // MinIO server host address
-	MinioKey    string // MinIO access key
-	MinioSecret string // MinIO secret key
-	MinioBucket string // MinIO bucket name
+	StorageBackend    string // "minio" (MinIO or another S3 compatible service) or "local"
+	MinioHost         string // MinIO server host address
+	MinioKey          string // MinIO access key
+	MinioSecret       string // MinIO secret key
+	MinioBucket       string // MinIO bucket name
+	MinioSecure       string // "true" to connect to MinIO over TLS
+	MinioRegion       string // Region of the bucket
+	LocalStoragePath  string // Directory of the local storage backend
+	StoragePublicUrl  string // Public base URL of the file service, used in presigned URLs of the local backend
+	StorageSigningKey string // Secret signing presigned URLs of the local backend
+	AuthServiceUrl    string // Base URL of the auth service, validating the access tokens of requests
+	MessageServiceUrl string // Base URL of the message service, used for file access checks
+	ServiceToken      string // Secret shared with the message service for calls made without a user
+	DBHost            string // Database host address
+	DBUser            string // Database username
+	DBPassword        string // Database password
+	DBName            string // Name of the database
+	DBPort            string // Database port number
+
+	MasterKeys   string // Master keys wrapping the data keys: comma separated "id:base64key" pairs
+	MasterKeyId  string // ID of the master key wrapping new data keys
+	Scanner      string // "clamd" or "fake", which only detects the EICAR test file
+	ClamdAddress string // Address of clamd: tcp://host:port or unix:///path/to/clamd.sock
+
+	FilePolicyPath     string // JSON file with the file type policy; the built-in policy applies when empty
+	StripImageMetadata string // "false" keeps EXIF and other metadata of uploaded images, anything else strips it
+	DefaultUserQuota   string // Bytes each user may store unless their own or their group quota says otherwise
+	QuotaAdmins        string // Comma separated user IDs allowed to change quotas
+
+	GCInterval    string // How often the garbage collector runs, e.g. "24h"; it only runs as a subcommand when empty
+	GCGracePeriod string // Age before files never sent in a message and untracked objects are removed
+	GCDryRun      string // "true" makes scheduled collections only report what they would remove
+
+	PublicUrl  string // Public base URL of the file service prefixed to file links; links are relative when empty
+	LinkKey    string // Secret signing the file links
+	LinkExpiry string // How long a file link is valid, e.g. "15m"
 }
 
 // NewEnv initializes a new EnvManger instance and loads environment variables.
@@ -38,21 +72,68 @@ type EnvManger struct {
 func NewEnv() *EnvManger {
 	env := &EnvManger{} // Initialize EnvManger struct
 
-	// Helper function to load environment variables into struct fields
-	loadEnv := func(envVar string, target *string) {
-		// Lookup environment variable
-		value, ok := os.LookupEnv(envVar)
-		if !ok {
-			log.Fatalf("Environment variable %s not found", envVar) // Log fatal error if variable is missing
-		}
-		*target = value // Set the value to the target field
+	// Load the settings of the selected storage backend
+	config.Optional("STORAGE_BACKEND", &env.StorageBackend, "minio")
+	switch env.StorageBackend {
+	case "minio":
+		config.Required("MINIO_HOST", &env.MinioHost)
+		config.Required("MINIO_KEY", &env.MinioKey)
+		config.Required("MINIO_SECRET", &env.MinioSecret)
+		config.Required("MINIO_BUCKET", &env.MinioBucket)
+		config.Optional("MINIO_SECURE", &env.MinioSecure, "false")
+		config.Optional("MINIO_REGION", &env.MinioRegion, "us-east-1")
+	case "local":
+		config.Required("LOCAL_STORAGE_PATH", &env.LocalStoragePath)
+		config.Required("STORAGE_PUBLIC_URL", &env.StoragePublicUrl)
+		config.Required("STORAGE_SIGNING_KEY", &env.StorageSigningKey)
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %s", env.StorageBackend)
 	}
 
-	// Load MinIO-specific environment variables into struct fields
-	loadEnv("MINIO_HOST", &env.MinioHost)
-	loadEnv("MINIO_KEY", &env.MinioKey)
-	loadEnv("MINIO_SECRET", &env.MinioSecret)
-	loadEnv("MINIO_BUCKET", &env.MinioBucket)
+	// Load the master keys of the encryption at rest
+	config.Required("MASTER_KEYS", &env.MasterKeys)
+	config.Required("MASTER_KEY_ID", &env.MasterKeyId)
+
+	// Load the settings of the malware scanner
+	config.Optional("SCANNER", &env.Scanner, "clamd")
+	switch env.Scanner {
+	case "clamd":
+		config.Required("CLAMD_ADDRESS", &env.ClamdAddress)
+	case "fake":
+	default:
+		log.Fatalf("Unknown SCANNER %s", env.Scanner)
+	}
+
+	// Load Database configuration values
+	config.Required("DB_HOST", &env.DBHost)
+	config.Required("DB_USER", &env.DBUser)
+	config.Required("DB_PASSWORD", &env.DBPassword)
+	config.Required("DB_NAME", &env.DBName)
+	config.Required("DB_PORT", &env.DBPort)
+
+	// Load the URLs of the other services; URUFI_AUTH_URL is the former name of URULINK_AUTH_SERVICE
+	config.Optional("URULINK_AUTH_SERVICE", &env.AuthServiceUrl, os.Getenv("URUFI_AUTH_URL"))
+	if env.AuthServiceUrl == "" {
+		log.Fatalf("Environment variable %s not found", "URULINK_AUTH_SERVICE")
+	}
+	config.Required("URULINK_MESSAGE_SERVICE", &env.MessageServiceUrl)
+	config.Optional("SERVICE_TOKEN", &env.ServiceToken, "")
+
+	// Load the optional upload policies
+	config.Optional("FILE_POLICY_PATH", &env.FilePolicyPath, "")
+	config.Optional("STRIP_IMAGE_METADATA", &env.StripImageMetadata, "true")
+	config.Optional("DEFAULT_USER_QUOTA", &env.DefaultUserQuota, "1073741824") // 1 GiB
+	config.Optional("QUOTA_ADMINS", &env.QuotaAdmins, "")
+
+	// Load the garbage collection settings
+	config.Optional("GC_INTERVAL", &env.GCInterval, "")
+	config.Optional("GC_GRACE_PERIOD", &env.GCGracePeriod, "72h")
+	config.Optional("GC_DRY_RUN", &env.GCDryRun, "false")
+
+	// Load the settings of the file links
+	config.Optional("PUBLIC_URL", &env.PublicUrl, env.StoragePublicUrl)
+	config.Required("FILE_LINK_KEY", &env.LinkKey)
+	config.Optional("FILE_LINK_EXPIRY", &env.LinkExpiry, "15m")
 
 	return env // Return populated EnvManger instance
 }
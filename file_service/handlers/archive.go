@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/archive"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/platform/logs"
+)
+
+// inspectArchive lists the contents of a zip archive from its upload, decrypted with key unless it
+// is nil. Zip bombs and archives with denied file types or nested archives are rejected with 422.
+func (h *Handler) inspectArchive(c *fiber.Ctx, rule policy.Rule, fileInfo *models.FileInfo, key []byte) (int, error) {
+	if !archive.Supported(rule.SniffedMime) {
+		return 0, nil
+	}
+
+	reader := &objectReaderAt{handler: h, key: key, fileInfo: *fileInfo}
+	listing, err := archive.Inspect(reader, fileInfo.Size, h.Policies.Policy().Archives)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalid) || errors.Is(err, archive.ErrBomb) || errors.Is(err, archive.ErrDenied) || errors.Is(err, archive.ErrNested) {
+			logs.Info(c, "Archive rejected", map[string]interface{}{"fileId": fileInfo.Id, "reason": err.Error()})
+			return 422, err
+		}
+		logs.Error(c, "Failed to read archive", err)
+		return 500, err
+	}
+	fileInfo.Archive = &listing
+	return 0, nil
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/audio"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/platform/logs"
+)
+
+// describeAudio reads the duration and waveform of an audio file from its upload, decrypted with
+// key unless it is nil. Files the analysis fails on are still accepted without them.
+func (h *Handler) describeAudio(c *fiber.Ctx, rule policy.Rule, fileInfo *models.FileInfo, key []byte) {
+	if !audio.Supported(rule.SniffedMime) || fileInfo.Size == 0 {
+		return
+	}
+
+	reader, err := h.openContent(h.Ctx, key, *fileInfo, 0, fileInfo.Size-1)
+	if err != nil {
+		logs.Error(c, "Failed to read audio file", err)
+		return
+	}
+	defer reader.Close()
+
+	info, err := audio.Analyze(reader, rule.SniffedMime)
+	if err != nil {
+		logs.Error(c, "Failed to analyze audio file", err)
+		return
+	}
+	fileInfo.DurationMs = info.DurationMs
+	fileInfo.Waveform = info.Waveform
+}
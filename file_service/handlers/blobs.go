@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/platform/logs"
+)
+
+// blobCollectBatch is the number of unreferenced blobs removed per collection round
+const blobCollectBatch = 100
+
+// newBlobObjectName returns a new name for an object that becomes a blob once the hash of its
+// content is known
+func newBlobObjectName() string {
+	return "blobs/" + helper.GenerateFilesName()
+}
+
+// storageObject returns the storage object holding the content of a file
+func storageObject(fileInfo models.FileInfo) string {
+	if fileInfo.ObjectName != "" {
+		return fileInfo.ObjectName
+	}
+	return fileInfo.Id
+}
+
+// promoteBlob makes the object of fileInfo, encrypted with the wrapped data key while its checksum
+// was computed, the blob of its content. When the same content is stored already, the object is
+// dropped and the file shares the existing blob.
+func (h *Handler) promoteBlob(c *fiber.Ctx, fileInfo *models.FileInfo, keyId string, wrappedKey []byte) error {
+	objectName, existed, err := h.Database.StoreBlob(fileInfo.Checksum, fileInfo.Size, fileInfo.ObjectName, keyId, wrappedKey)
+	if err != nil {
+		return err
+	}
+	if existed {
+		if err := h.Storage.DeleteFile(h.Ctx, fileInfo.ObjectName); err != nil {
+			logs.Error(c, "Failed to remove duplicate object", err)
+		}
+		logs.Info(c, "Upload deduplicated", map[string]interface{}{"fileId": fileInfo.Id, "checksum": fileInfo.Checksum})
+	}
+	fileInfo.ObjectName = objectName
+	return nil
+}
+
+// errUploadChanged is returned when an upload object no longer holds the bytes it was accepted with
+var errUploadChanged = errors.New("uploaded file changed while it was stored")
+
+// storeAsBlob stores an upload written to storage in plaintext by the client, through tus or a
+// presigned URL, as the blob of its content. The upload is copied by copyUpload into a new object
+// encrypted with a new data key; the audio and archive inspections read that copy, which is then
+// promoted like a direct upload. The upload object is left for the caller to remove once the file
+// is recorded. On failure it returns the status code to answer with.
+func (h *Handler) storeAsBlob(c *fiber.Ctx, rule policy.Rule, fileInfo *models.FileInfo, uploadObject, expectedChecksum string) (int, error) {
+	dataKey, keyId, wrappedKey, err := h.Keys.NewDataKey()
+	if err != nil {
+		return 500, err
+	}
+	objectName := newBlobObjectName()
+	if status, err := h.copyUpload(dataKey, objectName, rule, fileInfo, uploadObject, expectedChecksum); err != nil {
+		return status, err
+	}
+
+	h.describeAudio(c, rule, fileInfo, dataKey)
+	status, err := h.inspectArchive(c, rule, fileInfo, dataKey)
+	if err == nil {
+		status = 500
+		err = h.promoteBlob(c, fileInfo, keyId, wrappedKey)
+	}
+	if err != nil {
+		if err := h.Storage.DeleteFile(h.Ctx, objectName); err != nil {
+			logs.Error(c, "Failed to remove untracked object", err)
+		}
+		return status, err
+	}
+	return 0, nil
+}
+
+// copyUpload reads an upload object once: its content is checked against rule, stripped of image
+// metadata and encrypted with dataKey into objectName while its checksum is computed. The client
+// may still be able to write the upload object, so everything recorded about the file comes from
+// this single read and later reads use the copy. expectedChecksum, when set, is the checksum of
+// the bytes the server received itself; an object that changed since is refused, like an object
+// of another size than fileInfo.Size. On success fileInfo describes the copy.
+func (h *Handler) copyUpload(dataKey []byte, objectName string, rule policy.Rule, fileInfo *models.FileInfo, uploadObject, expectedChecksum string) (int, error) {
+	reader, _, err := h.Storage.OpenFile(h.Ctx, uploadObject)
+	if err != nil {
+		return 500, err
+	}
+	defer reader.Close()
+
+	// One byte more than expected is read, to notice an object that grew
+	object := io.LimitReader(reader, fileInfo.Size+1)
+	head := make([]byte, helper.SniffLength)
+	n, err := io.ReadFull(object, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 500, err
+	}
+	if err := rule.CheckContent(head[:n]); err != nil {
+		return 415, err
+	}
+	content := io.Reader(io.MultiReader(bytes.NewReader(head[:n]), object))
+
+	// Images are read whole to strip their metadata and describe them. The checksum of the received
+	// bytes differs from the stored one when metadata is stripped
+	size := fileInfo.Size
+	receivedChecksum := ""
+	if h.stripsMetadata(fileInfo.MimeType) || rule.Previews {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return 500, err
+		}
+		if int64(len(data)) != fileInfo.Size {
+			return 422, errUploadChanged
+		}
+		received := sha256.Sum256(data)
+		receivedChecksum = hex.EncodeToString(received[:])
+		if h.stripsMetadata(fileInfo.MimeType) {
+			if data, err = helper.StripImageMetadata(data, fileInfo.MimeType); err != nil {
+				return 422, err
+			}
+		}
+		describeImage(fileInfo, data)
+		content = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	hasher := sha256.New()
+	if err := h.storeObject(dataKey, objectName, io.TeeReader(content, hasher), size, fileInfo.MimeType); err != nil {
+		h.Storage.DeleteFile(h.Ctx, objectName)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 422, errUploadChanged
+		}
+		return 500, err
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if receivedChecksum == "" {
+		receivedChecksum = checksum
+	}
+	extra, _ := io.ReadFull(content, make([]byte, 1))
+	if extra > 0 || (expectedChecksum != "" && receivedChecksum != expectedChecksum) {
+		h.Storage.DeleteFile(h.Ctx, objectName)
+		return 422, errUploadChanged
+	}
+
+	fileInfo.Size = size
+	fileInfo.Checksum = checksum
+	fileInfo.ObjectName = objectName
+	return 0, nil
+}
+
+// removeUploadObject drops the plaintext upload object once its content is stored as a blob
+func (h *Handler) removeUploadObject(c *fiber.Ctx, uploadObject string) {
+	if err := h.Storage.DeleteFile(h.Ctx, uploadObject); err != nil {
+		logs.Error(c, "Failed to remove upload object", err)
+	}
+}
+
+// collectBlobs removes the blobs no file references anymore
+func (h *Handler) collectBlobs() {
+	removed, err := h.Database.CollectBlobs(blobCollectBatch, func(objectName string) error {
+		return h.Storage.DeleteFile(h.Ctx, objectName)
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to collect unreferenced blobs: %v", err)
+	}
+	if removed > 0 {
+		log.Printf("[INFO] Unreferenced blobs removed: %d", removed)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"urulink.com/file_service/encryption"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/storage"
+)
+
+func TestObjectReaderAtDecryptsRanges(t *testing.T) {
+	local, err := storage.InitLocal(t.TempDir(), "", "signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &Handler{Storage: local, Ctx: context.Background()}
+
+	// Several encryption chunks and object blocks, with an unaligned end
+	content := make([]byte, 3*objectBlockSize+1234)
+	rand.Read(content)
+	key := make([]byte, encryption.DataKeySize)
+	rand.Read(key)
+
+	objectName := newBlobObjectName()
+	if err := handler.storeObject(key, objectName, bytes.NewReader(content), int64(len(content)), "application/zip"); err != nil {
+		t.Fatal(err)
+	}
+	fileInfo := models.FileInfo{Id: "a.zip", ObjectName: objectName, Size: int64(len(content))}
+	reader := &objectReaderAt{handler: handler, key: key, fileInfo: fileInfo}
+
+	for _, r := range []struct{ offset, length int64 }{
+		{0, 10},
+		{objectBlockSize - 5, 10},
+		{int64(len(content)) - 100, 100},
+		{12345, 2 * objectBlockSize},
+	} {
+		got := make([]byte, r.length)
+		if _, err := reader.ReadAt(got, r.offset); err != nil {
+			t.Fatalf("ReadAt(%d, %d): %v", r.offset, r.length, err)
+		}
+		if !bytes.Equal(got, content[r.offset:r.offset+r.length]) {
+			t.Fatalf("ReadAt(%d, %d) returned other bytes", r.offset, r.length)
+		}
+	}
+
+	// Reading past the end stops at the content
+	got := make([]byte, 10)
+	if n, err := reader.ReadAt(got, int64(len(content))-4); n != 4 || err != io.EOF {
+		t.Fatalf("ReadAt past the end = %d, %v; want 4, EOF", n, err)
+	}
+}
+
+func TestObjectKind(t *testing.T) {
+	for _, test := range []struct{ name, kind, key string }{
+		{"blobs/0f3c", "blob", "blobs/0f3c"},
+		{"blobs/ab12.bin", "blob", "blobs/ab12.bin"},
+		{"previews/f1.jpg/thumbnail", "preview", "f1.jpg"},
+		{"f1.jpg", "file", "f1.jpg"},
+	} {
+		kind, key := objectKind(test.name)
+		if kind != test.kind || key != test.key {
+			t.Errorf("objectKind(%q) = %q, %q; want %q, %q", test.name, kind, key, test.kind, test.key)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/storage"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// presignExpiry is how long a presigned direct upload stays usable
+const presignExpiry = 15 * time.Minute
+
+// PresignUpload validates a declared file and returns a presigned PUT URL or POST policy that lets
+// the client send it straight to storage. The file is registered by CompleteUpload afterwards.
+func (h *Handler) PresignUpload(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	var input models.PresignUploadInput
+	if err := c.BodyParser(&input); err != nil {
+		logs.Error(c, "Failed to parse presign request", err)
+		return c.Status(400).SendString("invalid request body")
+	}
+	if input.FileName == "" || input.Size <= 0 {
+		return c.Status(400).SendString("file_name and size are required")
+	}
+	rule, status, errMsg := h.fileRule(c, userJwtInfo.Uid, input.FileName, input.Size)
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+
+	// The stored type always follows the extension; a different declared type is refused up front
+	contentType := rule.ContentType
+	if input.ContentType != "" && !strings.EqualFold(input.ContentType, contentType) {
+		return c.Status(400).SendString("content_type does not match the file type")
+	}
+
+	method := strings.ToLower(input.Method)
+	if method == "" {
+		method = "put"
+	}
+	if method != "put" && method != "post" {
+		return c.Status(400).SendString("method must be put or post")
+	}
+
+	// The declared size counts against the quota until the upload completes or expires
+	if status, errMsg := h.reserveStorage(c, userJwtInfo.Uid, input.Size); status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+
+	upload := models.PendingUpload{
+		Id:           fmt.Sprintf("%s%s", helper.GenerateFilesName(), helper.FileExt(input.FileName)),
+		OwnerId:      userJwtInfo.Uid,
+		OriginalName: input.FileName,
+		Size:         input.Size,
+		MimeType:     contentType,
+		ExpiresAt:    time.Now().Add(presignExpiry).Unix(),
+	}
+
+	presigned := models.PresignedUpload{UploadId: upload.Id, Method: method, ExpiresAt: upload.ExpiresAt}
+	var err error
+	if method == "put" {
+		presigned.Url, presigned.Headers, err = h.Storage.PresignedPutURL(h.Ctx, upload.Id, contentType, upload.Size, presignExpiry)
+	} else {
+		presigned.Url, presigned.Fields, err = h.Storage.PresignedPostPolicy(h.Ctx, upload.Id, contentType, upload.Size, presignExpiry)
+	}
+	if errors.Is(err, storage.ErrNotSupported) {
+		h.releaseStorage(upload.OwnerId, upload.Size)
+		return c.Status(400).SendString("method " + method + " is not supported by the storage backend")
+	}
+	if err != nil {
+		logs.Error(c, "Failed to presign upload", err)
+		h.releaseStorage(upload.OwnerId, upload.Size)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if err := h.Database.CreatePendingUpload(upload); err != nil {
+		logs.Error(c, "Failed to store pending upload", err)
+		h.releaseStorage(upload.OwnerId, upload.Size)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	logs.Info(c, "Direct upload presigned", map[string]interface{}{"uploadId": upload.Id, "method": method})
+	return response.HandleInformation(c, 200, presigned)
+}
+
+// CompleteUpload is called by the client once its direct upload finished. The object is checked
+// against the declared size and its content against the declared type before it joins the catalog;
+// an object that fails the checks is deleted.
+func (h *Handler) CompleteUpload(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	upload, err := h.Database.GetPendingUpload(c.Params("id"))
+	if err != nil {
+		logs.Error(c, "Failed to retrieve pending upload", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	if upload.Id == "" || upload.OwnerId != userJwtInfo.Uid {
+		return c.Status(404).SendString("upload not found")
+	}
+
+	objectInfo, err := h.Storage.StatFile(h.Ctx, upload.Id)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return c.Status(409).SendString("file was not uploaded yet")
+		}
+		logs.Error(c, "Failed to stat uploaded file", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	if objectInfo.Size != upload.Size {
+		h.rejectUpload(c, upload, fmt.Errorf("uploaded %d bytes, declared %d", objectInfo.Size, upload.Size))
+		return c.Status(422).SendString("uploaded file size does not match the declared size")
+	}
+
+	// The policy may have changed since the upload was presigned
+	rule, status, errMsg := h.fileRule(c, upload.OwnerId, upload.OriginalName, upload.Size)
+	if status != 0 {
+		h.rejectUpload(c, upload, errors.New(errMsg))
+		return c.Status(status).SendString(errMsg)
+	}
+
+	// Claim the upload; a concurrent completion that already did so wins
+	claimed, err := h.Database.DeletePendingUpload(upload.Id)
+	if err != nil {
+		logs.Error(c, "Failed to claim pending upload", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	if !claimed {
+		return c.Status(409).SendString("upload already completed")
+	}
+
+	fileInfo := models.FileInfo{
+		Id:            upload.Id,
+		OwnerId:       upload.OwnerId,
+		OriginalName:  upload.OriginalName,
+		Size:          upload.Size,
+		MimeType:      upload.MimeType,
+		Category:      rule.Category,
+		CreatedAt:     time.Now().Unix(),
+		PreviewStatus: previewStatusFor(rule),
+		ScanStatus:    scanPending,
+	}
+	// The presigned URL can replace the object until it expires, so the object is read once into
+	// a copy the client cannot write and the file is checked and described from that read only
+	if status, err := h.storeAsBlob(c, rule, &fileInfo, upload.Id, ""); err != nil {
+		logs.Error(c, "Failed to store file content", err)
+		h.discardDirectUpload(c, upload)
+		return c.Status(status).SendString(err.Error())
+	}
+	if err := h.Database.CreateFile(fileInfo); err != nil {
+		logs.Error(c, "Failed to record file metadata", err)
+		if err := h.Database.ReleaseBlob(fileInfo.Checksum); err != nil {
+			logs.Error(c, "Failed to release file content", err)
+		}
+		h.discardDirectUpload(c, upload)
+		return c.Status(500).SendString(err.Error())
+	}
+	// Stripping metadata may have made the file smaller than the reserved size
+	h.releaseStorage(upload.OwnerId, upload.Size-fileInfo.Size)
+	h.removeUploadObject(c, upload.Id)
+	h.scheduleScan(c, &fileInfo)
+	h.scheduleDocument(c, rule, fileInfo)
+
+	logs.Info(c, "Direct upload completed", map[string]interface{}{"fileId": fileInfo.Id})
+	return response.HandleInformation(c, 200, fileInfo)
+}
+
+// discardDirectUpload deletes the object of a claimed upload that could not be stored and gives
+// back its quota
+func (h *Handler) discardDirectUpload(c *fiber.Ctx, upload models.PendingUpload) {
+	if err := h.Storage.DeleteFile(h.Ctx, upload.Id); err != nil {
+		logs.Error(c, "Failed to remove rejected file", err)
+	}
+	h.releaseStorage(upload.OwnerId, upload.Size)
+}
+
+// rejectUpload deletes an uploaded object that failed verification, along with its pending upload,
+// and gives back its quota
+func (h *Handler) rejectUpload(c *fiber.Ctx, upload models.PendingUpload, reason error) {
+	logs.Error(c, "Direct upload rejected", reason)
+	if err := h.Storage.DeleteFile(h.Ctx, upload.Id); err != nil {
+		logs.Error(c, "Failed to remove rejected file", err)
+	}
+	deleted, err := h.Database.DeletePendingUpload(upload.Id)
+	if err != nil {
+		logs.Error(c, "Failed to delete pending upload", err)
+	}
+	if deleted {
+		h.releaseStorage(upload.OwnerId, upload.Size)
+	}
+}
+
+// expirePendingUploads deletes presigned uploads that were never completed, including any bytes
+// the client sent without calling CompleteUpload
+func (h *Handler) expirePendingUploads(now time.Time) {
+	// Leave a margin so an upload sent just before expiry can still be completed
+	uploads, err := h.Database.GetExpiredPendingUploads(now.Add(-presignExpiry).Unix())
+	if err != nil {
+		log.Printf("[ERROR] Failed to list expired direct uploads: %v", err)
+		return
+	}
+	for _, upload := range uploads {
+		if err := h.Storage.DeleteFile(h.Ctx, upload.Id); err != nil && !storage.IsNotFound(err) {
+			log.Printf("[ERROR] Failed to remove expired direct upload %s: %v", upload.Id, err)
+			continue
+		}
+		deleted, err := h.Database.DeletePendingUpload(upload.Id)
+		if err != nil {
+			log.Printf("[ERROR] Failed to delete expired direct upload %s: %v", upload.Id, err)
+			continue
+		}
+		if deleted {
+			h.releaseStorage(upload.OwnerId, upload.Size)
+		}
+		log.Printf("[INFO] Expired direct upload removed: %s", upload.Id)
+	}
+}
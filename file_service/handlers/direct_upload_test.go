@@ -0,0 +1,245 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/encryption"
+	"urulink.com/file_service/env"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/file_service/storage"
+	"urulink.com/file_service/worker"
+)
+
+// newUploadHandler returns a handler with local storage, an in-memory database, the default
+// policy and a master key, as the upload paths need them
+func newUploadHandler(t *testing.T) (*Handler, *storage.LocalStorage, *fakeDatabase) {
+	t.Helper()
+	local, err := storage.InitLocal(t.TempDir(), "", "signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database, fake := newFakeDatabase(t)
+	policies, err := policy.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey := make([]byte, encryption.DataKeySize)
+	rand.Read(masterKey)
+	keys, err := encryption.ParseKeyring("test:"+base64.StdEncoding.EncodeToString(masterKey), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &Handler{
+		EnvManger: &env.EnvManger{},
+		Storage:   local,
+		Database:  database,
+		Policies:  policies,
+		Keys:      keys,
+		Scans:     worker.NewPool[string](0, 16, nil),
+		Documents: worker.NewPool[documentJob](0, 16, nil),
+		Ctx:       context.Background(),
+	}
+	return handler, local, fake
+}
+
+// asUser runs the routes of a test app as an authenticated user
+func asUser(uid string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("userJwtInfo", models.ClientsLoginResponse{Uid: uid})
+		return c.Next()
+	}
+}
+
+// storedContent decrypts the content of a recorded file from its blob
+func storedContent(t *testing.T, handler *Handler, fake *fakeDatabase, fileId string) ([]byte, string) {
+	t.Helper()
+	blob, checksum := fake.fileBlob(fileId)
+	if blob == nil {
+		t.Fatalf("file %s has no blob", fileId)
+	}
+	key, err := handler.Keys.Unwrap(blob.keyId, blob.dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, _, err := handler.openObject(key, blob.objectName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content, checksum
+}
+
+// swappingStorage replaces an object right before it is opened, like a client writing to its
+// presigned URL again between the completion checks and the copy of the upload
+type swappingStorage struct {
+	*storage.LocalStorage
+	objectName string
+	content    []byte
+}
+
+func (s *swappingStorage) OpenFile(ctx context.Context, objectName string) (io.ReadCloser, int64, error) {
+	if objectName == s.objectName && s.content != nil {
+		if err := s.UploadFile(ctx, bytes.NewReader(s.content), objectName, int64(len(s.content)), "text/plain", nil); err != nil {
+			return nil, 0, err
+		}
+		s.content = nil
+	}
+	return s.LocalStorage.OpenFile(ctx, objectName)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// completeDirectUpload stores content as the object of a pending upload and completes it
+func completeDirectUpload(t *testing.T, app *fiber.App, local *storage.LocalStorage, fake *fakeDatabase, id string, content []byte) (int, string) {
+	t.Helper()
+	fake.pending[id] = &models.PendingUpload{Id: id, OwnerId: "alice", OriginalName: "notes.txt", Size: int64(len(content)), MimeType: "text/plain"}
+	if err := local.UploadFile(context.Background(), bytes.NewReader(content), id, int64(len(content)), "text/plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	status, body := testRequest(t, app, httptest.NewRequest("POST", "/upload/complete/"+id, nil))
+	return status, string(body)
+}
+
+func TestCompleteUploadStoresWhatItChecks(t *testing.T) {
+	handler, local, fake := newUploadHandler(t)
+	original := []byte("meeting moved to 10am, see you there")
+	replaced := []byte("meeting moved to 11pm, bring cash!!!")
+	swapping := &swappingStorage{LocalStorage: local, objectName: "first.txt", content: replaced}
+	handler.Storage = swapping
+
+	app := fiber.New()
+	app.Post("/upload/complete/:id", asUser("alice"), handler.CompleteUpload)
+
+	// The object is replaced after the size check; the blob holds the replacement under its own hash
+	if status, body := completeDirectUpload(t, app, local, fake, "first.txt", original); status != 200 {
+		t.Fatalf("completion answered %d: %s", status, body)
+	}
+	content, checksum := storedContent(t, handler, fake, "first.txt")
+	if !bytes.Equal(content, replaced) || checksum != sha256Hex(replaced) {
+		t.Fatalf("blob %s holds %q", checksum, content)
+	}
+
+	// A later upload of the original content is not deduplicated onto the replacement
+	if status, body := completeDirectUpload(t, app, local, fake, "second.txt", original); status != 200 {
+		t.Fatalf("completion answered %d: %s", status, body)
+	}
+	content, checksum = storedContent(t, handler, fake, "second.txt")
+	if !bytes.Equal(content, original) || checksum != sha256Hex(original) {
+		t.Fatalf("blob %s holds %q", checksum, content)
+	}
+
+	// The plaintext upload objects are gone once the files are recorded
+	for _, id := range []string{"first.txt", "second.txt"} {
+		if _, err := local.StatFile(context.Background(), id); !storage.IsNotFound(err) {
+			t.Errorf("upload object %s was kept: %v", id, err)
+		}
+	}
+}
+
+func TestCompleteUploadRefusesChangedObjects(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content []byte
+		status  int
+	}{
+		{"shorter", []byte("short"), 422},
+		{"longer", []byte("meeting moved to 10am, see you there, and more"), 422},
+		{"other type", append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte("x"), 27)...), 415},
+	} {
+		handler, local, fake := newUploadHandler(t)
+		handler.Storage = &swappingStorage{LocalStorage: local, objectName: "notes.txt", content: test.content}
+		app := fiber.New()
+		app.Post("/upload/complete/:id", asUser("alice"), handler.CompleteUpload)
+
+		original := []byte("meeting moved to 10am, see you there")
+		if status, body := completeDirectUpload(t, app, local, fake, "notes.txt", original); status != test.status {
+			t.Errorf("%s: completion answered %d (%s), want %d", test.name, status, body, test.status)
+		}
+		if len(fake.files) != 0 || len(fake.blobs) != 0 {
+			t.Errorf("%s: a changed object was recorded", test.name)
+		}
+		if fake.released["alice"] != int64(len(original)) {
+			t.Errorf("%s: released %d bytes of quota, want %d", test.name, fake.released["alice"], len(original))
+		}
+		objects, err := local.ListFiles(context.Background(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 0 {
+			t.Errorf("%s: objects left behind: %v", test.name, objects)
+		}
+	}
+}
+
+func TestCopyUploadChecksExpectedChecksum(t *testing.T) {
+	handler, local, _ := newUploadHandler(t)
+	content := []byte("received by the server")
+	if err := local.UploadFile(context.Background(), bytes.NewReader(content), "upload.txt", int64(len(content)), "text/plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := handler.Policies.Policy().Check("upload.txt", int64(len(content)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, encryption.DataKeySize)
+	rand.Read(key)
+
+	fileInfo := models.FileInfo{Id: "upload.txt", Size: int64(len(content)), MimeType: "text/plain"}
+	if status, err := handler.copyUpload(key, "blobs/other", rule, &fileInfo, "upload.txt", sha256Hex([]byte("something else"))); status != 422 || err != errUploadChanged {
+		t.Fatalf("copy of another content = %d, %v", status, err)
+	}
+	if _, err := local.StatFile(context.Background(), "blobs/other"); !storage.IsNotFound(err) {
+		t.Fatal("refused copy was kept")
+	}
+
+	if status, err := handler.copyUpload(key, "blobs/same", rule, &fileInfo, "upload.txt", sha256Hex(content)); err != nil {
+		t.Fatalf("copy = %d, %v", status, err)
+	}
+	if fileInfo.ObjectName != "blobs/same" || fileInfo.Checksum != sha256Hex(content) {
+		t.Fatalf("copy described as %+v", fileInfo)
+	}
+	reader, _, err := handler.openObject(key, "blobs/same")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if copied, _ := io.ReadAll(reader); !bytes.Equal(copied, content) {
+		t.Fatalf("copy holds %q", copied)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/document"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/platform/logs"
+)
+
+// Document preview settings
+const (
+	documentWorkers   = 1    // Documents analyzed at the same time
+	documentQueueSize = 1024 // Documents waiting for a worker before new ones are skipped
+)
+
+// documentJob is a recorded file whose document preview is read in the background
+type documentJob struct {
+	fileInfo    models.FileInfo
+	sniffedMime string
+}
+
+// scheduleDocument queues the analysis of a newly recorded text file or PDF. Uploads never wait
+// for it: a PDF may take long to read, and the preview appears in the file metadata once it is done.
+func (h *Handler) scheduleDocument(c *fiber.Ctx, rule policy.Rule, fileInfo models.FileInfo) {
+	if !document.Supported(rule.SniffedMime) || fileInfo.Size == 0 {
+		return
+	}
+	if !h.Documents.Submit(documentJob{fileInfo: fileInfo, sniffedMime: rule.SniffedMime}) {
+		logs.Error(c, "Document queue is full", fmt.Errorf("document preview of %s skipped", fileInfo.Id))
+	}
+}
+
+// describeDocument runs on a document worker: it reads the snippet of a text file, or the page
+// count and title of a PDF, and records them. Files the analysis fails on keep no preview.
+func (h *Handler) describeDocument(job documentJob) {
+	fileInfo := job.fileInfo
+	key, err := h.contentKey(fileInfo)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read the data key of %s: %v", fileInfo.Id, err)
+		return
+	}
+
+	reader := &objectReaderAt{handler: h, key: key, fileInfo: fileInfo}
+	info, err := document.Analyze(reader, fileInfo.Size, job.sniffedMime)
+	if err != nil {
+		log.Printf("[ERROR] Failed to analyze document %s: %v", fileInfo.Id, err)
+		return
+	}
+	if err := h.Database.UpdateFileDocument(fileInfo.Id, info.Snippet, info.Encoding, info.PageCount, info.Title); err != nil {
+		log.Printf("[ERROR] Failed to record the document preview of %s: %v", fileInfo.Id, err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/storage"
+	"urulink.com/platform/logs"
+)
+
+// DownloadFile streams a stored file to its owner or to a participant of a conversation it was
+// shared in. Single HTTP ranges are honoured so media players can seek in videos.
+func (h *Handler) DownloadFile(c *fiber.Ctx) error {
+	fileInfo, status, errMsg := h.downloadableFile(c, c.Params("id"))
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+	return h.sendFile(c, fileInfo)
+}
+
+// sendFile streams the content of a file, or the requested range of it
+func (h *Handler) sendFile(c *fiber.Ctx, fileInfo models.FileInfo) error {
+	start, end, partial, err := helper.ParseRange(c.Get(fiber.HeaderRange), fileInfo.Size)
+	if err != nil {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", fileInfo.Size))
+		return c.Status(fiber.StatusRequestedRangeNotSatisfiable).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderContentType, fileInfo.MimeType)
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(fileInfo.MimeType, fileInfo.OriginalName, fileInfo.Id))
+	if fileInfo.Size == 0 {
+		return c.Status(200).Send(nil)
+	}
+
+	key, err := h.contentKey(fileInfo)
+	if err != nil {
+		logs.Error(c, "Failed to load file key", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	reader, err := h.openContent(h.Ctx, key, fileInfo, start, end)
+	if err != nil {
+		logs.Error(c, "Failed to open file stream", err)
+		if storage.IsNotFound(err) {
+			return c.Status(404).SendString("file content not found")
+		}
+		return c.Status(500).SendString(err.Error())
+	}
+
+	status := 200
+	if partial {
+		status = fiber.StatusPartialContent
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, fileInfo.Size))
+	}
+
+	// Fiber closes the reader once the body has been written
+	return c.Status(status).SendStream(reader, int(end-start+1))
+}
+
+// contentDisposition renders media inline and everything else as an attachment, keeping the original name
+func contentDisposition(contentType, originalName, fileId string) string {
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/") ||
+		strings.HasPrefix(contentType, "audio/") || contentType == "application/pdf" {
+		disposition = "inline"
+	}
+	if originalName == "" {
+		originalName = fileId
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": originalName})
+}
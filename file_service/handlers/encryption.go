@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+
+	"urulink.com/file_service/encryption"
+	"urulink.com/file_service/models"
+)
+
+// rewrapBatch is the number of data keys re-wrapped per database round trip
+const rewrapBatch = 500
+
+// contentKey returns the data key encrypting the content and the previews of a file, or nil when
+// they are stored in plaintext because the file predates the encryption at rest
+func (h *Handler) contentKey(fileInfo models.FileInfo) ([]byte, error) {
+	if fileInfo.ObjectName == "" {
+		return nil, nil
+	}
+	keyId, wrappedKey, err := h.Database.GetBlobKey(fileInfo.Checksum)
+	if err != nil || keyId == "" {
+		return nil, err
+	}
+	return h.Keys.Unwrap(keyId, wrappedKey)
+}
+
+// storeObject uploads size bytes of content to objectName, encrypted with key unless it is nil
+func (h *Handler) storeObject(key []byte, objectName string, content io.Reader, size int64, contentType string) error {
+	if key == nil {
+		return h.Storage.UploadFile(h.Ctx, content, objectName, size, contentType, nil)
+	}
+	encrypted, err := encryption.NewEncrypter(key, content, size)
+	if err != nil {
+		return err
+	}
+	return h.Storage.UploadFile(h.Ctx, encrypted, objectName, encryption.EncryptedSize(size), "application/octet-stream", nil)
+}
+
+// openObject opens a whole object written by storeObject and returns its plaintext size
+func (h *Handler) openObject(key []byte, objectName string) (io.ReadCloser, int64, error) {
+	reader, size, err := h.Storage.OpenFile(h.Ctx, objectName)
+	if err != nil || key == nil {
+		return reader, size, err
+	}
+
+	header := make([]byte, encryption.HeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		reader.Close()
+		return nil, 0, err
+	}
+	plainSize := encryption.PlainSize(size)
+	decrypted, err := encryption.NewDecrypter(key, header, reader, 0, plainSize-1, plainSize)
+	if err != nil {
+		reader.Close()
+		return nil, 0, err
+	}
+	return decryptedObject{Reader: decrypted, Closer: reader}, plainSize, nil
+}
+
+// openContent reads bytes start to end, both inclusive, of the content of a file. Only the chunks
+// covering the range are fetched from storage and decrypted.
+func (h *Handler) openContent(ctx context.Context, key []byte, fileInfo models.FileInfo, start, end int64) (io.ReadCloser, error) {
+	objectName := storageObject(fileInfo)
+	if key == nil {
+		return h.Storage.DownloadFile(ctx, objectName, start, end)
+	}
+
+	headerReader, err := h.Storage.DownloadFile(ctx, objectName, 0, int64(encryption.HeaderSize)-1)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, encryption.HeaderSize)
+	_, err = io.ReadFull(headerReader, header)
+	headerReader.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := encryption.ChunkRange(start, end, fileInfo.Size)
+	chunks, err := h.Storage.DownloadFile(ctx, objectName, from, to)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := encryption.NewDecrypter(key, header, chunks, start, end, fileInfo.Size)
+	if err != nil {
+		chunks.Close()
+		return nil, err
+	}
+	return decryptedObject{Reader: decrypted, Closer: chunks}, nil
+}
+
+// decryptedObject closes the encrypted storage stream behind a decrypting reader
+type decryptedObject struct {
+	io.Reader
+	io.Closer
+}
+
+// RewrapDataKeys re-wraps the data keys of all blobs with the current master key, so the keys
+// that wrapped them before can be removed from MASTER_KEYS. It returns the number of keys re-wrapped.
+func (h *Handler) RewrapDataKeys() (int, error) {
+	rewrapped := 0
+	for {
+		keys, err := h.Database.GetBlobKeysToRewrap(h.Keys.CurrentId, rewrapBatch)
+		if err != nil || len(keys) == 0 {
+			return rewrapped, err
+		}
+		for _, key := range keys {
+			keyId, wrappedKey, err := h.Keys.Rewrap(key.KeyId, key.DataKey)
+			if err != nil {
+				return rewrapped, err
+			}
+			if err := h.Database.UpdateBlobKey(key.Hash, key.KeyId, keyId, wrappedKey); err != nil {
+				return rewrapped, err
+			}
+			rewrapped++
+		}
+		log.Printf("[INFO] Data keys re-wrapped: %d", rewrapped)
+	}
+}
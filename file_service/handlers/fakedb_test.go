@@ -0,0 +1,297 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"urulink.com/file_service/db"
+	"urulink.com/file_service/models"
+)
+
+// fakeDatabase keeps the rows the upload handlers read and write in memory and answers their
+// queries through a database/sql driver, so the handlers can be tested without MySQL. Only the
+// statements of the upload paths are known; any other query fails the test.
+type fakeDatabase struct {
+	t  *testing.T
+	mu sync.Mutex
+
+	sessions map[string]*models.UploadSession
+	pending  map[string]*models.PendingUpload
+	files    map[string]map[string]driver.Value // Columns of the recorded files, by file ID
+	blobs    map[string]*fakeBlob               // Blobs by hash
+	released map[string]int64                   // Quota given back, by user
+
+	failCreateFile int // Number of file inserts still to fail
+}
+
+type fakeBlob struct {
+	objectName string
+	refCount   int64
+	keyId      string
+	dataKey    []byte
+}
+
+// newFakeDatabase opens a db.Database backed by a new fakeDatabase
+func newFakeDatabase(t *testing.T) (*db.Database, *fakeDatabase) {
+	t.Helper()
+	fake := &fakeDatabase{
+		t:        t,
+		sessions: map[string]*models.UploadSession{},
+		pending:  map[string]*models.PendingUpload{},
+		files:    map[string]map[string]driver.Value{},
+		blobs:    map[string]*fakeBlob{},
+		released: map[string]int64{},
+	}
+	sqlDb := sql.OpenDB(fakeConnector{fake})
+	t.Cleanup(func() { sqlDb.Close() })
+	gormDb, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDb, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &db.Database{Db: gormDb}, fake
+}
+
+// errFakeInsert is the error of the file inserts made to fail
+var errFakeInsert = errors.New("fake insert failure")
+
+// query runs one statement against the rows of the fake
+func (f *fakeDatabase) query(query string, args []driver.Value) (*fakeRows, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query = strings.Join(strings.Fields(query), " ")
+
+	switch {
+	case strings.HasPrefix(query, "SELECT u.user_id, u.used_bytes"):
+		return &fakeRows{columns: []string{"user_id", "used_bytes", "group_name", "quota_bytes"}}, 0, nil
+	case strings.HasPrefix(query, "UPDATE storage_usage SET used_bytes = GREATEST(used_bytes - ?, 0)"):
+		f.released[args[1].(string)] += args[0].(int64)
+		return nil, 1, nil
+
+	case query == "SELECT * FROM upload_session WHERE id = ?":
+		if session, ok := f.sessions[args[0].(string)]; ok {
+			return structRows(*session), 0, nil
+		}
+		return structRows(models.UploadSession{}).empty(), 0, nil
+	case strings.HasPrefix(query, "UPDATE upload_session SET locked_until = ? WHERE id = ? AND `offset` = ? AND locked_until < ?"):
+		session, ok := f.sessions[args[1].(string)]
+		if !ok || session.Offset != args[2].(int64) || session.LockedUntil >= args[3].(int64) {
+			return nil, 0, nil
+		}
+		session.LockedUntil = args[0].(int64)
+		return nil, 1, nil
+	case query == "UPDATE upload_session SET locked_until = 0 WHERE id = ?":
+		if session, ok := f.sessions[args[0].(string)]; ok && session.LockedUntil != 0 {
+			session.LockedUntil = 0
+			return nil, 1, nil
+		}
+		return nil, 0, nil
+	case strings.HasPrefix(query, "UPDATE upload_session SET `offset` = ?, parts = ?, tail = ?, hash_state = ?, expires_at = ?, locked_until = ?"):
+		session, ok := f.sessions[args[6].(string)]
+		if !ok || session.Offset != args[7].(int64) {
+			return nil, 0, nil
+		}
+		session.Offset, session.Parts = args[0].(int64), args[1].(string)
+		session.Tail, session.HashState = bytesValue(args[2]), bytesValue(args[3])
+		session.ExpiresAt, session.LockedUntil = args[4].(int64), args[5].(int64)
+		return nil, 1, nil
+	case query == "DELETE FROM upload_session WHERE id = ?":
+		_, ok := f.sessions[args[0].(string)]
+		delete(f.sessions, args[0].(string))
+		return nil, affected(ok), nil
+
+	case query == "SELECT * FROM pending_upload WHERE id = ?":
+		if upload, ok := f.pending[args[0].(string)]; ok {
+			return structRows(*upload), 0, nil
+		}
+		return structRows(models.PendingUpload{}).empty(), 0, nil
+	case query == "DELETE FROM pending_upload WHERE id = ?":
+		_, ok := f.pending[args[0].(string)]
+		delete(f.pending, args[0].(string))
+		return nil, affected(ok), nil
+
+	case strings.HasPrefix(query, "INSERT INTO file_blob (hash, size, object_name, ref_count, key_id, data_key)"):
+		if blob, ok := f.blobs[args[0].(string)]; ok {
+			blob.refCount++
+			return nil, 2, nil
+		}
+		f.blobs[args[0].(string)] = &fakeBlob{objectName: args[2].(string), refCount: 1, keyId: args[3].(string), dataKey: bytesValue(args[4])}
+		return nil, 1, nil
+	case query == "SELECT object_name FROM file_blob WHERE hash = ?":
+		rows := &fakeRows{columns: []string{"object_name"}}
+		if blob, ok := f.blobs[args[0].(string)]; ok {
+			rows.rows = [][]driver.Value{{blob.objectName}}
+		}
+		return rows, 0, nil
+	case query == "SELECT hash, key_id, data_key FROM file_blob WHERE hash = ?":
+		rows := &fakeRows{columns: []string{"hash", "key_id", "data_key"}}
+		if blob, ok := f.blobs[args[0].(string)]; ok {
+			rows.rows = [][]driver.Value{{args[0], blob.keyId, blob.dataKey}}
+		}
+		return rows, 0, nil
+	case strings.HasPrefix(query, "UPDATE file_blob SET ref_count = ref_count - 1 WHERE hash = ?"):
+		if blob, ok := f.blobs[args[0].(string)]; ok && blob.refCount > 0 {
+			blob.refCount--
+			return nil, 1, nil
+		}
+		return nil, 0, nil
+
+	case strings.HasPrefix(query, "INSERT INTO `files` ("):
+		if f.failCreateFile > 0 {
+			f.failCreateFile--
+			return nil, 0, errFakeInsert
+		}
+		columns := strings.Split(query[len("INSERT INTO `files` ("):strings.Index(query, ")")], ",")
+		file := map[string]driver.Value{}
+		for i, column := range columns {
+			file[strings.Trim(column, "` ")] = args[i]
+		}
+		f.files[file["id"].(string)] = file
+		return nil, 1, nil
+	}
+	f.t.Errorf("unexpected query: %s", query)
+	return nil, 0, fmt.Errorf("unexpected query: %s", query)
+}
+
+// fileBlob returns the blob holding the content of the recorded file fileId, and its hash
+func (f *fakeDatabase) fileBlob(fileId string) (*fakeBlob, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.files[fileId]
+	if !ok {
+		return nil, ""
+	}
+	checksum := file["checksum"].(string)
+	return f.blobs[checksum], checksum
+}
+
+func affected(ok bool) int64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+// bytesValue reads a []byte argument, which database/sql passes as nil when empty
+func bytesValue(value driver.Value) []byte {
+	b, _ := value.([]byte)
+	return append([]byte(nil), b...)
+}
+
+// structRows returns the row of a model, with the column names gorm gives its fields
+func structRows(model any) *fakeRows {
+	parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(err)
+	}
+	value := reflect.ValueOf(model)
+	rows := &fakeRows{rows: [][]driver.Value{{}}}
+	for _, field := range parsed.Fields {
+		rows.columns = append(rows.columns, field.DBName)
+		rows.rows[0] = append(rows.rows[0], value.FieldByIndex(field.StructField.Index).Interface())
+	}
+	return rows
+}
+
+// empty drops the rows, keeping the columns
+func (r *fakeRows) empty() *fakeRows {
+	r.rows = nil
+	return r
+}
+
+// fakeRows is the result of a query
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeConnector hands out connections to a fakeDatabase
+type fakeConnector struct{ fake *fakeDatabase }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c.fake}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+// fakeConn runs statements without preparing them; transactions commit nothing, as every
+// statement applies at once
+type fakeConn struct{ fake *fakeDatabase }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, rowsAffected, err := c.fake.query(query, values(args))
+	return fakeResult(rowsAffected), err
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, _, err := c.fake.query(query, values(args))
+	if err == nil && rows == nil {
+		rows = &fakeRows{}
+	}
+	return rows, err
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// fakeResult reports the rows affected by a statement; no table of the fake has an auto-increment key
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
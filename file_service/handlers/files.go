@@ -0,0 +1,165 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/models"
+	"urulink.com/platform/httpclient"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// filesPageSize is the default and maximum number of files returned by ListFiles
+const filesPageSize = 100
+
+// accessibleFile loads the catalog entry of a file the caller may read: their own files and the
+// files shared with them in a conversation. On failure it returns the status code and message to answer with.
+func (h *Handler) accessibleFile(c *fiber.Ctx, fileId string) (models.FileInfo, int, string) {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+	if !helper.IsValidFileId(fileId) {
+		return models.FileInfo{}, 400, "invalid file id"
+	}
+
+	fileInfo, err := h.Database.GetFileById(fileId)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve file metadata", err)
+		return models.FileInfo{}, 500, "failed to retrieve file"
+	}
+	if fileInfo.Id == "" {
+		return models.FileInfo{}, 404, "file not found"
+	}
+
+	// Anyone but the owner must have received the file in a conversation
+	if fileInfo.OwnerId != userJwtInfo.Uid {
+		allowed, err := h.hasConversationAccess(c, fileId)
+		if err != nil {
+			logs.Error(c, "Failed to check file access", err)
+			return models.FileInfo{}, 502, "failed to check file access"
+		}
+		if !allowed {
+			logs.Info(c, "File access denied", map[string]interface{}{"fileId": fileId, "uid": userJwtInfo.Uid})
+			return models.FileInfo{}, 403, "access denied"
+		}
+	}
+	return fileInfo, 0, ""
+}
+
+// hasConversationAccess asks the message service whether the caller exchanged the file in a conversation
+func (h *Handler) hasConversationAccess(c *fiber.Ctx, fileId string) (bool, error) {
+	statusCode, _, err := httpclient.Call(fiber.MethodGet, h.EnvManger.MessageServiceUrl+"/files/"+fileId+"/access", c.Get(fiber.HeaderAuthorization), nil)
+	if err != nil {
+		return false, err
+	}
+	switch statusCode {
+	case 200:
+		return true, nil
+	case 403, 404:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status code %d from message service", statusCode)
+}
+
+// ListFiles returns the files uploaded by the authenticated user, newest first, paginated with ?limit and ?offset
+func (h *Handler) ListFiles(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	limit := c.QueryInt("limit", filesPageSize)
+	if limit <= 0 || limit > filesPageSize {
+		limit = filesPageSize
+	}
+	offset := c.QueryInt("offset")
+	if offset < 0 {
+		offset = 0
+	}
+
+	files, err := h.Database.GetFilesByOwner(userJwtInfo.Uid, limit, offset)
+	if err != nil {
+		logs.Error(c, "Failed to list files", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	if files == nil {
+		files = []models.FileInfo{}
+	}
+	return response.HandleInformation(c, 200, files)
+}
+
+// GetFileMetadata returns the catalog entry of a file the caller may read
+func (h *Handler) GetFileMetadata(c *fiber.Ctx) error {
+	fileInfo, status, errMsg := h.accessibleFile(c, c.Params("id"))
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+	return response.HandleInformation(c, 200, fileInfo)
+}
+
+// DeleteFile removes a file owned by the caller from storage and from the catalog
+func (h *Handler) DeleteFile(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+	fileId := c.Params("id")
+	if !helper.IsValidFileId(fileId) {
+		return c.Status(400).SendString("invalid file id")
+	}
+
+	fileInfo, err := h.Database.GetFileById(fileId)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve file metadata", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	if fileInfo.Id == "" || fileInfo.OwnerId != userJwtInfo.Uid {
+		return c.Status(404).SendString("file not found")
+	}
+
+	if err := h.removeFile(fileInfo); err != nil {
+		logs.Error(c, "Failed to delete file", err)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	logs.Info(c, "File deleted", map[string]interface{}{"fileId": fileId})
+	return c.SendStatus(200)
+}
+
+// removeFile deletes a file with its previews from storage and from the catalog and frees its
+// space in the quota of its owner
+func (h *Handler) removeFile(fileInfo models.FileInfo) error {
+	// A file stored before deduplication owns its object. Remove it first: a leftover row can be
+	// deleted again, a leftover object would be untracked.
+	if fileInfo.ObjectName == "" {
+		if err := h.Storage.DeleteFile(h.Ctx, fileInfo.Id); err != nil {
+			return fmt.Errorf("failed to delete file from storage: %w", err)
+		}
+	}
+	h.deletePreviews(fileInfo)
+	if err := h.Database.DeleteFile(fileInfo.Id); err != nil {
+		return fmt.Errorf("failed to delete file metadata: %w", err)
+	}
+	// Shared content is only dropped by the blob collector once no file references it anymore
+	if fileInfo.ObjectName != "" {
+		if err := h.Database.ReleaseBlob(fileInfo.Checksum); err != nil {
+			log.Printf("[ERROR] Failed to release content of %s: %v", fileInfo.Id, err)
+		}
+	}
+	h.releaseStorage(fileInfo.OwnerId, fileInfo.Size)
+	return nil
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/platform/httpclient"
+)
+
+// gcBatchSize is the number of files or objects checked per database query and message service call
+const gcBatchSize = 500
+
+// Reasons of the garbage collector for removing a file or an object
+const (
+	gcUnreferenced = "unreferenced" // File never sent in a message, or only in deleted messages
+	gcRetention    = "retention"    // File older than the retention of its category
+	gcOrphaned     = "orphaned"     // Storage object no file, blob, preview or upload uses
+)
+
+// ScheduleGC runs the garbage collector every interval, following GC_DRY_RUN
+func (h *Handler) ScheduleGC(interval time.Duration) {
+	for range time.Tick(interval) {
+		report := h.CollectGarbage(h.EnvManger.GCDryRun == "true")
+		log.Printf("[INFO] Garbage collection finished: dryRun: %t, filesChecked: %d, objectsChecked: %d, removed: %d, removedBytes: %d, errors: %d",
+			report.DryRun, report.FilesChecked, report.ObjectsChecked, len(report.Removed), report.RemovedBytes, len(report.Errors))
+		for _, skipped := range report.Skipped {
+			log.Printf("[INFO] Garbage collection skipped %s", skipped)
+		}
+		for _, err := range report.Errors {
+			log.Printf("[ERROR] Garbage collection: %s", err)
+		}
+	}
+}
+
+// CollectGarbage removes the files nobody can reach anymore and the storage objects nothing tracks:
+//   - files older than the retention of their category in the file type policy
+//   - files older than the grace period that no message references
+//   - storage objects older than the grace period that belong to no file, blob, preview or upload
+//
+// With dryRun nothing is removed; the report lists what would be.
+func (h *Handler) CollectGarbage(dryRun bool) models.GCReport {
+	now := time.Now()
+	report := models.GCReport{DryRun: dryRun, StartedAt: now.Unix(), Removed: []models.GCEntry{}}
+
+	h.collectFiles(&report, h.Policies.Policy(), now)
+	h.collectObjects(&report, now)
+	// Blobs only lose their last reference in this pass when files were actually removed
+	if !dryRun {
+		h.collectBlobs()
+	}
+
+	report.FinishedAt = time.Now().Unix()
+	return report
+}
+
+// collectFiles walks the files older than the grace period and removes the expired and unreferenced ones
+func (h *Handler) collectFiles(report *models.GCReport, pol *policy.Policy, now time.Time) {
+	checkReferences := h.EnvManger.ServiceToken != ""
+	if !checkReferences {
+		report.Skipped = append(report.Skipped, "unreferenced files: SERVICE_TOKEN is not set")
+	}
+
+	afterId := ""
+	for {
+		files, err := h.Database.GetFilesCreatedBefore(now.Add(-h.GCGrace).Unix(), afterId, gcBatchSize)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to list files: %v", err))
+			return
+		}
+		if len(files) == 0 {
+			return
+		}
+		afterId = files[len(files)-1].Id
+		report.FilesChecked += len(files)
+
+		var candidates []models.FileInfo
+		for _, fileInfo := range files {
+			// Files recorded before their category was stored get it from their extension
+			if fileInfo.Category == "" {
+				fileInfo.Category = pol.Category(fileInfo.OriginalName)
+			}
+			if retention := pol.Retention(fileInfo.Category); retention > 0 && fileInfo.CreatedAt < now.Add(-retention).Unix() {
+				h.collectFile(report, fileInfo, gcRetention)
+				continue
+			}
+			candidates = append(candidates, fileInfo)
+		}
+
+		if checkReferences && len(candidates) > 0 {
+			ids := make([]string, len(candidates))
+			for i, fileInfo := range candidates {
+				ids[i] = fileInfo.Id
+			}
+			referenced, err := h.referencedFiles(ids)
+			if err != nil {
+				// Without an answer every file is assumed to be referenced
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to check message references: %v", err))
+				checkReferences = false
+			}
+			for _, fileInfo := range candidates {
+				if checkReferences && !referenced[fileInfo.Id] {
+					h.collectFile(report, fileInfo, gcUnreferenced)
+				}
+			}
+		}
+
+		if len(files) < gcBatchSize {
+			return
+		}
+	}
+}
+
+// collectFile removes a file, unless in a dry run, and records it in the report
+func (h *Handler) collectFile(report *models.GCReport, fileInfo models.FileInfo, reason string) {
+	if !report.DryRun {
+		if err := h.removeFile(fileInfo); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to remove file %s: %v", fileInfo.Id, err))
+			return
+		}
+	}
+	report.Removed = append(report.Removed, models.GCEntry{
+		Kind:     "file",
+		Name:     fileInfo.Id,
+		Reason:   reason,
+		Category: fileInfo.Category,
+		Size:     fileInfo.Size,
+	})
+	report.RemovedBytes += fileInfo.Size
+}
+
+// referencedFiles asks the message service which of the given files are attached to a message
+func (h *Handler) referencedFiles(ids []string) (map[string]bool, error) {
+	statusCode, body, err := httpclient.Do(fiber.MethodPost, h.EnvManger.MessageServiceUrl+"/internal/files/referenced",
+		map[string]string{httpclient.ServiceTokenHeader: h.EnvManger.ServiceToken}, models.FileIdList{FileIds: ids})
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code %d from message service", statusCode)
+	}
+
+	list, err := httpclient.Decode[models.FileIdList](body)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(list.FileIds))
+	for _, id := range list.FileIds {
+		referenced[id] = true
+	}
+	return referenced, nil
+}
+
+// collectObjects removes the storage objects older than the grace period that nothing tracks, such as
+// the leftovers of uploads interrupted before their file was recorded
+func (h *Handler) collectObjects(report *models.GCReport, now time.Time) {
+	objects, err := h.Storage.ListFiles(h.Ctx, "")
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to list storage objects: %v", err))
+		return
+	}
+
+	// Recent objects may belong to an upload that is being recorded right now
+	cutoff := now.Add(-h.GCGrace)
+	var candidates []string
+	sizes := map[string]int64{}
+	for _, object := range objects {
+		if object.LastModified.Before(cutoff) {
+			candidates = append(candidates, object.Name)
+			sizes[object.Name] = object.Size
+		}
+	}
+	report.ObjectsChecked = len(objects)
+
+	for start := 0; start < len(candidates); start += gcBatchSize {
+		batch := candidates[start:min(start+gcBatchSize, len(candidates))]
+		tracked, err := h.trackedObjects(batch)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to check storage objects: %v", err))
+			return
+		}
+
+		for _, name := range batch {
+			if tracked[name] {
+				continue
+			}
+			if !report.DryRun {
+				if err := h.Storage.DeleteFile(h.Ctx, name); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("failed to remove object %s: %v", name, err))
+					continue
+				}
+			}
+			report.Removed = append(report.Removed, models.GCEntry{Kind: "object", Name: name, Reason: gcOrphaned, Size: sizes[name]})
+			report.RemovedBytes += sizes[name]
+		}
+	}
+}
+
+// trackedObjects returns which of the given object names belong to a blob, to the preview of a file,
+// to a file stored before deduplication or to an upload in progress
+func (h *Handler) trackedObjects(names []string) (map[string]bool, error) {
+	var blobNames, fileIds, others []string
+	for _, name := range names {
+		switch kind, key := objectKind(name); kind {
+		case "blob":
+			blobNames = append(blobNames, key)
+		case "preview":
+			fileIds = append(fileIds, key)
+		default:
+			fileIds = append(fileIds, key)
+			others = append(others, key)
+		}
+	}
+
+	blobs, files, uploads := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	var err error
+	if len(blobNames) > 0 {
+		if blobs, err = h.Database.ExistingBlobObjects(blobNames); err != nil {
+			return nil, err
+		}
+	}
+	if len(fileIds) > 0 {
+		if files, err = h.Database.ExistingFiles(fileIds); err != nil {
+			return nil, err
+		}
+	}
+	if len(others) > 0 {
+		if uploads, err = h.Database.ExistingUploadObjects(others); err != nil {
+			return nil, err
+		}
+	}
+
+	tracked := make(map[string]bool, len(names))
+	for _, name := range names {
+		switch kind, key := objectKind(name); kind {
+		case "blob":
+			tracked[name] = blobs[key]
+		case "preview":
+			tracked[name] = files[key]
+		default:
+			tracked[name] = files[key] || uploads[key]
+		}
+	}
+	return tracked, nil
+}
+
+// objectKind tells what a storage object holds: "blob" with the name of the object, "preview" with
+// the ID of its file, or "file" for the objects named after a file or an upload
+func objectKind(name string) (string, string) {
+	if strings.HasPrefix(name, "blobs/") {
+		return "blob", name
+	}
+	if rest, ok := strings.CutPrefix(name, "previews/"); ok {
+		fileId, _, _ := strings.Cut(rest, "/")
+		return "preview", fileId
+	}
+	return "file", name
+}
+
+// parseDuration reads a duration such as "24h" from the environment
+func parseDuration(envVar, value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		panic("invalid " + envVar + ": " + value)
+	}
+	return duration
+}
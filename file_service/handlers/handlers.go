@@ -22,19 +22,40 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"time"
 
-	"urulink.com/file_service/env"     // Package to manage environment variables
-	"urulink.com/file_service/storage" // Package for MinIO storage operations
+	"urulink.com/file_service/db"         // Package for the file catalog database
+	"urulink.com/file_service/encryption" // Package for the encryption at rest
+	"urulink.com/file_service/env"        // Package to manage environment variables
+	"urulink.com/file_service/links"      // Package for the signed file links
+	"urulink.com/file_service/models"     // Package for the data models
+	"urulink.com/file_service/policy"     // Package for the file type policy
+	"urulink.com/file_service/scanner"    // Package for malware scanners
+	"urulink.com/file_service/storage"    // Package for the storage backends
+	"urulink.com/file_service/worker"     // Package for background worker pools
 )
 
-// Handler struct stores environment configuration, MinIO storage instance, and context
+// Handler struct stores environment configuration, storage backend, and context
 type Handler struct {
-	EnvManger *env.EnvManger        // Environment manager for accessing MinIO configurations
-	Minio     *storage.MinioStorage // Instance of MinIO storage to handle file operations
-	Ctx       context.Context       // Context for handling request lifetimes
+	EnvManger *env.EnvManger                // Environment manager for accessing the service configuration
+	Storage   storage.Storage               // Storage backend holding the files
+	Database  *db.Database                  // File catalog database
+	Scanner   scanner.Scanner               // Malware scanner checking every upload
+	Scans     *worker.Pool[string]          // Background workers scanning files, by file ID
+	Policies  *policy.Store                 // File type policy, reloaded when its file changes
+	Keys      *encryption.Keyring           // Master keys wrapping the data keys of stored files
+	Previews  *worker.Pool[models.FileInfo] // Background workers generating image previews
+	Documents *worker.Pool[documentJob]     // Background workers reading the previews of documents
+	Links     *links.Signer                 // Signer of the short-lived links serving files without a login
+
+	DefaultQuota int64           // Quota of users without their own or a group quota
+	QuotaAdmins  map[string]bool // Users allowed to change quotas
+	GCInterval   time.Duration   // Interval of scheduled garbage collections, 0 when they are disabled
+	GCGrace      time.Duration   // Age before untracked files and objects are collected
+	Ctx          context.Context // Context for handling request lifetimes
 }
 
-// Init initializes the Handler struct and connects to the MinIO server
+// Init initializes the Handler struct and connects to the storage backend
 func Init() Handler {
 	var err error
 	var handlers_data Handler
@@ -42,13 +63,84 @@ func Init() Handler {
 
 	handlers_data.Ctx = context.Background() // Initialize a background context for the handler
 
-	// Initialize MinIO storage instance with environment variables and context
-	handlers_data.Minio, err = storage.InitMinio(env.MinioHost, env.MinioKey, env.MinioSecret, env.MinioBucket, handlers_data.Ctx)
+	// Initialize the selected storage backend with environment variables and context
+	switch env.StorageBackend {
+	case "minio":
+		handlers_data.Storage, err = storage.InitMinio(env.MinioHost, env.MinioKey, env.MinioSecret, env.MinioBucket,
+			env.MinioSecure == "true", env.MinioRegion, handlers_data.Ctx)
+	case "local":
+		handlers_data.Storage, err = storage.InitLocal(env.LocalStoragePath, env.StoragePublicUrl, env.StorageSigningKey)
+	}
 	if err != nil {
-		fmt.Println(err)                                 // Print the error if initialization fails
-		panic("failed to connect to the Minio server :") // Panic with an error message
+		fmt.Println(err)                                  // Print the error if initialization fails
+		panic("failed to initialize the storage backend") // Panic with an error message
 	}
 	handlers_data.EnvManger = env
 
+	// Construct the DSN (Data Source Name) for MySQL using environment variables
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+		env.DBUser,
+		env.DBPassword,
+		env.DBHost,
+		env.DBPort,
+		env.DBName,
+	)
+
+	// Initialize the file catalog database connection
+	handlers_data.Database, err = db.UruLinkInit(dsn)
+	if err != nil {
+		fmt.Println(err)
+		panic("failed to connect to the database!")
+	}
+
+	// Load the master keys encrypting the stored files
+	handlers_data.Keys, err = encryption.ParseKeyring(env.MasterKeys, env.MasterKeyId)
+	if err != nil {
+		fmt.Println(err)
+		panic("failed to load the master keys")
+	}
+
+	// Load the file type policy
+	handlers_data.Policies, err = policy.Load(env.FilePolicyPath)
+	if err != nil {
+		fmt.Println(err)
+		panic("failed to load the file type policy")
+	}
+
+	// Load the storage quota settings
+	handlers_data.DefaultQuota = parseQuota(env.DefaultUserQuota)
+	handlers_data.QuotaAdmins = parseQuotaAdmins(env.QuotaAdmins)
+
+	// Load the garbage collection schedule
+	if env.GCInterval != "" {
+		handlers_data.GCInterval = parseDuration("GC_INTERVAL", env.GCInterval)
+	}
+	handlers_data.GCGrace = parseDuration("GC_GRACE_PERIOD", env.GCGracePeriod)
+
+	// Load the key signing the file links
+	if env.LinkKey == "" {
+		panic("FILE_LINK_KEY must not be empty")
+	}
+	handlers_data.Links = links.NewSigner([]byte(env.LinkKey), parseDuration("FILE_LINK_EXPIRY", env.LinkExpiry))
+
+	// Connect the malware scanner and start the workers scanning new files in the background
+	switch env.Scanner {
+	case "clamd":
+		handlers_data.Scanner, err = scanner.NewClamd(env.ClamdAddress)
+	case "fake":
+		handlers_data.Scanner = scanner.Fake{}
+	}
+	if err != nil {
+		fmt.Println(err)
+		panic("failed to initialize the malware scanner")
+	}
+	handlers_data.Scans = worker.NewPool(scanWorkers, scanQueueSize, handlers_data.scanFile)
+
+	// Start the workers generating image previews in the background
+	handlers_data.Previews = worker.NewPool(previewWorkers, previewQueueSize, handlers_data.generatePreview)
+
+	// Start the workers reading the previews of text files and PDFs in the background
+	handlers_data.Documents = worker.NewPool(documentWorkers, documentQueueSize, handlers_data.describeDocument)
+
 	return handlers_data
 }
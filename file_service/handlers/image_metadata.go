@@ -17,11 +17,12 @@
  * along with urulink. If not, see <http://www.gnu.org/licenses/>.
  */
 
-package response
+package handlers
 
-import "github.com/gofiber/fiber/v2"
+import "urulink.com/file_service/helper"
 
-func HandleInformation(c *fiber.Ctx, statusCode int, data interface{}) error {
-	c.Status(statusCode)
-	return c.JSON(data)
+// stripsMetadata reports whether the metadata of uploaded files of this type is removed,
+// following the STRIP_IMAGE_METADATA policy of the deployment
+func (h *Handler) stripsMetadata(mimeType string) bool {
+	return h.EnvManger.StripImageMetadata != "false" && helper.HasStrippableMetadata(mimeType)
 }
@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// maxLinkBatch is the number of files links can be requested for at once
+const maxLinkBatch = 100
+
+// GetFileUrls issues short-lived links for the files the caller may download. Messages only keep
+// file IDs, so clients exchange them here for URLs usable by <img>, <video> and the like; the same
+// link is returned until shortly before it expires, letting browsers cache what it serves.
+func (h *Handler) GetFileUrls(c *fiber.Ctx) error {
+	var input models.FileIdList
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).SendString("invalid request body")
+	}
+	if len(input.FileIds) == 0 || len(input.FileIds) > maxLinkBatch {
+		return c.Status(400).SendString("file_ids must hold 1 to " + strconv.Itoa(maxLinkBatch) + " file IDs")
+	}
+
+	now := time.Now()
+	result := models.FileLinks{Links: map[string]models.FileLink{}, Errors: map[string]string{}}
+	for _, fileId := range input.FileIds {
+		if _, done := result.Links[fileId]; done {
+			continue
+		}
+		fileInfo, status, errMsg := h.downloadableFile(c, fileId)
+		if status != 0 {
+			result.Errors[fileId] = errMsg
+			continue
+		}
+		result.Links[fileId] = h.fileLink(fileInfo, now)
+	}
+	return response.HandleInformation(c, 200, result)
+}
+
+// fileLink issues the links of a file and of its previews when they are ready
+func (h *Handler) fileLink(fileInfo models.FileInfo, now time.Time) models.FileLink {
+	link := h.Links.Link(fileInfo.Id, "", now)
+	fileLink := models.FileLink{Url: h.EnvManger.PublicUrl + link.Path, ExpiresAt: link.ExpiresAt}
+	if fileInfo.PreviewStatus != previewReady {
+		return fileLink
+	}
+
+	fileLink.Previews = map[string]string{}
+	for variant := range previewPaths(fileInfo.Id) {
+		link := h.Links.Link(fileInfo.Id, variant, now)
+		fileLink.Previews[variant] = h.EnvManger.PublicUrl + link.Path
+		fileLink.ExpiresAt = min(fileLink.ExpiresAt, link.ExpiresAt)
+	}
+	return fileLink
+}
+
+// GetLinkedFile streams a file through a link issued by GetFileUrls
+func (h *Handler) GetLinkedFile(c *fiber.Ctx) error {
+	fileInfo, status, errMsg := h.linkedFile(c, "")
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+	return h.sendFile(c, fileInfo)
+}
+
+// GetLinkedPreview streams a preview variant through a link issued by GetFileUrls
+func (h *Handler) GetLinkedPreview(c *fiber.Ctx) error {
+	variant := c.Params("variant")
+	fileInfo, status, errMsg := h.linkedFile(c, variant)
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+	return h.sendPreview(c, fileInfo, variant)
+}
+
+// linkedFile checks the signature of a link and loads the file it serves. Browsers may cache the
+// content until the link expires.
+func (h *Handler) linkedFile(c *fiber.Ctx, variant string) (models.FileInfo, int, string) {
+	fileId := c.Params("id")
+	expires := c.Query("expires")
+	if !h.Links.Verify(fileId, variant, expires, c.Query("signature"), time.Now()) {
+		return models.FileInfo{}, 403, "invalid or expired link"
+	}
+
+	fileInfo, err := h.Database.GetFileById(fileId)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve file metadata", err)
+		return models.FileInfo{}, 500, "failed to retrieve file"
+	}
+	if fileInfo.Id == "" {
+		return models.FileInfo{}, 404, "file not found"
+	}
+	// Links are only issued for clean files, but a rescan may have quarantined the file since
+	if fileInfo.ScanStatus != scanClean {
+		return models.FileInfo{}, 403, "file is quarantined"
+	}
+
+	expiresAt, _ := strconv.ParseInt(expires, 10, 64)
+	c.Set(fiber.HeaderCacheControl, "private, max-age="+strconv.FormatInt(max(expiresAt-time.Now().Unix(), 0), 10))
+	return fileInfo, 0, ""
+}
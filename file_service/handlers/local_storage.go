@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/storage"
+	"urulink.com/platform/logs"
+)
+
+// presignedObject returns the local storage backend and the object named in the URL of a
+// presigned request, after checking its signature. On failure it returns the status code to answer with.
+func (h *Handler) presignedObject(c *fiber.Ctx, contentType string, size int64) (*storage.LocalStorage, string, int) {
+	local, ok := h.Storage.(*storage.LocalStorage)
+	if !ok {
+		return nil, "", 404
+	}
+	objectName := c.Params("*")
+	if !local.VerifyPresigned(c.Method(), objectName, contentType, size, c.Query("expires"), c.Query("signature")) {
+		return nil, "", 403
+	}
+	return local, objectName, 0
+}
+
+// GetPresignedObject serves an object of the local storage backend through a presigned URL.
+// MinIO serves its presigned URLs itself, so this route only answers for local storage.
+func (h *Handler) GetPresignedObject(c *fiber.Ctx) error {
+	local, objectName, status := h.presignedObject(c, "", 0)
+	if status != 0 {
+		return c.SendStatus(status)
+	}
+
+	info, err := local.StatFile(h.Ctx, objectName)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return c.SendStatus(404)
+		}
+		logs.Error(c, "Failed to stat object", err)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	start, end, partial, err := helper.ParseRange(c.Get(fiber.HeaderRange), info.Size)
+	if err != nil {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+		return c.Status(fiber.StatusRequestedRangeNotSatisfiable).SendString(err.Error())
+	}
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderContentType, info.ContentType)
+	if info.Size == 0 {
+		return c.Status(200).Send(nil)
+	}
+
+	reader, err := local.DownloadFile(h.Ctx, objectName, start, end)
+	if err != nil {
+		logs.Error(c, "Failed to open object stream", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	status = 200
+	if partial {
+		status = fiber.StatusPartialContent
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, info.Size))
+	}
+	return c.Status(status).SendStream(reader, int(end-start+1))
+}
+
+// PutPresignedObject stores an object in the local storage backend through a presigned PUT URL.
+// The signature covers the content type and size, so the request must match what was presigned.
+// The body is streamed to the object instead of being buffered in memory.
+func (h *Handler) PutPresignedObject(c *fiber.Ctx) error {
+	size := int64(c.Request().Header.ContentLength())
+	if size < 0 {
+		return c.Status(fiber.StatusLengthRequired).SendString("Content-Length is required")
+	}
+	contentType := c.Get(fiber.HeaderContentType)
+	local, objectName, status := h.presignedObject(c, contentType, size)
+	if status != 0 {
+		return c.SendStatus(status)
+	}
+
+	// A presigned URL uploads a new object once; it cannot replace a registered file
+	if _, err := local.StatFile(h.Ctx, objectName); err == nil {
+		return c.Status(409).SendString("object already exists")
+	}
+
+	if err := local.UploadFile(h.Ctx, requestBody(c), objectName, size, contentType, nil); err != nil {
+		logs.Error(c, "Failed to store object", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	return c.SendStatus(200)
+}
+
+// maxPostFormOverhead is how many bytes a presigned POST form may carry besides the file itself
+const maxPostFormOverhead = 64 * 1024
+
+// PostPresignedObject stores an object in the local storage backend through a presigned POST form
+// holding the file in its "file" field. The content type and size are signed in the URL and
+// checked before the form is read.
+func (h *Handler) PostPresignedObject(c *fiber.Ctx) error {
+	contentType := c.Query("content_type")
+	size, err := strconv.ParseInt(c.Query("size"), 10, 64)
+	if err != nil {
+		return c.SendStatus(403)
+	}
+	local, objectName, status := h.presignedObject(c, contentType, size)
+	if status != 0 {
+		return c.SendStatus(status)
+	}
+
+	length := int64(c.Request().Header.ContentLength())
+	if length < 0 {
+		return c.Status(fiber.StatusLengthRequired).SendString("Content-Length is required")
+	}
+	if length > size+maxPostFormOverhead {
+		return c.Status(413).SendString("request is larger than the presigned file")
+	}
+	if _, err := local.StatFile(h.Ctx, objectName); err == nil {
+		return c.Status(409).SendString("object already exists")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).SendString("file field is required")
+	}
+	if file.Size != size {
+		return c.Status(400).SendString("file size does not match the presigned size")
+	}
+	if field := c.FormValue(fiber.HeaderContentType); field != "" && field != contentType {
+		return c.Status(400).SendString("Content-Type does not match the presigned type")
+	}
+
+	fileData, err := file.Open()
+	if err != nil {
+		logs.Error(c, "Failed to open file", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	defer fileData.Close()
+	if err := local.UploadFile(h.Ctx, fileData, objectName, size, contentType, nil); err != nil {
+		logs.Error(c, "Failed to store object", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	return c.SendStatus(204)
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/storage"
+)
+
+// newPresignedApp serves the presigned storage routes of a local backend, configured like main
+func newPresignedApp(t *testing.T) (*fiber.App, *storage.LocalStorage) {
+	t.Helper()
+	local, err := storage.InitLocal(t.TempDir(), "", "signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &Handler{Storage: local, Ctx: context.Background()}
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Use(DiscardUnreadBody)
+	app.Get("/storage/*", handler.GetPresignedObject)
+	app.Put("/storage/*", handler.PutPresignedObject)
+	app.Post("/storage/*", handler.PostPresignedObject)
+	return app, local
+}
+
+func testRequest(t *testing.T, app *fiber.App, req *http.Request) (int, []byte) {
+	t.Helper()
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, body
+}
+
+func TestPutPresignedObjectStreamsLargeBodies(t *testing.T) {
+	app, local := newPresignedApp(t)
+	// Larger than the default BodyLimit of 4 MB
+	data := bytes.Repeat([]byte("0123456789abcdef"), 6*1024*1024/16)
+
+	putUrl, _, _ := local.PresignedPutURL(context.Background(), "big.bin", "application/octet-stream", int64(len(data)), time.Minute)
+	req := httptest.NewRequest("PUT", putUrl, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if status, body := testRequest(t, app, req); status != 200 {
+		t.Fatalf("PUT answered %d: %s", status, body)
+	}
+
+	// The same URL cannot replace the object
+	req = httptest.NewRequest("PUT", putUrl, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if status, _ := testRequest(t, app, req); status != 409 {
+		t.Fatalf("second PUT answered %d, want 409", status)
+	}
+
+	getUrl, _ := local.GeneratePresignedURL(context.Background(), "big.bin", time.Minute)
+	status, body := testRequest(t, app, httptest.NewRequest("GET", getUrl, nil))
+	if status != 200 || !bytes.Equal(body, data) {
+		t.Fatalf("GET answered %d with %d bytes", status, len(body))
+	}
+}
+
+func TestPutPresignedObjectChecksSignature(t *testing.T) {
+	app, local := newPresignedApp(t)
+	putUrl, _, _ := local.PresignedPutURL(context.Background(), "a.txt", "text/plain", 5, time.Minute)
+
+	// A different size or type than presigned is refused
+	req := httptest.NewRequest("PUT", putUrl, bytes.NewReader([]byte("hello!")))
+	req.Header.Set("Content-Type", "text/plain")
+	if status, _ := testRequest(t, app, req); status != 403 {
+		t.Fatalf("PUT of another size answered %d, want 403", status)
+	}
+	req = httptest.NewRequest("PUT", putUrl, bytes.NewReader([]byte("hello")))
+	req.Header.Set("Content-Type", "text/html")
+	if status, _ := testRequest(t, app, req); status != 403 {
+		t.Fatalf("PUT of another type answered %d, want 403", status)
+	}
+	if _, err := local.StatFile(context.Background(), "a.txt"); !storage.IsNotFound(err) {
+		t.Fatal("a refused PUT stored the object")
+	}
+}
+
+// postForm builds a multipart form with the presigned fields followed by the file
+func postForm(t *testing.T, fields map[string]string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	part, err := writer.CreateFormFile("file", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+	return &form, writer.FormDataContentType()
+}
+
+func TestPostPresignedObject(t *testing.T) {
+	app, local := newPresignedApp(t)
+	data := bytes.Repeat([]byte("x"), 5*1024*1024)
+	postUrl, fields, err := local.PresignedPostPolicy(context.Background(), "form.bin", "application/octet-stream", int64(len(data)), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file of another size than presigned is refused
+	form, contentType := postForm(t, fields, data[:100])
+	req := httptest.NewRequest("POST", postUrl, form)
+	req.Header.Set("Content-Type", contentType)
+	if status, _ := testRequest(t, app, req); status != 400 {
+		t.Fatalf("POST of another size answered %d, want 400", status)
+	}
+
+	// A tampered size in the URL breaks the signature
+	tampered, _ := url.Parse(postUrl)
+	query := tampered.Query()
+	query.Set("size", "100")
+	tampered.RawQuery = query.Encode()
+	form, contentType = postForm(t, fields, data[:100])
+	req = httptest.NewRequest("POST", tampered.String(), form)
+	req.Header.Set("Content-Type", contentType)
+	if status, _ := testRequest(t, app, req); status != 403 {
+		t.Fatalf("POST with a tampered size answered %d, want 403", status)
+	}
+
+	form, contentType = postForm(t, fields, data)
+	req = httptest.NewRequest("POST", postUrl, form)
+	req.Header.Set("Content-Type", contentType)
+	if status, body := testRequest(t, app, req); status != 204 {
+		t.Fatalf("POST answered %d: %s", status, body)
+	}
+	info, err := local.StatFile(context.Background(), "form.bin")
+	if err != nil || info.Size != int64(len(data)) || info.ContentType != "application/octet-stream" {
+		t.Fatalf("stored object %+v, %v", info, err)
+	}
+}
+
+func TestPostPresignedObjectRejectsOversizedRequests(t *testing.T) {
+	app, local := newPresignedApp(t)
+	postUrl, fields, _ := local.PresignedPostPolicy(context.Background(), "small.bin", "application/octet-stream", 10, time.Minute)
+	form, contentType := postForm(t, fields, bytes.Repeat([]byte("x"), maxPostFormOverhead+100))
+	req := httptest.NewRequest("POST", postUrl, form)
+	req.Header.Set("Content-Type", contentType)
+	if status, _ := testRequest(t, app, req); status != 413 {
+		t.Fatalf("oversized POST answered %d, want 413", status)
+	}
+}
+
+func TestGetPresignedObjectRanges(t *testing.T) {
+	app, local := newPresignedApp(t)
+	if err := local.UploadFile(context.Background(), bytes.NewReader([]byte("0123456789")), "digits.txt", 10, "text/plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	getUrl, _ := local.GeneratePresignedURL(context.Background(), "digits.txt", time.Minute)
+
+	for _, test := range []struct {
+		header       string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"", 200, "", "0123456789"},
+		{"bytes=2-4", 206, "bytes 2-4/10", "234"},
+		{"bytes=7-", 206, "bytes 7-9/10", "789"},
+		{"bytes=-2", 206, "bytes 8-9/10", "89"},
+		{"bytes=10-", 416, "bytes */10", ""},
+		{"bytes=x-y", 416, "bytes */10", ""},
+	} {
+		req := httptest.NewRequest("GET", getUrl, nil)
+		if test.header != "" {
+			req.Header.Set("Range", test.header)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status || resp.Header.Get("Content-Range") != test.contentRange {
+			t.Errorf("Range %q answered %d %q, want %d %q", test.header, resp.StatusCode, resp.Header.Get("Content-Range"), test.status, test.contentRange)
+		}
+		if test.status != 416 && string(body) != test.body {
+			t.Errorf("Range %q returned %q, want %q", test.header, body, test.body)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"io"
+
+	"urulink.com/file_service/models"
+)
+
+// objectBlockSize is the smallest range read from storage by an objectReaderAt
+const objectBlockSize = 64 << 10
+
+// objectReaderAt reads the content of a file through range requests, decrypting it with key unless
+// it is nil. Zip and PDF readers read many small pieces close to each other, so the last block read
+// is kept.
+type objectReaderAt struct {
+	handler  *Handler
+	key      []byte
+	fileInfo models.FileInfo
+
+	blockStart int64
+	block      []byte
+}
+
+func (r *objectReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	read := 0
+	for read < len(p) {
+		position := offset + int64(read)
+		if position >= r.fileInfo.Size {
+			return read, io.EOF
+		}
+		if r.block == nil || position < r.blockStart || position >= r.blockStart+int64(len(r.block)) {
+			if err := r.load(position, len(p)-read); err != nil {
+				return read, err
+			}
+		}
+		read += copy(p[read:], r.block[position-r.blockStart:])
+	}
+	return read, nil
+}
+
+// load reads the block starting at offset, large enough for length bytes
+func (r *objectReaderAt) load(offset int64, length int) error {
+	end := min(offset+max(objectBlockSize, int64(length)), r.fileInfo.Size) - 1
+	object, err := r.handler.openContent(r.handler.Ctx, r.key, r.fileInfo, offset, end)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	block := make([]byte, end-offset+1)
+	if _, err := io.ReadFull(object, block); err != nil {
+		return err
+	}
+	r.blockStart, r.block = offset, block
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// userRole is the role whose file type policy applies to a user: the quota group they belong to
+func (h *Handler) userRole(userId string) (string, error) {
+	usage, err := h.Database.GetStorageUsage(userId, h.DefaultQuota)
+	return usage.GroupName, err
+}
+
+// fileRule checks the name and size of a file against the file type policy of its owner and
+// returns the matching rule. On failure it returns the status code and message to answer with.
+func (h *Handler) fileRule(c *fiber.Ctx, ownerId, fileName string, size int64) (policy.Rule, int, string) {
+	role, err := h.userRole(ownerId)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve user role", err)
+		return policy.Rule{}, 500, "failed to load the file type policy"
+	}
+
+	rule, err := h.Policies.Policy().Check(fileName, size, role)
+	if err != nil {
+		logs.Error(c, "File validation failed", err)
+		if errors.Is(err, policy.ErrTooLarge) {
+			return policy.Rule{}, 413, err.Error()
+		}
+		return policy.Rule{}, 415, err.Error()
+	}
+	return rule, 0, ""
+}
+
+// GetPolicy returns the file types the caller may upload with their size limits, so clients can
+// check files before uploading them
+func (h *Handler) GetPolicy(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	role, err := h.userRole(userJwtInfo.Uid)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve user role", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	return response.HandleInformation(c, 200, h.Policies.Policy().ForRole(role))
+}
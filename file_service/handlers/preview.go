@@ -0,0 +1,227 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/policy"
+	"urulink.com/file_service/preview"
+	"urulink.com/file_service/storage"
+	"urulink.com/platform/logs"
+)
+
+// Preview generation settings
+const (
+	previewWorkers   = 2   // Images processed at the same time
+	previewQueueSize = 256 // Images waiting for a worker before new ones are skipped
+)
+
+// Values of FileInfo.PreviewStatus
+const (
+	previewPending = "pending"
+	previewReady   = "ready"
+	previewFailed  = "failed"
+)
+
+// previewStatusFor is the initial PreviewStatus of a new file: pending for the types the file type
+// policy generates previews for, empty otherwise
+func previewStatusFor(rule policy.Rule) string {
+	if rule.Previews {
+		return previewPending
+	}
+	return ""
+}
+
+// previewObjectName is the storage object holding one preview variant of a file
+func previewObjectName(fileId, variant string) string {
+	return "previews/" + fileId + "/" + variant + ".jpg"
+}
+
+// previewPaths lists the download paths of the preview variants of a file
+func previewPaths(fileId string) map[string]string {
+	paths := make(map[string]string, len(preview.Variants))
+	for _, variant := range preview.Variants {
+		paths[variant.Name] = "/files/" + fileId + "/preview/" + variant.Name
+	}
+	return paths
+}
+
+// schedulePreview queues the preview generation of an image once its malware scan found it clean.
+// Uploads never wait for it: the previews appear in the file metadata once PreviewStatus is "ready".
+func (h *Handler) schedulePreview(fileInfo models.FileInfo) {
+	if fileInfo.PreviewStatus != previewPending {
+		return
+	}
+	if h.Previews.Submit(fileInfo) {
+		return
+	}
+
+	log.Printf("[ERROR] Preview queue is full, previews of %s skipped", fileInfo.Id)
+	if err := h.Database.UpdateFilePreview(fileInfo.Id, fileInfo.Width, fileInfo.Height, "", previewFailed); err != nil {
+		log.Printf("[ERROR] Failed to record preview status of %s: %v", fileInfo.Id, err)
+	}
+}
+
+// fileSender describes an uploaded file in upload responses, including its previews when it has some
+func fileSender(fileInfo models.FileInfo) models.FileSender {
+	sender := models.FileSender{
+		FileName:      fileInfo.Id,
+		ScanStatus:    fileInfo.ScanStatus,
+		Width:         fileInfo.Width,
+		Height:        fileInfo.Height,
+		BlurHash:      fileInfo.BlurHash,
+		PreviewStatus: fileInfo.PreviewStatus,
+		DurationMs:    fileInfo.DurationMs,
+		Waveform:      fileInfo.Waveform,
+		Archive:       fileInfo.Archive,
+		Snippet:       fileInfo.Snippet,
+		Encoding:      fileInfo.Encoding,
+		PageCount:     fileInfo.PageCount,
+		Title:         fileInfo.Title,
+	}
+	if fileInfo.PreviewStatus != "" {
+		sender.Previews = previewPaths(fileInfo.Id)
+	}
+	return sender
+}
+
+// describeImage records the dimensions and the BlurHash of an image from its stored bytes, so the
+// upload response carries them before the previews are generated. Images that cannot be decoded
+// keep the dimensions of their header.
+func describeImage(fileInfo *models.FileInfo, data []byte) {
+	if fileInfo.PreviewStatus == "" {
+		return
+	}
+	img, err := preview.Decode(data)
+	if err != nil {
+		fileInfo.Width, fileInfo.Height, _ = preview.Dimensions(bytes.NewReader(data))
+		return
+	}
+	bounds := img.Bounds()
+	fileInfo.Width, fileInfo.Height = bounds.Dx(), bounds.Dy()
+	fileInfo.BlurHash = preview.BlurHash(img)
+}
+
+// generatePreview runs on a preview worker: it stores the resized variants of an image and
+// records its dimensions and BlurHash again
+func (h *Handler) generatePreview(fileInfo models.FileInfo) {
+	width, height, blurHash, err := h.storePreviews(fileInfo)
+	status := previewReady
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate previews of %s: %v", fileInfo.Id, err)
+		status = previewFailed
+	}
+	if err := h.Database.UpdateFilePreview(fileInfo.Id, width, height, blurHash, status); err != nil {
+		log.Printf("[ERROR] Failed to record previews of %s: %v", fileInfo.Id, err)
+	}
+}
+
+// storePreviews decodes an image from storage and uploads every preview variant, encrypted with
+// the data key of the image
+func (h *Handler) storePreviews(fileInfo models.FileInfo) (int, int, string, error) {
+	key, err := h.contentKey(fileInfo)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	reader, err := h.openContent(h.Ctx, key, fileInfo, 0, fileInfo.Size-1)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	img, err := preview.Decode(data)
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	for _, variant := range preview.Variants {
+		encoded, err := preview.EncodeJPEG(preview.Resize(img, variant.MaxSide))
+		if err != nil {
+			return 0, 0, "", err
+		}
+		if err := h.storeObject(key, previewObjectName(fileInfo.Id, variant.Name), bytes.NewReader(encoded), int64(len(encoded)), "image/jpeg"); err != nil {
+			return 0, 0, "", err
+		}
+	}
+
+	// Computed from the whole image like at upload, so the BlurHash stays the one the upload returned
+	bounds := img.Bounds()
+	return bounds.Dx(), bounds.Dy(), preview.BlurHash(img), nil
+}
+
+// deletePreviews removes the preview variants of a file from storage
+func (h *Handler) deletePreviews(fileInfo models.FileInfo) {
+	if fileInfo.PreviewStatus == "" {
+		return
+	}
+	for _, variant := range preview.Variants {
+		if err := h.Storage.DeleteFile(h.Ctx, previewObjectName(fileInfo.Id, variant.Name)); err != nil {
+			log.Printf("[ERROR] Failed to delete preview %s of %s: %v", variant.Name, fileInfo.Id, err)
+		}
+	}
+}
+
+// GetFilePreview streams a preview variant (one of preview.Variants) of an image the caller may read
+func (h *Handler) GetFilePreview(c *fiber.Ctx) error {
+	fileInfo, status, errMsg := h.downloadableFile(c, c.Params("id"))
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+
+	// Previews never change for a given file ID
+	c.Set(fiber.HeaderCacheControl, "private, max-age=31536000, immutable")
+	return h.sendPreview(c, fileInfo, c.Params("variant"))
+}
+
+// sendPreview streams a preview variant of an image
+func (h *Handler) sendPreview(c *fiber.Ctx, fileInfo models.FileInfo, variant string) error {
+	if _, known := previewPaths(fileInfo.Id)[variant]; !known {
+		return c.Status(404).SendString("unknown preview variant")
+	}
+	if fileInfo.PreviewStatus != previewReady {
+		return c.Status(404).SendString("preview not available")
+	}
+
+	key, err := h.contentKey(fileInfo)
+	if err != nil {
+		logs.Error(c, "Failed to load file key", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	reader, size, err := h.openObject(key, previewObjectName(fileInfo.Id, variant))
+	if err != nil {
+		logs.Error(c, "Failed to open file preview", err)
+		if storage.IsNotFound(err) {
+			return c.Status(404).SendString("preview not found")
+		}
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "image/jpeg")
+	return c.Status(200).SendStream(reader, int(size))
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/preview"
+)
+
+func TestUploadResponseCarriesBlurHash(t *testing.T) {
+	handler, local, fake := newUploadHandler(t)
+	app := fiber.New()
+	app.Post("/upload/complete/:id", asUser("alice"), handler.CompleteUpload)
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 128, 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatal(err)
+	}
+	fake.pending["photo.png"] = &models.PendingUpload{Id: "photo.png", OwnerId: "alice", OriginalName: "photo.png", Size: int64(encoded.Len()), MimeType: "image/png"}
+	if err := local.UploadFile(context.Background(), bytes.NewReader(encoded.Bytes()), "photo.png", int64(encoded.Len()), "image/png", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The upload response describes the image before any preview is generated
+	status, body := testRequest(t, app, httptest.NewRequest("POST", "/upload/complete/photo.png", nil))
+	if status != 200 {
+		t.Fatalf("completion answered %d: %s", status, body)
+	}
+	var fileInfo models.FileInfo
+	if err := json.Unmarshal(body, &fileInfo); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := storedContent(t, handler, fake, "photo.png")
+	decoded, err := preview.Decode(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.Width != 64 || fileInfo.Height != 48 || fileInfo.PreviewStatus != previewPending || fileInfo.BlurHash != preview.BlurHash(decoded) {
+		t.Fatalf("upload response describes the image as %dx%d, %s, BlurHash %q", fileInfo.Width, fileInfo.Height, fileInfo.PreviewStatus, fileInfo.BlurHash)
+	}
+
+	// The preview worker stores the variants and records the same description
+	blob, checksum := fake.fileBlob("photo.png")
+	fileInfo.ObjectName, fileInfo.Checksum = blob.objectName, checksum
+	width, height, blurHash, err := handler.storePreviews(fileInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 64 || height != 48 || blurHash != fileInfo.BlurHash {
+		t.Errorf("preview worker describes the image as %dx%d, BlurHash %q", width, height, blurHash)
+	}
+	key, err := handler.contentKey(fileInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, variant := range preview.Variants {
+		reader, _, err := handler.openObject(key, previewObjectName("photo.png", variant.Name))
+		if err != nil {
+			t.Fatalf("%s: %v", variant.Name, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		thumbnail, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s is not a JPEG: %v", variant.Name, err)
+		}
+		if bounds := thumbnail.Bounds(); bounds.Dx() != 64 || bounds.Dy() != 48 {
+			t.Errorf("%s of a small image is %dx%d", variant.Name, bounds.Dx(), bounds.Dy())
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/scanner"
+	"urulink.com/platform/logs"
+)
+
+// Malware scan settings
+const (
+	scanWorkers    = 2                // Files scanned at the same time
+	scanQueueSize  = 1024             // Files waiting for a worker; more are picked up by the retry pass
+	scanTimeout    = 10 * time.Minute // Longest time a single scan may take
+	scanRetryAfter = 15 * time.Minute // Age after which files still pending are queued again
+	scanRetryBatch = 500              // Pending files queued again per retry pass
+)
+
+// Values of FileInfo.ScanStatus. Files are quarantined until their status is clean.
+const (
+	scanPending  = "pending"
+	scanClean    = "clean"
+	scanInfected = "infected"
+	scanFailed   = "failed" // The scanner refused the content, e.g. an archive beyond its limits
+)
+
+// scheduleScan queues the malware scan of a newly registered file. When the queue is full the file
+// stays pending and is queued again by the retry pass.
+func (h *Handler) scheduleScan(c *fiber.Ctx, fileInfo *models.FileInfo) {
+	if !h.Scans.Submit(fileInfo.Id) {
+		logs.Error(c, "Scan queue is full", fmt.Errorf("scan of %s postponed", fileInfo.Id))
+	}
+}
+
+// downloadableFile is accessibleFile for reading the content of a file, which is refused while
+// the file is quarantined
+func (h *Handler) downloadableFile(c *fiber.Ctx, fileId string) (models.FileInfo, int, string) {
+	fileInfo, status, errMsg := h.accessibleFile(c, fileId)
+	if status != 0 {
+		return fileInfo, status, errMsg
+	}
+	if status, errMsg := quarantineStatus(fileInfo.ScanStatus); status != 0 {
+		if status == 403 {
+			logs.Info(c, "Quarantined file requested", map[string]interface{}{"fileId": fileId, "scanStatus": fileInfo.ScanStatus})
+		}
+		return models.FileInfo{}, status, errMsg
+	}
+	return fileInfo, 0, ""
+}
+
+// quarantineStatus returns the status code and message refusing the content of a file with the
+// given scan status, or 0 when the file is clean
+func quarantineStatus(scanStatus string) (int, string) {
+	switch scanStatus {
+	case scanClean:
+		return 0, ""
+	case scanPending:
+		return 409, "file is still being scanned for malware"
+	default:
+		return 403, "file is quarantined"
+	}
+}
+
+// scanFile runs on a scan worker: it scans a pending file, records the verdict and releases clean
+// files for previews
+func (h *Handler) scanFile(fileId string) {
+	fileInfo, err := h.Database.GetFileById(fileId)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load file %s for scanning: %v", fileId, err)
+		return
+	}
+	// The file was deleted, or scanned by an earlier job, in the meantime
+	if fileInfo.Id == "" || fileInfo.ScanStatus != scanPending {
+		return
+	}
+
+	result, err := h.scanContent(fileInfo)
+	status := scanStatusOf(result, err)
+	switch status {
+	case scanFailed:
+		log.Printf("[ERROR] File %s cannot be scanned: %v", fileId, err)
+	case scanPending:
+		// The scanner or the storage is unavailable; the retry pass tries again later
+		log.Printf("[ERROR] Failed to scan file %s: %v", fileId, err)
+		return
+	case scanInfected:
+		log.Printf("[WARN] Malware %s found in file %s of %s, file quarantined", result.Signature, fileId, fileInfo.OwnerId)
+	}
+
+	if err := h.Database.UpdateFileScan(fileId, status, result.Signature); err != nil {
+		log.Printf("[ERROR] Failed to record scan of %s: %v", fileId, err)
+		return
+	}
+	if status == scanClean {
+		h.schedulePreview(fileInfo)
+	}
+}
+
+// scanStatusOf returns the scan status following a scan. Files stay pending when the scanner or
+// the storage failed, and fail when the scanner refused their content.
+func scanStatusOf(result scanner.Result, err error) string {
+	switch {
+	case errors.Is(err, scanner.ErrUnscannable):
+		return scanFailed
+	case err != nil:
+		return scanPending
+	case result.Infected:
+		return scanInfected
+	default:
+		return scanClean
+	}
+}
+
+// scanContent streams the content of a file from storage to the scanner
+func (h *Handler) scanContent(fileInfo models.FileInfo) (scanner.Result, error) {
+	ctx, cancel := context.WithTimeout(h.Ctx, scanTimeout)
+	defer cancel()
+
+	var content io.Reader = bytes.NewReader(nil)
+	if fileInfo.Size > 0 {
+		key, err := h.contentKey(fileInfo)
+		if err != nil {
+			return scanner.Result{}, err
+		}
+		reader, err := h.openContent(ctx, key, fileInfo, 0, fileInfo.Size-1)
+		if err != nil {
+			return scanner.Result{}, err
+		}
+		defer reader.Close()
+		content = reader
+	}
+	return h.Scanner.Scan(ctx, content)
+}
+
+// retryScans queues the files that are still pending a while after upload again, e.g. because
+// the scanner was unavailable or the service restarted
+func (h *Handler) retryScans(now time.Time) {
+	ids, err := h.Database.GetFilesWithScanStatus(scanPending, now.Add(-scanRetryAfter).Unix(), scanRetryBatch)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list files pending a scan: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if !h.Scans.Submit(id) {
+			return
+		}
+	}
+}
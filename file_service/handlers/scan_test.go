@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/scanner"
+	"urulink.com/file_service/storage"
+)
+
+// eicarContent is the EICAR anti-virus test string, split so this source file is not flagged itself
+var eicarContent = []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$` + `EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+
+// failingScanner fails every scan with err
+type failingScanner struct{ err error }
+
+func (s failingScanner) Scan(ctx context.Context, content io.Reader) (scanner.Result, error) {
+	return scanner.Result{}, s.err
+}
+
+func TestScanStatusTransitions(t *testing.T) {
+	local, err := storage.InitLocal(t.TempDir(), "", "signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for name, content := range map[string][]byte{"clean.txt": []byte("hello"), "eicar.txt": eicarContent, "empty.txt": nil} {
+		if err := local.UploadFile(ctx, bytes.NewReader(content), name, int64(len(content)), "text/plain", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name    string
+		fileId  string
+		size    int64
+		scanner scanner.Scanner
+		status  string
+	}{
+		{"clean", "clean.txt", 5, scanner.Fake{}, scanClean},
+		{"empty", "empty.txt", 0, scanner.Fake{}, scanClean},
+		{"infected", "eicar.txt", int64(len(eicarContent)), scanner.Fake{}, scanInfected},
+		{"refused by the scanner", "clean.txt", 5, failingScanner{scanner.ErrUnscannable}, scanFailed},
+		{"scanner unavailable", "clean.txt", 5, failingScanner{errors.New("connection refused")}, scanPending},
+		{"content missing", "missing.txt", 5, scanner.Fake{}, scanPending},
+	} {
+		handler := &Handler{Storage: local, Scanner: test.scanner, Ctx: ctx}
+		fileInfo := models.FileInfo{Id: test.fileId, Size: test.size, ScanStatus: scanPending}
+		result, err := handler.scanContent(fileInfo)
+		if status := scanStatusOf(result, err); status != test.status {
+			t.Errorf("%s: status = %q (%v), want %q", test.name, status, err, test.status)
+		}
+	}
+}
+
+func TestQuarantineStatus(t *testing.T) {
+	for _, test := range []struct {
+		scanStatus string
+		status     int
+	}{
+		{scanClean, 0},
+		{scanPending, 409},
+		{scanInfected, 403},
+		{scanFailed, 403},
+	} {
+		if status, _ := quarantineStatus(test.scanStatus); status != test.status {
+			t.Errorf("quarantineStatus(%q) = %d, want %d", test.scanStatus, status, test.status)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/storage"
+	"urulink.com/platform/logs"
+)
+
+// tus protocol constants
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+	tusExpiry     = 24 * time.Hour   // Uploads without progress for this long are aborted
+	tusLockExpiry = 10 * time.Minute // A PATCH request that died holding an upload releases it after this long
+)
+
+// TusOptions advertises the tus version and extensions supported by the server, and the largest
+// upload the file type policy accepts for any role. It answers without a login, as browsers send
+// it as a preflight; POST /tus checks the limit of the caller
+func (h *Handler) TusOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.FormatInt(h.Policies.Policy().LargestMaxSize(), 10))
+	return c.SendStatus(204)
+}
+
+// TusCreate starts a resumable upload (tus creation extension). The declared name and size are
+// validated with the same rules as multipart uploads before any byte is accepted.
+func (h *Handler) TusCreate(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+	c.Set("Tus-Resumable", tusVersion)
+	if c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return c.Status(412).SendString("unsupported tus version")
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		return c.Status(400).SendString("invalid Upload-Length")
+	}
+
+	metadata := parseTusMetadata(c.Get("Upload-Metadata"))
+	fileName := metadata["filename"]
+	if fileName == "" {
+		return c.Status(400).SendString("filename metadata is required")
+	}
+	rule, status, errMsg := h.fileRule(c, userJwtInfo.Uid, fileName, length)
+	if status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+
+	// The declared length counts against the quota until the upload completes or is discarded
+	if status, errMsg := h.reserveStorage(c, userJwtInfo.Uid, length); status != 0 {
+		return c.Status(status).SendString(errMsg)
+	}
+
+	objectName := fmt.Sprintf("%s%s", helper.GenerateFilesName(), helper.FileExt(fileName))
+	contentType := rule.ContentType
+	storageUploadId, err := h.Storage.NewMultipartUpload(h.Ctx, objectName, contentType, map[string]string{
+		"Owner":         userJwtInfo.Uid,
+		"Original-Name": fileName,
+	})
+	if err != nil {
+		logs.Error(c, "Failed to start multipart upload", err)
+		h.releaseStorage(userJwtInfo.Uid, length)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	hashState, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		h.Storage.AbortMultipartUpload(h.Ctx, objectName, storageUploadId)
+		h.releaseStorage(userJwtInfo.Uid, length)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	now := time.Now()
+	session := models.UploadSession{
+		Id:              helper.GenerateFilesName(),
+		OwnerId:         userJwtInfo.Uid,
+		ObjectName:      objectName,
+		OriginalName:    fileName,
+		MimeType:        contentType,
+		Length:          length,
+		StorageUploadId: storageUploadId,
+		Parts:           "[]",
+		HashState:       hashState,
+		CreatedAt:       now.Unix(),
+		ExpiresAt:       now.Add(tusExpiry).Unix(),
+	}
+	if err := h.Database.CreateUploadSession(session); err != nil {
+		logs.Error(c, "Failed to store upload session", err)
+		h.Storage.AbortMultipartUpload(h.Ctx, objectName, storageUploadId)
+		h.releaseStorage(userJwtInfo.Uid, length)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	logs.Info(c, "Resumable upload created", map[string]interface{}{"uploadId": session.Id, "length": length})
+	c.Set(fiber.HeaderLocation, c.BaseURL()+"/tus/"+session.Id)
+	c.Set("Upload-Expires", time.Unix(session.ExpiresAt, 0).UTC().Format(time.RFC1123))
+	return c.SendStatus(201)
+}
+
+// TusHead reports how many bytes of an upload the server has received
+func (h *Handler) TusHead(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	session, status := h.ownUploadSession(c)
+	if status != 0 {
+		return c.SendStatus(status)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Set("Upload-Expires", time.Unix(session.ExpiresAt, 0).UTC().Format(time.RFC1123))
+	return c.SendStatus(200)
+}
+
+// TusPatch appends a chunk at the current offset. The chunk is streamed to storage in parts of
+// storage.MinPartSize; the remainder is kept with the session until the next chunk. When the
+// request breaks off, the bytes received so far are kept and the client resumes from there.
+// The object is assembled, validated and added to the catalog when the last byte arrives.
+func (h *Handler) TusPatch(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return c.Status(415).SendString("Content-Type must be application/offset+octet-stream")
+	}
+
+	session, status := h.ownUploadSession(c)
+	if status != 0 {
+		return c.SendStatus(status)
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != session.Offset {
+		return c.Status(409).SendString("Upload-Offset does not match the current offset")
+	}
+
+	// A declared length is checked up front; chunked requests are checked while they stream
+	remaining := session.Length - session.Offset
+	declared := int64(c.Request().Header.ContentLength())
+	if declared > remaining {
+		return c.Status(413).SendString("chunk exceeds Upload-Length")
+	}
+
+	// Claim the offset before touching storage, so two requests at the same offset cannot both
+	// upload the next part; the session is read again to get the state the claim applies to
+	now := time.Now()
+	claimed, err := h.Database.ClaimUploadSession(session.Id, session.Offset, now.Unix(), now.Add(tusLockExpiry).Unix())
+	if err != nil {
+		logs.Error(c, "Failed to lock upload session", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	if !claimed {
+		return c.Status(409).SendString("upload is being modified concurrently")
+	}
+	// The lock is released when the request ends, unless the saved progress released it already
+	// or the upload completed and its session is gone
+	locked := true
+	defer func() {
+		if locked {
+			if err := h.Database.UnlockUploadSession(session.Id); err != nil {
+				logs.Error(c, "Failed to unlock upload session", err)
+			}
+		}
+	}()
+	if session, err = h.Database.GetUploadSession(session.Id); err != nil || session.Id == "" {
+		logs.Error(c, "Failed to retrieve upload session", err)
+		return c.Status(500).SendString("failed to retrieve upload session")
+	}
+
+	// Restore the checksum state and the parts uploaded so far
+	hasher := sha256.New()
+	if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.HashState); err != nil {
+		logs.Error(c, "Failed to restore upload checksum", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	var parts []models.UploadPart
+	if err := json.Unmarshal([]byte(session.Parts), &parts); err != nil {
+		logs.Error(c, "Failed to decode upload parts", err)
+		return c.Status(500).SendString(err.Error())
+	}
+
+	// Read the chunk into part sized blocks, uploading every full block. One byte more than the
+	// upload still needs is read, to notice chunks exceeding Upload-Length
+	body := io.LimitReader(requestBody(c), remaining+1)
+	pending := make([]byte, len(session.Tail), storage.MinPartSize)
+	copy(pending, session.Tail)
+	var received int64
+	var readErr error
+	for readErr == nil {
+		var n int
+		n, readErr = io.ReadFull(body, pending[len(pending):cap(pending)])
+		if received += int64(n); received > remaining {
+			return c.Status(413).SendString("chunk exceeds Upload-Length")
+		}
+		hasher.Write(pending[len(pending) : len(pending)+n])
+		pending = pending[:len(pending)+n]
+
+		if len(pending) == cap(pending) {
+			etag, err := h.Storage.UploadPart(h.Ctx, session.ObjectName, session.StorageUploadId, len(parts)+1, pending)
+			if err != nil {
+				logs.Error(c, "Failed to upload part", err)
+				return c.Status(500).SendString(err.Error())
+			}
+			parts = append(parts, models.UploadPart{Number: len(parts) + 1, ETag: etag})
+			pending = pending[:0]
+		}
+	}
+	newOffset := session.Offset + received
+	complete := newOffset == session.Length
+	// The end of the body shows as EOF; anything else, or fewer bytes than declared, means the
+	// request broke off. What arrived is saved all the same
+	interrupted := !complete && ((readErr != io.EOF && readErr != io.ErrUnexpectedEOF) || (declared >= 0 && received != declared))
+	if complete && len(pending) > 0 {
+		etag, err := h.Storage.UploadPart(h.Ctx, session.ObjectName, session.StorageUploadId, len(parts)+1, pending)
+		if err != nil {
+			logs.Error(c, "Failed to upload part", err)
+			return c.Status(500).SendString(err.Error())
+		}
+		parts = append(parts, models.UploadPart{Number: len(parts) + 1, ETag: etag})
+		pending = pending[:0]
+	}
+
+	partsJson, _ := json.Marshal(parts)
+	hashState, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	// An empty request at the final offset retries a completion that failed; there is no progress to save
+	if newOffset != session.Offset {
+		previousOffset := session.Offset
+		session.Offset = newOffset
+		session.Parts = string(partsJson)
+		session.Tail = pending
+		session.HashState = hashState
+		session.ExpiresAt = time.Now().Add(tusExpiry).Unix()
+		// The last chunk keeps the lock while the upload is completed
+		session.LockedUntil = 0
+		if complete {
+			session.LockedUntil = now.Add(tusLockExpiry).Unix()
+		}
+
+		saved, err := h.Database.AdvanceUploadSession(session, previousOffset)
+		if err != nil {
+			logs.Error(c, "Failed to save upload progress", err)
+			return c.Status(500).SendString(err.Error())
+		}
+		if !saved {
+			return c.Status(409).SendString("upload was modified concurrently")
+		}
+		locked = complete
+	}
+	if interrupted {
+		logs.Error(c, "Upload chunk interrupted", readErr)
+		c.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		return c.Status(400).SendString("incomplete chunk")
+	}
+
+	if complete {
+		// The offset stays at the end when the completion fails, so the client can retry it with
+		// an empty request; uploads refused by the checks are discarded instead
+		if status, err := h.completeUpload(c, session, parts, hex.EncodeToString(hasher.Sum(nil))); err != nil {
+			c.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+			return c.Status(status).SendString(err.Error())
+		}
+		locked = false
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Set("Upload-Expires", time.Unix(session.ExpiresAt, 0).UTC().Format(time.RFC1123))
+	return c.SendStatus(204)
+}
+
+// TusDelete terminates an upload and discards the received bytes (tus termination extension)
+func (h *Handler) TusDelete(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+
+	session, status := h.ownUploadSession(c)
+	if status != 0 {
+		return c.SendStatus(status)
+	}
+
+	if err := h.abortUpload(session); err != nil {
+		logs.Error(c, "Failed to terminate upload", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	return c.SendStatus(204)
+}
+
+// completeUpload assembles the object, checks it against the file rules again and records it in the catalog.
+// Uploads refused by the checks are discarded. After any other failure the session and the assembled
+// object are kept, so the completion can run again.
+func (h *Handler) completeUpload(c *fiber.Ctx, session models.UploadSession, parts []models.UploadPart, checksum string) (int, error) {
+	// A completion that is retried finds the parts assembled already
+	if _, err := h.Storage.StatFile(h.Ctx, session.ObjectName); storage.IsNotFound(err) {
+		if err := h.Storage.CompleteMultipartUpload(h.Ctx, session.ObjectName, session.StorageUploadId, parts); err != nil {
+			logs.Error(c, "Failed to complete multipart upload", err)
+			return 500, err
+		}
+	} else if err != nil {
+		logs.Error(c, "Failed to stat uploaded file", err)
+		return 500, err
+	}
+
+	// The policy may have changed since the upload started
+	rule, status, errMsg := h.fileRule(c, session.OwnerId, session.OriginalName, session.Offset)
+	if status != 0 {
+		if status != 500 {
+			h.discardCompletedUpload(c, session)
+		}
+		return status, errors.New(errMsg)
+	}
+
+	fileInfo := models.FileInfo{
+		Id:            session.ObjectName,
+		OwnerId:       session.OwnerId,
+		OriginalName:  session.OriginalName,
+		Size:          session.Offset,
+		MimeType:      session.MimeType,
+		Category:      rule.Category,
+		CreatedAt:     time.Now().Unix(),
+		PreviewStatus: previewStatusFor(rule),
+		ScanStatus:    scanPending,
+	}
+	// The content is only known once assembled; it is checked and stored from a single read that
+	// must match the checksum of the received bytes
+	if status, err := h.storeAsBlob(c, rule, &fileInfo, session.ObjectName, checksum); err != nil {
+		logs.Error(c, "Failed to store file content", err)
+		if status != 500 {
+			h.discardCompletedUpload(c, session)
+		}
+		return status, err
+	}
+	if err := h.Database.CreateFile(fileInfo); err != nil {
+		logs.Error(c, "Failed to record file metadata", err)
+		if err := h.Database.ReleaseBlob(fileInfo.Checksum); err != nil {
+			logs.Error(c, "Failed to release file content", err)
+		}
+		return 500, err
+	}
+	// Stripping metadata may have made the file smaller than the reserved length
+	h.releaseStorage(session.OwnerId, session.Length-fileInfo.Size)
+	h.removeUploadObject(c, session.ObjectName)
+	h.scheduleScan(c, &fileInfo)
+	h.scheduleDocument(c, rule, fileInfo)
+	if err := h.Database.DeleteUploadSession(session.Id); err != nil {
+		logs.Error(c, "Failed to delete finished upload session", err)
+	}
+
+	// The tus upload URL stays the handle of the client; expose the resulting file ID alongside it
+	c.Set("Upload-File-Id", fileInfo.Id)
+	logs.Info(c, "Resumable upload completed", map[string]interface{}{"uploadId": session.Id, "fileId": fileInfo.Id})
+	return 0, nil
+}
+
+// discardCompletedUpload removes an assembled upload that failed validation, and gives back its quota
+func (h *Handler) discardCompletedUpload(c *fiber.Ctx, session models.UploadSession) {
+	if err := h.Storage.DeleteFile(h.Ctx, session.ObjectName); err != nil {
+		logs.Error(c, "Failed to remove rejected file", err)
+	}
+	if err := h.Database.DeleteUploadSession(session.Id); err != nil {
+		logs.Error(c, "Failed to delete upload session", err)
+	}
+	h.releaseStorage(session.OwnerId, session.Length)
+}
+
+// abortUpload discards the stored parts of an upload and its session, and gives back its quota.
+// Uploads whose completion failed were assembled already, so their object is removed as well.
+func (h *Handler) abortUpload(session models.UploadSession) error {
+	if err := h.Storage.AbortMultipartUpload(h.Ctx, session.ObjectName, session.StorageUploadId); err != nil && !storage.IsNoSuchUpload(err) {
+		return err
+	}
+	if err := h.Storage.DeleteFile(h.Ctx, session.ObjectName); err != nil {
+		return err
+	}
+	if err := h.Database.DeleteUploadSession(session.Id); err != nil {
+		return err
+	}
+	h.releaseStorage(session.OwnerId, session.Length)
+	return nil
+}
+
+// ownUploadSession loads the upload :id of the caller. On failure it returns the status code to answer with.
+func (h *Handler) ownUploadSession(c *fiber.Ctx) (models.UploadSession, int) {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	session, err := h.Database.GetUploadSession(c.Params("id"))
+	if err != nil {
+		logs.Error(c, "Failed to retrieve upload session", err)
+		return models.UploadSession{}, 500
+	}
+	if session.Id == "" || session.OwnerId != userJwtInfo.Uid || session.ExpiresAt < time.Now().Unix() {
+		return models.UploadSession{}, 404
+	}
+	return session, 0
+}
+
+// ExpireUploads periodically discards resumable uploads that made no progress before their expiry
+// time and presigned direct uploads that were never completed. Blobs no file references anymore
+// are collected and stalled malware scans queued again in the same pass.
+func (h *Handler) ExpireUploads(interval time.Duration) {
+	for now := range time.Tick(interval) {
+		h.expireUploadSessions(now)
+		h.expirePendingUploads(now)
+		h.collectBlobs()
+		h.retryScans(now)
+	}
+}
+
+// expireUploadSessions aborts the resumable uploads whose expiry time has passed
+func (h *Handler) expireUploadSessions(now time.Time) {
+	sessions, err := h.Database.GetExpiredUploadSessions(now.Unix())
+	if err != nil {
+		log.Printf("[ERROR] Failed to list expired uploads: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		if err := h.abortUpload(session); err != nil {
+			log.Printf("[ERROR] Failed to abort expired upload %s: %v", session.Id, err)
+			continue
+		}
+		log.Printf("[INFO] Expired upload aborted: %s", session.Id)
+	}
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma separated "key base64(value)" pairs
+func parseTusMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(value)
+	}
+	return metadata
+}
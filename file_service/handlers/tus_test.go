@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/file_service/storage"
+)
+
+// patchUpload sends a chunk of a resumable upload at the given offset
+func patchUpload(t *testing.T, app *fiber.App, id string, offset int, chunk []byte) (int, string, string) {
+	t.Helper()
+	req := httptest.NewRequest("PATCH", "/tus/"+id, bytes.NewReader(chunk))
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := new(bytes.Buffer)
+	body.ReadFrom(resp.Body)
+	return resp.StatusCode, resp.Header.Get("Upload-Offset"), body.String()
+}
+
+func TestTusPatchRetriesFailedCompletion(t *testing.T) {
+	handler, local, fake := newUploadHandler(t)
+	app := fiber.New()
+	app.Patch("/tus/:id", asUser("alice"), handler.TusPatch)
+
+	// A session as TusCreate leaves it
+	content := []byte("minutes of the planning meeting")
+	storageUploadId, err := local.NewMultipartUpload(context.Background(), "minutes.txt", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashState, _ := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	now := time.Now()
+	fake.sessions["upload"] = &models.UploadSession{
+		Id:              "upload",
+		OwnerId:         "alice",
+		ObjectName:      "minutes.txt",
+		OriginalName:    "minutes.txt",
+		MimeType:        "text/plain",
+		Length:          int64(len(content)),
+		StorageUploadId: storageUploadId,
+		Parts:           "[]",
+		HashState:       hashState,
+		CreatedAt:       now.Unix(),
+		ExpiresAt:       now.Add(tusExpiry).Unix(),
+	}
+
+	// The catalog is unavailable when the last chunk arrives; the upload stays at its final offset
+	fake.failCreateFile = 1
+	status, offset, body := patchUpload(t, app, "upload", 0, content)
+	if status != 500 || offset != strconv.Itoa(len(content)) {
+		t.Fatalf("failed completion answered %d at offset %s: %s", status, offset, body)
+	}
+	session := fake.sessions["upload"]
+	if session == nil || session.LockedUntil != 0 {
+		t.Fatalf("session after failed completion: %+v", session)
+	}
+	if len(fake.files) != 0 || fake.released["alice"] != 0 {
+		t.Fatalf("failed completion recorded %d files and released %d bytes", len(fake.files), fake.released["alice"])
+	}
+
+	// An empty request at the final offset completes the upload
+	if status, offset, body = patchUpload(t, app, "upload", len(content), nil); status != 204 || offset != strconv.Itoa(len(content)) {
+		t.Fatalf("retried completion answered %d at offset %s: %s", status, offset, body)
+	}
+	if _, ok := fake.sessions["upload"]; ok {
+		t.Error("session was kept after completion")
+	}
+	if len(fake.files) != 1 {
+		t.Fatalf("recorded %d files, want 1", len(fake.files))
+	}
+	for fileId := range fake.files {
+		stored, checksum := storedContent(t, handler, fake, fileId)
+		if !bytes.Equal(stored, content) || checksum != sha256Hex(content) {
+			t.Errorf("blob %s holds %q", checksum, stored)
+		}
+	}
+	if _, err := local.StatFile(context.Background(), "minutes.txt"); !storage.IsNotFound(err) {
+		t.Errorf("upload object was kept: %v", err)
+	}
+}
+
+func TestTusOptionsWithoutLogin(t *testing.T) {
+	handler, _, _ := newUploadHandler(t)
+	app := fiber.New()
+	app.Options("/tus", handler.TusOptions)
+
+	resp, err := app.Test(httptest.NewRequest("OPTIONS", "/tus", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 204 {
+		t.Fatalf("preflight answered %d", resp.StatusCode)
+	}
+	maxSize := strconv.FormatInt(handler.Policies.Policy().LargestMaxSize(), 10)
+	if got := resp.Header.Get("Tus-Max-Size"); got != maxSize {
+		t.Errorf("Tus-Max-Size = %s, want %s", got, maxSize)
+	}
+}
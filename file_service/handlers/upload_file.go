@@ -20,77 +20,194 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"path/filepath"
+	"io"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"urulink.com/file_service/helper"
 	"urulink.com/file_service/models"
-	"urulink.com/file_service/response"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
 )
 
 // UploadFile handles file uploads through a multipart form
 func (h *Handler) UploadFile(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
 	// Parse the multipart form from the incoming request
 	form, err := c.MultipartForm()
 	if err != nil {
-		helper.LogError(c, "Failed to parse multipart form", err)
+		logs.Error(c, "Failed to parse multipart form", err)
 		return c.Status(400).SendString(err.Error())
 	}
 
 	// Retrieve the list of uploaded files from the form
 	files := form.File["files"]
 	if len(files) == 0 {
-		helper.LogError(c, "No files found in request", fmt.Errorf("files not found"))
+		logs.Error(c, "No files found in request", fmt.Errorf("files not found"))
 		return c.Status(400).SendString("files not found")
 	}
 
 	var filesInfo []models.FileSender // Slice to hold information about uploaded files
 	for _, file := range files {      // Iterate over each file in the uploaded files
-		// Validate the file's type and size
-		err := helper.ValidateFile(file)
-		if err != nil {
-			helper.LogError(c, "File validation failed", err)
-			return c.Status(405).SendString(err.Error())
+		// Validate the file's type and size against the file type policy
+		rule, status, errMsg := h.fileRule(c, userJwtInfo.Uid, file.Filename, file.Size)
+		if status != 0 {
+			return c.Status(status).SendString(errMsg)
 		}
 
 		// Generate a random file name with the original file extension
-		randomFileName := fmt.Sprintf("%s%s", helper.GenerateFilesName(), filepath.Ext(file.Filename))
+		randomFileName := fmt.Sprintf("%s%s", helper.GenerateFilesName(), helper.FileExt(file.Filename))
 
 		// Open the file for reading
 		fileData, err := file.Open()
 		if err != nil {
-			helper.LogError(c, "Failed to open file", err) // Log error if file opening fails
-			return c.Status(500).SendString(err.Error())   // Return 500 status with error message
+			logs.Error(c, "Failed to open file", err)    // Log error if file opening fails
+			return c.Status(500).SendString(err.Error()) // Return 500 status with error message
 		}
 		defer fileData.Close() // Ensure the file is closed after processing
 
-		// Upload the file to MinIO storage
-		if err := h.Minio.UploadFile(h.Ctx, fileData, randomFileName, file.Size); err != nil {
-			helper.LogError(c, "Failed to upload file to Minio", err) // Log error if upload fails
-			return c.Status(500).SendString(err.Error())              // Return 500 status with error message
+		// Check the real content type from the first bytes; the extension alone proves nothing
+		head := make([]byte, helper.SniffLength)
+		n, err := io.ReadFull(fileData, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			logs.Error(c, "Failed to read file", err)
+			return c.Status(500).SendString(err.Error())
+		}
+		if err := rule.CheckContent(head[:n]); err != nil {
+			logs.Error(c, "File content validation failed", err)
+			return c.Status(415).SendString(err.Error())
+		}
+
+		contentType := rule.ContentType
+		content := io.MultiReader(bytes.NewReader(head[:n]), fileData)
+		fileSize := file.Size
+
+		// Images are read whole: EXIF and other metadata are removed before they are stored, and
+		// the stored bytes are described for the response
+		var imageData []byte
+		if h.stripsMetadata(contentType) || rule.Previews {
+			imageData, err = io.ReadAll(content)
+			if err != nil {
+				logs.Error(c, "Failed to read file", err)
+				return c.Status(500).SendString(err.Error())
+			}
+			if h.stripsMetadata(contentType) {
+				if imageData, err = helper.StripImageMetadata(imageData, contentType); err != nil {
+					logs.Error(c, "Failed to strip image metadata", err)
+					return c.Status(422).SendString(err.Error())
+				}
+			}
+			content = bytes.NewReader(imageData)
+			fileSize = int64(len(imageData))
 		}
 
-		// Generate a presigned URL for the uploaded file, valid for 48 hours
-		presignedURL, err := h.Minio.GeneratePresignedURL(h.Ctx, randomFileName, 48*time.Hour)
+		// Count the file against the quota of the user before storing it
+		if status, errMsg := h.reserveStorage(c, userJwtInfo.Uid, fileSize); status != 0 {
+			return c.Status(status).SendString(errMsg)
+		}
+
+		// Upload the file to a new object, encrypted with a new data key. The SHA-256 checksum is
+		// computed while the content streams to storage; the object becomes the blob of that checksum
+		dataKey, keyId, wrappedKey, err := h.Keys.NewDataKey()
 		if err != nil {
-			helper.LogError(c, "Failed to generate presigned URL", err) // Log error if URL generation fails
-			return c.Status(400).SendString(err.Error())                // Return 400 status with error message
+			logs.Error(c, "Failed to generate data key", err)
+			h.releaseStorage(userJwtInfo.Uid, fileSize)
+			return c.Status(500).SendString(err.Error())
+		}
+		objectName := newBlobObjectName()
+		hasher := sha256.New()
+		if err := h.storeObject(dataKey, objectName, io.TeeReader(content, hasher), fileSize, contentType); err != nil {
+			logs.Error(c, "Failed to upload file to storage", err) // Log error if upload fails
+			h.releaseStorage(userJwtInfo.Uid, fileSize)
+			return c.Status(500).SendString(err.Error()) // Return 500 status with error message
+		}
+
+		// Record the file in the catalog; drop the object if that fails so no untracked data remains
+		fileInfo := models.FileInfo{
+			Id:            randomFileName,
+			OwnerId:       userJwtInfo.Uid,
+			OriginalName:  file.Filename,
+			Size:          fileSize,
+			MimeType:      contentType,
+			Category:      rule.Category,
+			Checksum:      hex.EncodeToString(hasher.Sum(nil)),
+			CreatedAt:     time.Now().Unix(),
+			PreviewStatus: previewStatusFor(rule),
+			ScanStatus:    scanPending,
+			ObjectName:    objectName,
 		}
+		// The dimensions and the BlurHash are known now, the previews follow in the background
+		describeImage(&fileInfo, imageData)
+		// Voice notes and other recordings get their duration and waveform
+		h.describeAudio(c, rule, &fileInfo, dataKey)
+		// Archives are listed and refused when they are zip bombs or hold denied file types
+		if status, err := h.inspectArchive(c, rule, &fileInfo, dataKey); err != nil {
+			if err := h.Storage.DeleteFile(h.Ctx, objectName); err != nil {
+				logs.Error(c, "Failed to remove rejected file", err)
+			}
+			h.releaseStorage(userJwtInfo.Uid, fileSize)
+			return c.Status(status).SendString(err.Error())
+		}
+		// Identical content is stored once; the file references the blob of its checksum
+		if err := h.promoteBlob(c, &fileInfo, keyId, wrappedKey); err != nil {
+			logs.Error(c, "Failed to store file content", err)
+			if err := h.Storage.DeleteFile(h.Ctx, objectName); err != nil {
+				logs.Error(c, "Failed to remove untracked file", err)
+			}
+			h.releaseStorage(userJwtInfo.Uid, fileSize)
+			return c.Status(500).SendString(err.Error())
+		}
+		if err := h.Database.CreateFile(fileInfo); err != nil {
+			logs.Error(c, "Failed to record file metadata", err)
+			if err := h.Database.ReleaseBlob(fileInfo.Checksum); err != nil {
+				logs.Error(c, "Failed to release file content", err)
+			}
+			h.releaseStorage(userJwtInfo.Uid, fileSize)
+			return c.Status(500).SendString(err.Error())
+		}
+		h.scheduleScan(c, &fileInfo)
+		h.scheduleDocument(c, rule, fileInfo)
 
-		// Append file information (name and URL) to the slice
-		filesInfo = append(filesInfo, models.FileSender{
-			FileName: randomFileName,
-			FileUrl:  presignedURL,
-		})
+		// No download URL is handed out: the file is quarantined until its malware scan is clean
+		filesInfo = append(filesInfo, fileSender(fileInfo))
 	}
 
 	// Log successful upload with the count of files
-	helper.LogInfo(c, "Files uploaded successfully", map[string]interface{}{
+	logs.Info(c, "Files uploaded successfully", map[string]interface{}{
 		"filesCount": len(filesInfo), // Include the count of uploaded files in the log
 	})
 
 	// Return a response with a 200 status and the information of uploaded files
 	return response.HandleInformation(c, 200, filesInfo)
 }
+
+// requestBody returns the body of a request as a stream. The app streams bodies larger than its
+// BodyLimit instead of refusing them, so large uploads are read from here rather than with Body.
+func requestBody(c *fiber.Ctx) io.Reader {
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.Body())
+}
+
+// maxDiscardedBody is how much of a request body left unread by a handler is skipped to keep the
+// connection open
+const maxDiscardedBody = 256 * 1024
+
+// DiscardUnreadBody runs around every handler. As bodies are streamed, the part of a body a handler
+// did not read, for example after refusing the request, would be parsed as the next request on the
+// connection: a small remainder is skipped, otherwise the connection is closed after the response.
+func DiscardUnreadBody(c *fiber.Ctx) error {
+	err := c.Next()
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		if _, discardErr := io.CopyN(io.Discard, stream, maxDiscardedBody); discardErr != io.EOF {
+			c.Context().SetConnectionClose()
+		}
+	}
+	return err
+}
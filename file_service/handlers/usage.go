@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// parseQuotaAdmins reads the comma separated user IDs allowed to change quotas
+func parseQuotaAdmins(value string) map[string]bool {
+	admins := map[string]bool{}
+	for _, uid := range strings.Split(value, ",") {
+		if uid = strings.TrimSpace(uid); uid != "" {
+			admins[uid] = true
+		}
+	}
+	return admins
+}
+
+// reserveStorage counts size bytes against the quota of a user before they are stored.
+// On failure it returns the status code and message to answer with.
+func (h *Handler) reserveStorage(c *fiber.Ctx, userId string, size int64) (int, string) {
+	reserved, err := h.Database.ReserveStorage(userId, size, h.DefaultQuota)
+	if err != nil {
+		logs.Error(c, "Failed to reserve storage", err)
+		return 500, "failed to check storage quota"
+	}
+	if reserved {
+		return 0, ""
+	}
+
+	usage, err := h.Database.GetStorageUsage(userId, h.DefaultQuota)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve storage usage", err)
+	}
+	logs.Info(c, "Storage quota exceeded", map[string]interface{}{"uid": userId, "size": size})
+	return 413, fmt.Sprintf("storage quota exceeded: %d of %d bytes used, %d more requested", usage.UsedBytes, usage.QuotaBytes, size)
+}
+
+// releaseStorage gives back size bytes of the quota of a user, after a delete or a failed upload
+func (h *Handler) releaseStorage(userId string, size int64) {
+	if size <= 0 {
+		return
+	}
+	if err := h.Database.ReleaseStorage(userId, size); err != nil {
+		log.Printf("[ERROR] Failed to release %d bytes of storage of %s: %v", size, userId, err)
+	}
+}
+
+// GetUsage returns the storage used by the authenticated user and their quota
+func (h *Handler) GetUsage(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	usage, err := h.Database.GetStorageUsage(userJwtInfo.Uid, h.DefaultQuota)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve storage usage", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	return response.HandleInformation(c, 200, usage)
+}
+
+// SetUserQuota lets a quota admin override the quota or the group of a user
+func (h *Handler) SetUserQuota(c *fiber.Ctx) error {
+	if status := h.requireQuotaAdmin(c); status != 0 {
+		return c.SendStatus(status)
+	}
+
+	var input models.QuotaInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).SendString("invalid request body")
+	}
+	userId := c.Params("user_id")
+
+	if input.QuotaBytes != nil {
+		quota := input.QuotaBytes
+		if *quota < 0 {
+			quota = nil
+		}
+		if err := h.Database.SetUserQuota(userId, quota); err != nil {
+			logs.Error(c, "Failed to set user quota", err)
+			return c.Status(500).SendString(err.Error())
+		}
+	}
+	if input.Group != nil {
+		if err := h.Database.SetUserGroup(userId, *input.Group); err != nil {
+			logs.Error(c, "Failed to set user quota group", err)
+			return c.Status(500).SendString(err.Error())
+		}
+	}
+
+	usage, err := h.Database.GetStorageUsage(userId, h.DefaultQuota)
+	if err != nil {
+		logs.Error(c, "Failed to retrieve storage usage", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	logs.Info(c, "User quota changed", map[string]interface{}{"uid": userId, "quota": usage.QuotaBytes})
+	return response.HandleInformation(c, 200, usage)
+}
+
+// SetGroupQuota lets a quota admin set the quota of each member of a group
+func (h *Handler) SetGroupQuota(c *fiber.Ctx) error {
+	if status := h.requireQuotaAdmin(c); status != 0 {
+		return c.SendStatus(status)
+	}
+
+	var input models.QuotaInput
+	if err := c.BodyParser(&input); err != nil || input.QuotaBytes == nil || *input.QuotaBytes < 0 {
+		return c.Status(400).SendString("quota_bytes is required")
+	}
+	if err := h.Database.SetGroupQuota(c.Params("name"), *input.QuotaBytes); err != nil {
+		logs.Error(c, "Failed to set group quota", err)
+		return c.Status(500).SendString(err.Error())
+	}
+	logs.Info(c, "Group quota changed", map[string]interface{}{"group": c.Params("name"), "quota": *input.QuotaBytes})
+	return c.SendStatus(200)
+}
+
+// requireQuotaAdmin returns 403 unless the caller is listed in QUOTA_ADMINS
+func (h *Handler) requireQuotaAdmin(c *fiber.Ctx) int {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+	if !h.QuotaAdmins[userJwtInfo.Uid] {
+		return 403
+	}
+	return 0
+}
+
+// parseQuota reads a quota in bytes from the environment
+func parseQuota(value string) int64 {
+	quota, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || quota < 0 {
+		panic("invalid DEFAULT_USER_QUOTA: " + value)
+	}
+	return quota
+}
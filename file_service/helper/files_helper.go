@@ -20,45 +20,11 @@
 package helper
 
 import (
-	"errors"
-	"mime/multipart"
 	"path/filepath"
-
-	"urulink.com/file_service/models" // Importing the models package for FileTypeConfig
-)
-
-// Define constants for maximum file sizes
-const (
-	ImageLimit = 20 * 1024 * 1024  // Maximum limit for image files (20 MB)
-	VideoLimit = 100 * 1024 * 1024 // Maximum limit for video files (100 MB)
-	FileLimit  = 50 * 1024 * 1024  // Maximum limit for general files (50 MB)
+	"strings"
 )
 
-// Mapping of file extensions to their configurations, including maximum file sizes
-var fileTypeMap = map[string]models.FileTypeConfig{
-	".jpeg": {MaxSize: ImageLimit}, // JPEG image configuration
-	".jpg":  {MaxSize: ImageLimit}, // JPG image configuration
-	".png":  {MaxSize: ImageLimit}, // PNG image configuration
-	".mp4":  {MaxSize: VideoLimit}, // MP4 video configuration
-	".pdf":  {MaxSize: FileLimit},  // PDF file configuration
-	".txt":  {MaxSize: FileLimit},  // TXT file configuration
-	".zip":  {MaxSize: FileLimit},  // ZIP file configuration
-}
-
-// ValidateFile checks the file type and size for a given multipart file header
-func ValidateFile(fileHeader *multipart.FileHeader) error {
-	fileExt := filepath.Ext(fileHeader.Filename)  // Get the file extension
-	fileSize := fileHeader.Size                   // Get the file size
-	fileConfig, supported := fileTypeMap[fileExt] // Check if the file extension is supported
-
-	// Return an error if the file type is unsupported
-	if !supported {
-		return errors.New("file type is unsupported")
-	}
-
-	// Return an error if the file size exceeds the allowed limit
-	if fileSize > fileConfig.MaxSize {
-		return errors.New("file size exceeds the allowed limit")
-	}
-	return nil // Return nil if the file is valid
+// FileExt returns the lowercase extension of a file name, so ".JPG" is handled like ".jpg"
+func FileExt(fileName string) string {
+	return strings.ToLower(filepath.Ext(fileName))
 }
@@ -20,30 +20,30 @@
 package helper
 
 import (
-	"math/rand"
-	"time"
-)
+	"strings"
 
-// Define a constant string containing allowed characters for random string generation
-const (
-	number = "0123456789abcdefghijklmnobqsrwxyz" // Allowed characters (numbers and lowercase letters)
+	"urulink.com/platform/ids"
 )
 
-// generateRandomString generates a random string of specified length from the provided characters
-func generateRandomString(chars string, length int) string {
-	source := rand.NewSource(time.Now().UnixNano())
-	randomGenerator := rand.New(source)
+// fileNameLength is the length of generated file names
+const fileNameLength = 20
 
-	var result string // Initialize an empty string to hold the result
-	for i := 0; i < length; i++ {
-		index := randomGenerator.Intn(len(chars))
-		result += string(chars[index])
+// IsValidFileId reports whether id has the shape of a generated file name: 20 alphanumeric
+// characters optionally followed by an extension. It keeps arbitrary keys out of storage calls.
+func IsValidFileId(id string) bool {
+	name, ext, _ := strings.Cut(id, ".")
+	if !ids.Valid(name, ids.Alphanumeric, fileNameLength) {
+		return false
+	}
+	for _, r := range ext {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') {
+			return false
+		}
 	}
-	return result // Return the generated random string
+	return true
 }
 
 // GenerateFilesName generates a random file name of fixed length (20 characters)
 func GenerateFilesName() string {
-	const uidLength = 20                           // Define the length of the generated file name
-	return generateRandomString(number, uidLength) // Call the helper function to generate the file name
+	return ids.New(ids.Alphanumeric, fileNameLength)
 }
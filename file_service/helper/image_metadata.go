@@ -0,0 +1,284 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// jpegReencodeQuality is used when a JPEG must be re-encoded to apply its orientation
+const jpegReencodeQuality = 90
+
+// MaxImagePixels bounds the size of the images decoded to apply their orientation, so a small
+// file that declares huge dimensions cannot exhaust memory
+const MaxImagePixels = 50 * 1000 * 1000
+
+// ErrInvalidImage is returned when an image cannot be parsed while removing its metadata
+var ErrInvalidImage = errors.New("invalid image data")
+
+// ErrImageTooLarge is returned when an image to rotate has more than MaxImagePixels pixels
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+// HasStrippableMetadata reports whether StripImageMetadata supports a file type
+func HasStrippableMetadata(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png"
+}
+
+// StripImageMetadata removes EXIF, XMP, IPTC, comments and text chunks from a JPEG or PNG image,
+// so locations and device details are not shared along with a photo. Pixels are kept as they are,
+// except when the EXIF orientation is not the default one: the image is then rotated as the
+// orientation says and re-encoded, since the orientation tag itself is removed.
+func StripImageMetadata(data []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	}
+	return data, nil
+}
+
+// stripJPEG drops the metadata segments before the image data. JFIF (APP0), ICC profiles (APP2)
+// and Adobe color information (APP14) are kept because they affect how the image is displayed.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrInvalidImage
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	orientation := 1
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, ErrInvalidImage
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // Fill byte
+			pos++
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+		segment := data[pos:end]
+		payload := data[pos+4 : end]
+
+		// Start of scan: the entropy coded data and everything after it is copied unchanged
+		if marker == 0xDA {
+			out.Write(data[pos:])
+			break
+		}
+
+		switch {
+		case marker == 0xE1: // APP1: EXIF or XMP
+			if bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				orientation = exifOrientation(payload[6:])
+			}
+		case marker == 0xE2: // APP2: keep ICC profiles only
+			if bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")) {
+				out.Write(segment)
+			}
+		case marker == 0xE0, marker == 0xEE: // APP0 (JFIF) and APP14 (Adobe)
+			out.Write(segment)
+		case marker >= 0xE3 && marker <= 0xEF, marker == 0xFE: // Other application segments and comments
+		default: // Tables and frame headers
+			out.Write(segment)
+		}
+		pos = end
+	}
+
+	if orientation == 1 {
+		return out.Bytes(), nil
+	}
+
+	if err := checkPixels(jpeg.DecodeConfig(bytes.NewReader(out.Bytes()))); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, applyOrientation(img, orientation), &jpeg.Options{Quality: jpegReencodeQuality}); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// pngKeptChunks are the PNG chunks needed to display an image; every other chunk, including
+// text, time and EXIF chunks, is dropped
+var pngKeptChunks = map[string]bool{
+	"IHDR": true, "PLTE": true, "IDAT": true, "IEND": true, "tRNS": true,
+	"gAMA": true, "cHRM": true, "sRGB": true, "iCCP": true, "sBIT": true, "pHYs": true,
+}
+
+// stripPNG keeps only the chunks listed in pngKeptChunks
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngMagic) {
+		return nil, ErrInvalidImage
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngMagic)
+	orientation := 1
+	pos := len(pngMagic)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if end > len(data) {
+			return nil, ErrInvalidImage
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if crc32.ChecksumIEEE(data[pos+4:end-4]) != binary.BigEndian.Uint32(data[end-4:]) {
+			return nil, ErrInvalidImage
+		}
+
+		if chunkType == "eXIf" {
+			orientation = exifOrientation(data[pos+8 : end-4])
+		}
+		if pngKeptChunks[chunkType] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	if orientation == 1 {
+		return out.Bytes(), nil
+	}
+
+	if err := checkPixels(png.DecodeConfig(bytes.NewReader(out.Bytes()))); err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, applyOrientation(img, orientation)); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// checkPixels rejects an image whose header could not be read or declares more than MaxImagePixels
+// pixels, before the image is decoded
+func checkPixels(config image.Config, err error) error {
+	if err != nil {
+		return ErrInvalidImage
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// exifOrientation reads the Orientation tag (0x0112) of IFD0 from a TIFF structured EXIF block.
+// It returns 1, the default orientation, when the tag is missing or the block is malformed.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation transforms an image so it displays upright without its EXIF orientation tag
+func applyOrientation(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			// Position in the stored image of the pixel displayed at x, y
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				sx, sy = width-1-x, y
+			case 3: // Rotated 180°
+				sx, sy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				sx, sy = x, height-1-y
+			case 5: // Mirrored along the top-left diagonal
+				sx, sy = y, x
+			case 6: // Rotated 90° clockwise
+				sx, sy = y, height-1-x
+			case 7: // Mirrored along the top-right diagonal
+				sx, sy = width-1-y, height-1-x
+			case 8: // Rotated 90° counter-clockwise
+				sx, sy = width-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// metadataMarkers are written into the test images as EXIF make, XMP city, IPTC caption, comment
+// and EXIF GPS latitude
+var metadataMarkers = []string{"Exif\x00\x00", "UruTestCam", "Baghdad", "IPTC", "secret comment", "II*\x00"}
+
+func readTestImage(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestStripImageMetadataRemovesMetadata(t *testing.T) {
+	tests := []struct {
+		file     string
+		mimeType string
+		markers  []string
+	}{
+		{"exif_gps.jpg", "image/jpeg", metadataMarkers},
+		{"orientation_6.jpg", "image/jpeg", metadataMarkers},
+		{"orientation_8.jpg", "image/jpeg", metadataMarkers},
+		{"text_chunks.png", "image/png", []string{"UruTestCam", "Baghdad", "secret comment", "II*\x00", "eXIf", "tEXt", "iTXt", "tIME"}},
+		{"orientation_3.png", "image/png", []string{"UruTestCam", "eXIf", "tEXt"}},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			data := readTestImage(t, test.file)
+			stripped, err := StripImageMetadata(data, test.mimeType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, marker := range test.markers {
+				if !bytes.Contains(data, []byte(marker)) {
+					t.Fatalf("test image does not contain %q", marker)
+				}
+				if bytes.Contains(stripped, []byte(marker)) {
+					t.Errorf("stripped image still contains %q", marker)
+				}
+			}
+			if _, _, err := image.Decode(bytes.NewReader(stripped)); err != nil {
+				t.Fatalf("stripped image does not decode: %v", err)
+			}
+		})
+	}
+}
+
+func TestStripImageMetadataKeepsPixels(t *testing.T) {
+	data := readTestImage(t, "exif_gps.jpg")
+	stripped, err := StripImageMetadata(data, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(stripped, []byte("JFIF")) {
+		t.Error("JFIF segment was removed")
+	}
+
+	// Without a rotation the entropy coded data is copied, so both decode to the same pixels
+	original, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original.Bounds() != result.Bounds() {
+		t.Fatalf("bounds changed from %v to %v", original.Bounds(), result.Bounds())
+	}
+	for y := 0; y < original.Bounds().Dy(); y++ {
+		for x := 0; x < original.Bounds().Dx(); x++ {
+			if original.At(x, y) != result.At(x, y) {
+				t.Fatalf("pixel %d,%d changed", x, y)
+			}
+		}
+	}
+}
+
+// isRed and isBlue tolerate the JPEG compression of the test images
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xC000 && g < 0x4000 && b < 0x4000
+}
+
+func isBlue(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r < 0x4000 && g < 0x4000 && b > 0xC000
+}
+
+func TestStripImageMetadataAppliesJPEGOrientation(t *testing.T) {
+	// The stored images are 32x16, red on the left half and blue on the right half
+	tests := []struct {
+		file        string
+		top, bottom func(color.Color) bool
+	}{
+		{"orientation_6.jpg", isRed, isBlue}, // Rotated 90° clockwise: the left half ends up on top
+		{"orientation_8.jpg", isBlue, isRed}, // Rotated 90° counter-clockwise: the right half ends up on top
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			stripped, err := StripImageMetadata(readTestImage(t, test.file), "image/jpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(stripped))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != 16 || img.Bounds().Dy() != 32 {
+				t.Fatalf("got %v, want a 16x32 image", img.Bounds())
+			}
+			if !test.top(img.At(8, 4)) || !test.bottom(img.At(8, 28)) {
+				t.Fatalf("unexpected colors %v on top and %v at the bottom", img.At(8, 4), img.At(8, 28))
+			}
+		})
+	}
+}
+
+func TestStripImageMetadataAppliesPNGOrientation(t *testing.T) {
+	// The stored images are 3x2 with a distinct color for each pixel
+	tests := []struct {
+		file          string
+		width, height int
+		source        func(x, y int) (int, int) // Stored pixel displayed at x, y
+	}{
+		{"orientation_3.png", 3, 2, func(x, y int) (int, int) { return 2 - x, 1 - y }},
+		{"orientation_6.png", 2, 3, func(x, y int) (int, int) { return y, 1 - x }},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			data := readTestImage(t, test.file)
+			original, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stripped, err := StripImageMetadata(data, "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(stripped))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != test.width || img.Bounds().Dy() != test.height {
+				t.Fatalf("got %v, want a %dx%d image", img.Bounds(), test.width, test.height)
+			}
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					sx, sy := test.source(x, y)
+					if color.NRGBAModel.Convert(img.At(x, y)) != color.NRGBAModel.Convert(original.At(sx, sy)) {
+						t.Errorf("pixel %d,%d is %v, want %v", x, y, img.At(x, y), original.At(sx, sy))
+					}
+				}
+			}
+		})
+	}
+}
+
+// rotatedExif is an EXIF block holding only the orientation 6 tag
+var rotatedExif = []byte("II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00")
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(append(chunk, chunkType...), data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripImageMetadataRejectsHugeImages(t *testing.T) {
+	// Both images declare 60000x60000 pixels without carrying any pixel data
+	ihdr := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 60000), 60000)
+	ihdr = append(ihdr, 8, 2, 0, 0, 0)
+	pngData := append(append([]byte{}, pngMagic...), pngChunk("IHDR", ihdr)...)
+	pngData = append(pngData, pngChunk("eXIf", rotatedExif)...)
+	pngData = append(pngData, pngChunk("IEND", nil)...)
+
+	jpegData := []byte{0xFF, 0xD8}
+	jpegData = append(jpegData, 0xFF, 0xE1, 0x00, byte(2+6+len(rotatedExif)))
+	jpegData = append(append(jpegData, "Exif\x00\x00"...), rotatedExif...)
+	jpegData = append(jpegData, 0xFF, 0xC0, 0x00, 0x0B, 0x08, 0xEA, 0x60, 0xEA, 0x60, 0x01, 0x01, 0x11, 0x00)
+	jpegData = append(jpegData, 0xFF, 0xDA, 0x00, 0x08, 0x01, 0x01, 0x00, 0x00, 0x3F, 0x00, 0xFF, 0xD9)
+
+	for mimeType, data := range map[string][]byte{"image/png": pngData, "image/jpeg": jpegData} {
+		if _, err := StripImageMetadata(data, mimeType); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("%s: got %v, want ErrImageTooLarge", mimeType, err)
+		}
+	}
+}
+
+func TestStripImageMetadataRejectsInvalidImages(t *testing.T) {
+	jpegData := readTestImage(t, "exif_gps.jpg")
+	pngData := readTestImage(t, "text_chunks.png")
+	corruptPng := append([]byte{}, pngData...)
+	corruptPng[len(pngMagic)+10] ^= 0xFF // Breaks the IHDR checksum
+
+	tests := map[string]struct {
+		data     []byte
+		mimeType string
+	}{
+		"not a jpeg":     {pngData, "image/jpeg"},
+		"truncated jpeg": {jpegData[:40], "image/jpeg"},
+		"not a png":      {jpegData, "image/png"},
+		"truncated png":  {pngData[:30], "image/png"},
+		"png checksum":   {corruptPng, "image/png"},
+	}
+	for name, test := range tests {
+		if _, err := StripImageMetadata(test.data, test.mimeType); !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("%s: got %v, want ErrInvalidImage", name, err)
+		}
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	if got := exifOrientation(rotatedExif); got != 6 {
+		t.Errorf("exifOrientation() = %d, want 6", got)
+	}
+	bigEndian := []byte("MM\x00*\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x03\x00\x00\x00\x00\x00\x00")
+	if got := exifOrientation(bigEndian); got != 3 {
+		t.Errorf("exifOrientation() of big endian block = %d, want 3", got)
+	}
+	for _, invalid := range [][]byte{nil, []byte("XX*\x00\x08\x00\x00\x00"), rotatedExif[:12]} {
+		if got := exifOrientation(invalid); got != 1 {
+			t.Errorf("exifOrientation(%q) = %d, want 1", invalid, got)
+		}
+	}
+}
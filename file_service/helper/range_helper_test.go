@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import "testing"
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		size    int64
+		start   int64
+		end     int64
+		partial bool
+		err     error
+	}{
+		{"no header", "", 10, 0, 9, false, nil},
+		{"start and end", "bytes=2-5", 10, 2, 5, true, nil},
+		{"single byte", "bytes=0-0", 10, 0, 0, true, nil},
+		{"spaces around the range", "bytes= 2-5 ", 10, 2, 5, true, nil},
+		{"end past the file", "bytes=2-100", 10, 2, 9, true, nil},
+		{"open ended", "bytes=4-", 10, 4, 9, true, nil},
+		{"open ended from the last byte", "bytes=9-", 10, 9, 9, true, nil},
+		{"suffix", "bytes=-3", 10, 7, 9, true, nil},
+		{"suffix of the whole file", "bytes=-10", 10, 0, 9, true, nil},
+		{"suffix longer than the file", "bytes=-100", 10, 0, 9, true, nil},
+		{"start at the size", "bytes=10-", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"start past the size", "bytes=20-30", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"range of an empty file", "bytes=0-", 0, 0, 0, false, ErrRangeNotSatisfiable},
+		{"suffix of an empty file", "bytes=-5", 0, 0, 0, false, ErrRangeNotSatisfiable},
+		{"empty suffix", "bytes=-0", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"end before start", "bytes=5-2", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"missing dash", "bytes=5", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"letters", "bytes=a-b", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"negative start", "bytes=--5", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"empty range", "bytes=-", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"two dashes", "bytes=1-2-3", 10, 0, 0, false, ErrRangeNotSatisfiable},
+		{"other unit", "items=0-5", 10, 0, 9, false, nil},
+		{"several ranges", "bytes=0-1,4-5", 10, 0, 9, false, nil},
+		{"several suffixes", "bytes=-1,-2", 10, 0, 9, false, nil},
+	}
+	for _, tt := range tests {
+		start, end, partial, err := ParseRange(tt.header, tt.size)
+		if err != tt.err {
+			t.Errorf("%s: ParseRange(%q, %d) error = %v, want %v", tt.name, tt.header, tt.size, err, tt.err)
+			continue
+		}
+		if err == nil && (start != tt.start || end != tt.end || partial != tt.partial) {
+			t.Errorf("%s: ParseRange(%q, %d) = %d-%d partial %v, want %d-%d partial %v",
+				tt.name, tt.header, tt.size, start, end, partial, tt.start, tt.end, tt.partial)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// SniffLength is the number of leading bytes SniffContentType looks at
+const SniffLength = 512
+
+// Magic numbers of the supported binary formats
+var (
+	jpegMagic     = []byte{0xFF, 0xD8, 0xFF}
+	pngMagic      = []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A}
+	pdfMagic      = []byte("%PDF-")
+	zipMagic      = []byte("PK\x03\x04")
+	emptyZipMagic = []byte("PK\x05\x06")
+	mp4Magic      = []byte("ftyp") // ISO base media file: box size (4 bytes) followed by "ftyp"
+	gif87Magic    = []byte("GIF87a")
+	gif89Magic    = []byte("GIF89a")
+	riffMagic     = []byte("RIFF") // RIFF container: size (4 bytes) followed by the form type
+	webpForm      = []byte("WEBP")
+	waveForm      = []byte("WAVE")
+	id3Magic      = []byte("ID3") // MP3 starting with an ID3v2 tag
+	oggMagic      = []byte("OggS")
+	utf16LEMark   = []byte{0xFF, 0xFE} // Byte order marks of UTF-16 text
+	utf16BEMark   = []byte{0xFE, 0xFF}
+)
+
+// Brands of ISO base media files holding audio only (M4A, audio books and protected M4A)
+var audioBrands = []string{"M4A ", "M4B ", "M4P "}
+
+// SniffedTypes lists the MIME types SniffContentType can detect, so a file type policy can only
+// require types that are actually recognized
+var SniffedTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "application/zip",
+	"video/mp4", "audio/mpeg", "audio/ogg", "audio/wav", "audio/aac", "audio/mp4", "text/plain",
+}
+
+// SniffContentType detects the real type of a file from its first bytes, independent of its name.
+// It only recognizes the formats accepted for upload and returns application/octet-stream otherwise.
+func SniffContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, jpegMagic):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, pngMagic):
+		return "image/png"
+	case bytes.HasPrefix(head, pdfMagic):
+		return "application/pdf"
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, emptyZipMagic):
+		return "application/zip"
+	case bytes.HasPrefix(head, gif87Magic), bytes.HasPrefix(head, gif89Magic):
+		return "image/gif"
+	case bytes.HasPrefix(head, riffMagic) && len(head) >= 12 && bytes.Equal(head[8:12], webpForm):
+		return "image/webp"
+	case bytes.HasPrefix(head, riffMagic) && len(head) >= 12 && bytes.Equal(head[8:12], waveForm):
+		return "audio/wav"
+	case len(head) >= 12 && bytes.Equal(head[4:8], mp4Magic) && hasAudioBrand(head):
+		return "audio/mp4"
+	case len(head) >= 8 && bytes.Equal(head[4:8], mp4Magic):
+		return "video/mp4"
+	case bytes.HasPrefix(head, oggMagic):
+		return "audio/ogg"
+	case isADTSFrame(head):
+		return "audio/aac"
+	case bytes.HasPrefix(head, id3Magic), isMP3Frame(head):
+		return "audio/mpeg"
+	case isPlainText(head):
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+// isMP3Frame reports whether data starts with the header of an MPEG audio layer III frame:
+// 11 sync bits followed by the version and the layer bits 01
+func isMP3Frame(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xE6 == 0xE2
+}
+
+// hasAudioBrand reports whether the ftyp box at the start of head names an audio-only brand,
+// either as major brand or among the compatible brands
+func hasAudioBrand(head []byte) bool {
+	end := min(int(binary.BigEndian.Uint32(head[:4])), len(head))
+	if slices.Contains(audioBrands, string(head[8:12])) {
+		return true
+	}
+	// The minor version (4 bytes) separates the major brand from the compatible brands
+	for offset := 16; offset+4 <= end; offset += 4 {
+		if slices.Contains(audioBrands, string(head[offset:offset+4])) {
+			return true
+		}
+	}
+	return false
+}
+
+// isADTSFrame reports whether data starts with the header of an AAC frame in an ADTS stream:
+// 12 sync bits followed by the version bit and the layer bits 00
+func isADTSFrame(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xF6 == 0xF0
+}
+
+// isPlainText reports whether data is text without control characters other than whitespace:
+// UTF-8, or UTF-16 starting with a byte order mark. A character cut off at the end of the sniffed
+// window is tolerated.
+func isPlainText(data []byte) bool {
+	if bytes.HasPrefix(data, utf16LEMark) || bytes.HasPrefix(data, utf16BEMark) {
+		return isUTF16Text(data)
+	}
+	for cut := 1; cut < utf8.UTFMax && cut <= len(data); cut++ {
+		if utf8.RuneStart(data[len(data)-cut]) {
+			if !utf8.FullRune(data[len(data)-cut:]) {
+				data = data[:len(data)-cut]
+			}
+			break
+		}
+	}
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if isControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUTF16Text reports whether data, starting with a byte order mark, is UTF-16 text without
+// control characters other than whitespace
+func isUTF16Text(data []byte) bool {
+	order := binary.ByteOrder(binary.LittleEndian)
+	if bytes.HasPrefix(data, utf16BEMark) {
+		order = binary.BigEndian
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for offset := 2; offset+2 <= len(data); offset += 2 {
+		units = append(units, order.Uint16(data[offset:]))
+	}
+	for _, r := range utf16.Decode(units) {
+		if isControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isControl reports whether r is a control character other than whitespace
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' || r == 0x7F
+}
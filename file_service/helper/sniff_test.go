@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSniffContentType(t *testing.T) {
+	// A window of UTF-8 text cut off inside a 3 byte character
+	cutText := append(bytes.Repeat([]byte("a"), SniffLength-1), "€"[:1]...)
+
+	for _, test := range []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 0x10, 'J', 'F', 'I', 'F'}, "image/jpeg"},
+		{"png", readTestImage(t, "text_chunks.png"), "image/png"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), "audio/wav"},
+		{"riff of another form", []byte("RIFF\x24\x00\x00\x00AVI LIST"), "application/octet-stream"},
+		{"pdf", []byte("%PDF-1.7\n%\xE2\xE3\xCF\xD3"), "application/pdf"},
+		{"zip", []byte("PK\x03\x04\x14\x00"), "application/zip"},
+		{"empty zip", []byte("PK\x05\x06\x00\x00"), "application/zip"},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"), "video/mp4"},
+		{"m4a", []byte("\x00\x00\x00\x1CftypM4A \x00\x00\x00\x00M4A isom"), "audio/mp4"},
+		{"mp4 with an audio brand", []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00M4B mp42"), "audio/mp4"},
+		{"ogg", []byte("OggS\x00\x02"), "audio/ogg"},
+		{"aac", []byte{0xFF, 0xF1, 0x50, 0x80}, "audio/aac"},
+		{"mp3 with id3", []byte("ID3\x04\x00\x00"), "audio/mpeg"},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x64}, "audio/mpeg"},
+		{"executable", []byte("MZ\x90\x00\x03\x00\x00\x00"), "application/octet-stream"},
+		{"elf", []byte("\x7FELF\x02\x01\x01"), "application/octet-stream"},
+		{"binary", []byte{0x00, 0x01, 0x02, 0x03, 0xFE, 0xFD}, "application/octet-stream"},
+		{"empty", nil, "text/plain"},
+		{"ascii", []byte("meeting notes\r\n\t- agenda\n"), "text/plain"},
+		{"utf-8", []byte("مرحبا بالعالم, héllo, 你好 👋\n"), "text/plain"},
+		{"utf-8 with byte order mark", []byte("\xEF\xBB\xBFnotes"), "text/plain"},
+		{"utf-8 cut off at the end of the window", cutText, "text/plain"},
+		{"utf-16le", []byte("\xFF\xFEh\x00i\x00\n\x00"), "text/plain"},
+		{"utf-16be", []byte("\xFE\xFF\x00h\x00i"), "text/plain"},
+		{"utf-16 with control characters", []byte("\xFF\xFEh\x00\x01\x00"), "application/octet-stream"},
+		{"windows-1252", []byte("caf\xE9 cr\xE8me"), "application/octet-stream"},
+		{"invalid utf-8 in the window", []byte("text \xC3\x28 more text"), "application/octet-stream"},
+		{"high bytes", bytes.Repeat([]byte{0x80, 0xA0, 0xFF}, 20), "application/octet-stream"},
+		{"text with a nul byte", []byte("text\x00text"), "application/octet-stream"},
+		{"text with escape codes", []byte("\x1B[31mred\x1B[0m"), "application/octet-stream"},
+		{"delete character", []byte(strings.Repeat("x", 10) + "\x7F"), "application/octet-stream"},
+	} {
+		if got := SniffContentType(test.head); got != test.want {
+			t.Errorf("%s: SniffContentType = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
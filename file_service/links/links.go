@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package links
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxCachedLinks bounds the number of issued links kept for reuse
+const maxCachedLinks = 100000
+
+// Link is a signed path serving a file, or one of its previews, until ExpiresAt (unix time)
+type Link struct {
+	Path      string
+	ExpiresAt int64
+}
+
+// Signer issues and verifies the short-lived links under which the file service serves files
+// without an Authorization header, e.g. to <img> and <video> elements. A link is handed out again
+// until a quarter of its lifetime is left, so clients and browsers can cache what it serves.
+type Signer struct {
+	key    []byte
+	expiry time.Duration
+
+	mu     sync.Mutex
+	issued map[string]Link // Links by file ID and variant
+}
+
+// NewSigner returns a Signer issuing links valid for expiry
+func NewSigner(key []byte, expiry time.Duration) *Signer {
+	return &Signer{key: key, expiry: expiry, issued: map[string]Link{}}
+}
+
+// Link returns a link to a file, or to one of its preview variants when variant is not empty
+func (s *Signer) Link(fileId, variant string, now time.Time) Link {
+	cacheKey := fileId + "/" + variant
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link, ok := s.issued[cacheKey]; ok && now.Add(s.expiry/4).Unix() < link.ExpiresAt {
+		return link
+	}
+
+	expires := now.Add(s.expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.signature(fileId, variant, expires))
+	link := Link{Path: linkPath(fileId, variant) + "?" + query.Encode(), ExpiresAt: expires}
+
+	if len(s.issued) >= maxCachedLinks {
+		s.prune(now)
+	}
+	s.issued[cacheKey] = link
+	return link
+}
+
+// Verify checks the expires and signature query parameters of a request to a link
+func (s *Signer) Verify(fileId, variant, expires, signature string, now time.Time) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return false
+	}
+	expected := s.signature(fileId, variant, expiresAt)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// signature is the hex encoded HMAC-SHA256 of a link
+func (s *Signer) signature(fileId, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s\n%s\n%d", fileId, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// prune drops the links that would not be handed out anymore, or all of them when they are all
+// still fresh
+func (s *Signer) prune(now time.Time) {
+	for cacheKey, link := range s.issued {
+		if now.Add(s.expiry/4).Unix() >= link.ExpiresAt {
+			delete(s.issued, cacheKey)
+		}
+	}
+	if len(s.issued) >= maxCachedLinks {
+		clear(s.issued)
+	}
+}
+
+// linkPath is the path of the link to a file or to one of its preview variants
+func linkPath(fileId, variant string) string {
+	if variant == "" {
+		return "/links/" + fileId
+	}
+	return "/links/" + fileId + "/preview/" + variant
+}
@@ -20,14 +20,57 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"log"
+	"os"
 
 	"github.com/gofiber/fiber/v2"
+	"urulink.com/file_service/handlers"
 	"urulink.com/file_service/routes"
+	"urulink.com/platform/logs"
 )
 
 func main() {
-	app := fiber.New()
+	logs.Setup("service.log")
+
+	// "rewrap" re-wraps all data keys with MASTER_KEY_ID after a master key rotation, then exits
+	if len(os.Args) > 1 && os.Args[1] == "rewrap" {
+		handler := handlers.Init()
+		count, err := handler.RewrapDataKeys()
+		if err != nil {
+			log.Fatalf("Re-wrapping data keys failed after %d keys: %v", count, err)
+		}
+		log.Printf("Re-wrapped %d data keys with master key %s", count, handler.Keys.CurrentId)
+		return
+	}
+
+	// "gc" runs one garbage collection and prints its report; "gc -dry-run" only reports
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		flags := flag.NewFlagSet("gc", flag.ExitOnError)
+		dryRun := flags.Bool("dry-run", false, "report what would be removed without removing it")
+		flags.Parse(os.Args[2:])
+
+		handler := handlers.Init()
+		report := handler.CollectGarbage(*dryRun)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		if len(report.Errors) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Request bodies larger than the BodyLimit are streamed to the handlers instead of being
+	// refused, and multipart forms are only read once a handler asks for them, after the
+	// caller was authenticated
+	app := fiber.New(fiber.Config{
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
 	routes.SetRoutes(app)
 	log.Fatal(app.Listen(":8082"))
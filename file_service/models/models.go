@@ -19,17 +19,197 @@
 
 package models
 
-type ClientsLoginResponse struct {
-	Uid      string `json:"uid"`
-	Username string `json:"username"`
-}
+import (
+	"urulink.com/platform/auth"
+)
+
+// ClientsLoginResponse is the user an access token belongs to, as reported by the auth service
+type ClientsLoginResponse = auth.User
 
 type FileSender struct {
-	FileName string `json:"file_name"`
-	FileUrl  string `json:"file_url"`
+	FileName      string            `json:"file_name"`
+	FileUrl       string            `json:"file_url,omitempty"`
+	ScanStatus    string            `json:"scan_status"`
+	Width         int               `json:"width,omitempty"`
+	Height        int               `json:"height,omitempty"`
+	BlurHash      string            `json:"blur_hash,omitempty"`
+	PreviewStatus string            `json:"preview_status,omitempty"`
+	Previews      map[string]string `json:"previews,omitempty"` // Variant name to download path
+	DurationMs    int64             `json:"duration_ms,omitempty"`
+	Waveform      []byte            `json:"waveform,omitempty"`
+	Archive       *ArchiveListing   `json:"archive,omitempty"`
+	Snippet       string            `json:"snippet,omitempty"`
+	Encoding      string            `json:"encoding,omitempty"`
+	PageCount     int               `json:"page_count,omitempty"`
+	Title         string            `json:"title,omitempty"`
+}
+
+// ArchiveListing lists the contents of a zip archive
+type ArchiveListing struct {
+	Entries    []ArchiveEntry `json:"entries"`             // Files of the archive, without directories
+	EntryCount int            `json:"entry_count"`         // Files and directories in the archive
+	TotalSize  int64          `json:"total_size"`          // Uncompressed size of the files
+	Truncated  bool           `json:"truncated,omitempty"` // Whether files were left out of Entries
+}
+
+// ArchiveEntry is a file inside a zip archive
+type ArchiveEntry struct {
+	Name           string  `json:"name"` // Path inside the archive
+	Size           int64   `json:"size"`
+	CompressedSize int64   `json:"compressed_size"`
+	Ratio          float64 `json:"ratio"` // Size divided by CompressedSize
+	Encrypted      bool    `json:"encrypted,omitempty"`
+}
+
+// FileInfo is the catalog entry of an uploaded file. Files with the same content share the
+// storage object named by ObjectName.
+type FileInfo struct {
+	Id           string `json:"id"`
+	OwnerId      string `json:"owner_id"`
+	OriginalName string `json:"original_name"`
+	Size         int64  `json:"size"`
+	MimeType     string `json:"mime_type"`
+	Checksum     string `json:"checksum"` // Hex encoded SHA-256 of the content
+	CreatedAt    int64  `json:"created_at"`
+	Category     string `json:"category,omitempty"` // Category of the file type policy, deciding the retention
+
+	// Images, described on upload; the preview variants are generated by the preview workers
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	BlurHash      string `json:"blur_hash,omitempty"`
+	PreviewStatus string `json:"preview_status,omitempty"` // "pending", "ready" or "failed"; empty for other files
+
+	// Audio playback, read from the file on upload
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Waveform   []byte `json:"waveform,omitempty"` // Peak amplitudes (0-255) of equal parts of the recording, base64 in JSON
+
+	// Text and PDF previews, read on upload
+	Snippet   string `json:"snippet,omitempty"`    // Beginning of a text file
+	Encoding  string `json:"encoding,omitempty"`   // Detected character encoding of a text file
+	PageCount int    `json:"page_count,omitempty"` // Pages of a PDF
+	Title     string `json:"title,omitempty"`      // Title of a PDF from its document information
+
+	// Contents of zip archives, read on upload
+	Archive *ArchiveListing `json:"archive,omitempty" gorm:"serializer:json"`
+
+	// Malware scan; only clean files can be downloaded
+	ScanStatus    string `json:"scan_status"`              // "pending", "clean", "infected" or "failed"
+	ScanSignature string `json:"scan_signature,omitempty"` // Malware found in infected files
+
+	ObjectName string `json:"-"` // Storage object holding the content; empty for files stored under their Id
+}
+
+// GCReport summarizes a garbage collection pass
+type GCReport struct {
+	DryRun         bool      `json:"dry_run"`
+	StartedAt      int64     `json:"started_at"`
+	FinishedAt     int64     `json:"finished_at"`
+	FilesChecked   int       `json:"files_checked"`
+	ObjectsChecked int       `json:"objects_checked"`
+	Removed        []GCEntry `json:"removed"` // What was removed, or would be in a dry run
+	RemovedBytes   int64     `json:"removed_bytes"`
+	Skipped        []string  `json:"skipped,omitempty"` // Checks that could not run with the current configuration
+	Errors         []string  `json:"errors,omitempty"`
+}
+
+// FileIdList is the request and response body of the reference check of the message service
+type FileIdList struct {
+	FileIds []string `json:"file_ids"`
+}
+
+// GCEntry is a file or storage object removed by the garbage collector
+type GCEntry struct {
+	Kind     string `json:"kind"`   // "file" or "object"
+	Name     string `json:"name"`   // File ID or object name
+	Reason   string `json:"reason"` // "unreferenced", "retention" or "orphaned"
+	Category string `json:"category,omitempty"`
+	Size     int64  `json:"size"`
+}
+
+// BlobKey is the data key encrypting a blob, wrapped by the master key KeyId
+type BlobKey struct {
+	Hash    string
+	KeyId   string
+	DataKey []byte
+}
+
+// UploadSession is the server-side state of a resumable (tus) upload.
+type UploadSession struct {
+	Id              string // tus upload ID, part of the upload URL
+	OwnerId         string
+	ObjectName      string // Name of the object being assembled, also the future file ID
+	OriginalName    string
+	MimeType        string
+	Length          int64  // Total size declared at creation
+	Offset          int64  // Number of bytes received so far
+	StorageUploadId string // Multipart upload ID in the storage backend
+	Parts           string // JSON encoded []UploadPart already uploaded to storage
+	Tail            []byte // Received bytes not yet uploaded because they are smaller than a part
+	HashState       []byte // Marshaled SHA-256 state of the bytes received so far
+	CreatedAt       int64
+	ExpiresAt       int64 // Abandoned uploads are aborted after this unix time
+	LockedUntil     int64 // Unix time until which a PATCH request holds the upload
+}
+
+// UploadPart identifies one uploaded part of a multipart upload.
+type UploadPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+// StorageUsage is the storage used by a user and their effective quota
+type StorageUsage struct {
+	UserId     string `json:"user_id"`
+	UsedBytes  int64  `json:"used_bytes"`
+	QuotaBytes int64  `json:"quota_bytes"`
+	GroupName  string `json:"group,omitempty"`
+}
+
+// QuotaInput changes the quota settings of a user or group. For users, a negative quota_bytes
+// removes their own quota so the group or default quota applies again.
+type QuotaInput struct {
+	QuotaBytes *int64  `json:"quota_bytes"`
+	Group      *string `json:"group"`
+}
+
+// PendingUpload is a direct-to-storage upload that was presigned but not yet completed.
+type PendingUpload struct {
+	Id           string // Object name the client uploads to, also the future file ID
+	OwnerId      string
+	OriginalName string
+	Size         int64
+	MimeType     string
+	ExpiresAt    int64 // The presigned request stops working at this unix time
+}
+
+// PresignUploadInput is the body of a direct upload request.
+type PresignUploadInput struct {
+	FileName    string `json:"file_name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	Method      string `json:"method"` // "put" (default) or "post"
+}
+
+// PresignedUpload tells the client how to send a file straight to storage.
+// A PUT must carry Headers; a POST is a multipart form with Fields followed by the file field.
+type PresignedUpload struct {
+	UploadId  string            `json:"upload_id"`
+	Method    string            `json:"method"`
+	Url       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt int64             `json:"expires_at"`
+}
+
+// FileLink holds the short-lived links serving a file and its previews without a login
+type FileLink struct {
+	Url       string            `json:"url"`
+	Previews  map[string]string `json:"previews,omitempty"` // Variant name to link
+	ExpiresAt int64             `json:"expires_at"`         // Unix time the first of the links expires
 }
 
-type FileTypeConfig struct {
-	Dir     string
-	MaxSize int64
+// FileLinks answers a batch of link requests, by file ID
+type FileLinks struct {
+	Links  map[string]FileLink `json:"links"`
+	Errors map[string]string   `json:"errors,omitempty"` // Why no links were issued for a file
 }
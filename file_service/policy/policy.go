@@ -0,0 +1,279 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"urulink.com/file_service/archive"
+	"urulink.com/file_service/helper"
+	"urulink.com/file_service/preview"
+)
+
+// Errors returned when a file does not satisfy the policy
+var (
+	ErrUnsupported     = errors.New("file type is unsupported")
+	ErrTooLarge        = errors.New("file size exceeds the allowed limit")
+	ErrContentMismatch = errors.New("file content does not match its type")
+)
+
+// Rule describes one accepted file type
+type Rule struct {
+	Extension   string `json:"extension"`              // Lowercase, with the leading dot
+	SniffedMime string `json:"sniffed_mime"`           // Type the content must be detected as
+	ContentType string `json:"content_type,omitempty"` // Type the file is served with; defaults to SniffedMime
+	Category    string `json:"category"`               // E.g. image, video, audio or file; role size limits apply per category
+	MaxSize     int64  `json:"max_size"`               // Largest accepted size in bytes
+	Previews    bool   `json:"previews"`               // Whether image previews are generated
+}
+
+// RoleOverride adjusts the policy for the users of one role
+type RoleOverride struct {
+	MaxSize map[string]int64 `json:"max_size,omitempty"` // Size limit by category
+	Allow   []Rule           `json:"allow,omitempty"`    // Additional types, or replacements of types by extension
+	Deny    []string         `json:"deny,omitempty"`     // Extensions refused to the role
+}
+
+// Policy is the file type policy: the types every user may upload and the overrides of each role
+type Policy struct {
+	Types []Rule                  `json:"types"`
+	Roles map[string]RoleOverride `json:"roles,omitempty"`
+
+	// Days the files of a category are kept before the garbage collector removes them; files of
+	// categories without retention are kept until they are deleted or never sent in a message
+	RetentionDays map[string]int `json:"retention_days,omitempty"`
+
+	// Limits of uploaded zip archives; the defaults apply to the limits left out
+	Archives archive.Limits `json:"archives"`
+
+	effective map[string]map[string]Rule // Rules by role ("" for users without a role) and extension
+}
+
+// Parse reads and checks a policy in JSON
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, err
+	}
+	if len(policy.Types) == 0 {
+		return nil, errors.New("policy has no file types")
+	}
+
+	base, err := ruleMap(policy.Types)
+	if err != nil {
+		return nil, err
+	}
+	for category, days := range policy.RetentionDays {
+		if days <= 0 {
+			return nil, fmt.Errorf("retention of category %s must be a positive number of days", category)
+		}
+	}
+	if err := checkArchiveLimits(&policy.Archives); err != nil {
+		return nil, err
+	}
+	policy.effective = map[string]map[string]Rule{"": base}
+	for role, override := range policy.Roles {
+		rules, err := override.apply(base)
+		if err != nil {
+			return nil, fmt.Errorf("role %s: %w", role, err)
+		}
+		policy.effective[role] = rules
+	}
+	return &policy, nil
+}
+
+// Default limits of zip archives
+const (
+	defaultArchiveEntries   = 10000
+	defaultArchiveTotalSize = 1 << 30 // 1 GiB
+	defaultArchiveRatio     = 100
+)
+
+// check fills in the default limits of zip archives and lowercases the denied extensions
+func checkArchiveLimits(l *archive.Limits) error {
+	if l.MaxEntries < 0 || l.MaxTotalSize < 0 || l.MaxRatio < 0 {
+		return errors.New("archive limits must not be negative")
+	}
+	if l.MaxEntries == 0 {
+		l.MaxEntries = defaultArchiveEntries
+	}
+	if l.MaxTotalSize == 0 {
+		l.MaxTotalSize = defaultArchiveTotalSize
+	}
+	if l.MaxRatio == 0 {
+		l.MaxRatio = defaultArchiveRatio
+	}
+	for i, extension := range l.DeniedExtensions {
+		extension = strings.ToLower(extension)
+		if !strings.HasPrefix(extension, ".") || len(extension) < 2 {
+			return fmt.Errorf("invalid denied archive extension %q", extension)
+		}
+		l.DeniedExtensions[i] = extension
+	}
+	return nil
+}
+
+// RolePolicy is the policy as it applies to the users of one role
+type RolePolicy struct {
+	Role     string         `json:"role"`
+	Types    []Rule         `json:"types"` // Ordered by extension
+	Archives archive.Limits `json:"archives"`
+}
+
+// ForRole returns the types the users of a role may upload. Unknown roles get the types of users
+// without a role.
+func (p *Policy) ForRole(role string) RolePolicy {
+	rules := p.rules(role)
+	types := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		types = append(types, rule)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Extension < types[j].Extension })
+	return RolePolicy{Role: role, Types: types, Archives: p.Archives}
+}
+
+// Check returns the rule of a file name for a role after checking the declared size against it
+func (p *Policy) Check(fileName string, size int64, role string) (Rule, error) {
+	rule, supported := p.rules(role)[helper.FileExt(fileName)]
+	if !supported {
+		return Rule{}, ErrUnsupported
+	}
+	if size > rule.MaxSize {
+		return Rule{}, ErrTooLarge
+	}
+	return rule, nil
+}
+
+// MaxSize is the largest size any type accepts for a role
+func (p *Policy) MaxSize(role string) int64 {
+	var maxSize int64
+	for _, rule := range p.rules(role) {
+		maxSize = max(maxSize, rule.MaxSize)
+	}
+	return maxSize
+}
+
+// LargestMaxSize is the largest size any type accepts for any role
+func (p *Policy) LargestMaxSize() int64 {
+	var maxSize int64
+	for role := range p.effective {
+		maxSize = max(maxSize, p.MaxSize(role))
+	}
+	return maxSize
+}
+
+// Category returns the category of a file name, looking at the types of every role. It serves files
+// recorded before their category was stored and is empty for types no longer in the policy.
+func (p *Policy) Category(fileName string) string {
+	extension := helper.FileExt(fileName)
+	if rule, ok := p.effective[""][extension]; ok {
+		return rule.Category
+	}
+	for _, rules := range p.effective {
+		if rule, ok := rules[extension]; ok {
+			return rule.Category
+		}
+	}
+	return ""
+}
+
+// Retention is how long the files of a category are kept, 0 when they are kept indefinitely
+func (p *Policy) Retention(category string) time.Duration {
+	return time.Duration(p.RetentionDays[category]) * 24 * time.Hour
+}
+
+// CheckContent verifies that the first bytes of a file match the type of the rule, so a renamed
+// executable is not accepted as an image
+func (r Rule) CheckContent(head []byte) error {
+	if helper.SniffContentType(head) != r.SniffedMime {
+		return ErrContentMismatch
+	}
+	return nil
+}
+
+func (p *Policy) rules(role string) map[string]Rule {
+	if rules, ok := p.effective[role]; ok {
+		return rules
+	}
+	return p.effective[""]
+}
+
+// apply returns the rules of a role derived from the base rules
+func (o RoleOverride) apply(base map[string]Rule) (map[string]Rule, error) {
+	allowed, err := ruleMap(o.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string]Rule, len(base)+len(allowed))
+	for extension, rule := range base {
+		rules[extension] = rule
+	}
+	for extension, rule := range allowed {
+		rules[extension] = rule
+	}
+	for extension, rule := range rules {
+		if maxSize, ok := o.MaxSize[rule.Category]; ok {
+			rule.MaxSize = maxSize
+			rules[extension] = rule
+		}
+	}
+	for _, extension := range o.Deny {
+		delete(rules, strings.ToLower(extension))
+	}
+	return rules, nil
+}
+
+// ruleMap checks rules and indexes them by extension
+func ruleMap(rules []Rule) (map[string]Rule, error) {
+	indexed := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		rule.Extension = strings.ToLower(rule.Extension)
+		if !strings.HasPrefix(rule.Extension, ".") || len(rule.Extension) < 2 {
+			return nil, fmt.Errorf("invalid extension %q", rule.Extension)
+		}
+		if _, duplicate := indexed[rule.Extension]; duplicate {
+			return nil, fmt.Errorf("extension %s is listed twice", rule.Extension)
+		}
+		if !slices.Contains(helper.SniffedTypes, rule.SniffedMime) {
+			return nil, fmt.Errorf("extension %s: sniffed_mime %q cannot be detected", rule.Extension, rule.SniffedMime)
+		}
+		if rule.Previews && !preview.CanDecode(rule.SniffedMime) {
+			return nil, fmt.Errorf("extension %s: no previews can be generated for %s", rule.Extension, rule.SniffedMime)
+		}
+		if rule.Category == "" || rule.MaxSize <= 0 {
+			return nil, fmt.Errorf("extension %s needs a category and a positive max_size", rule.Extension)
+		}
+		if rule.ContentType == "" {
+			rule.ContentType = rule.SniffedMime
+		}
+		indexed[rule.Extension] = rule
+	}
+	return indexed, nil
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPolicy = `{
+  "types": [
+    {"extension": ".JPG", "sniffed_mime": "image/jpeg", "category": "image", "max_size": 1000, "previews": true},
+    {"extension": ".txt", "sniffed_mime": "text/plain", "content_type": "text/plain; charset=utf-8", "category": "file", "max_size": 500}
+  ],
+  "roles": {
+    "premium": {
+      "max_size": {"image": 5000},
+      "allow": [{"extension": ".zip", "sniffed_mime": "application/zip", "category": "file", "max_size": 9000}],
+      "deny": [".TXT"]
+    }
+  },
+  "retention_days": {"file": 30}
+}`
+
+func TestParseAppliesRoles(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		fileName string
+		size     int64
+		role     string
+		err      error
+	}{
+		{"photo.jpg", 1000, "", nil},
+		{"photo.JPG", 1001, "", ErrTooLarge},
+		{"photo.jpg", 5000, "premium", nil},
+		{"notes.txt", 100, "", nil},
+		{"notes.txt", 100, "premium", ErrUnsupported},
+		{"backup.zip", 100, "", ErrUnsupported},
+		{"backup.zip", 9000, "premium", nil},
+		{"backup.zip", 100, "unknown role", ErrUnsupported},
+		{"program.exe", 1, "", ErrUnsupported},
+	} {
+		if _, err := policy.Check(test.fileName, test.size, test.role); !errors.Is(err, test.err) {
+			t.Errorf("Check(%q, %d, %q) = %v, want %v", test.fileName, test.size, test.role, err, test.err)
+		}
+	}
+
+	rule, _ := policy.Check("notes.txt", 1, "")
+	if rule.ContentType != "text/plain; charset=utf-8" || rule.Extension != ".txt" {
+		t.Errorf("rule of notes.txt = %+v", rule)
+	}
+	if rule, _ := policy.Check("photo.jpg", 1, ""); rule.ContentType != "image/jpeg" {
+		t.Errorf("content type of photo.jpg = %q, want the sniffed type", rule.ContentType)
+	}
+	if got := policy.MaxSize("premium"); got != 9000 {
+		t.Errorf("MaxSize(premium) = %d, want 9000", got)
+	}
+	if got := policy.LargestMaxSize(); got != 9000 {
+		t.Errorf("LargestMaxSize() = %d, want 9000", got)
+	}
+	if got := policy.Category("backup.zip"); got != "file" {
+		t.Errorf("Category(backup.zip) = %q, want the category of the premium rule", got)
+	}
+	if got := policy.Retention("file"); got != 30*24*time.Hour {
+		t.Errorf("Retention(file) = %v", got)
+	}
+	if got := policy.Retention("image"); got != 0 {
+		t.Errorf("Retention(image) = %v, want 0", got)
+	}
+	if policy.Archives.MaxEntries != defaultArchiveEntries || policy.Archives.MaxRatio != defaultArchiveRatio {
+		t.Errorf("archive limits = %+v, want the defaults", policy.Archives)
+	}
+
+	roles := policy.ForRole("premium")
+	var extensions []string
+	for _, rule := range roles.Types {
+		extensions = append(extensions, rule.Extension)
+	}
+	if got := strings.Join(extensions, " "); got != ".jpg .zip" {
+		t.Errorf("types of premium = %s, want .jpg .zip", got)
+	}
+}
+
+func TestParseRejectsInvalidPolicies(t *testing.T) {
+	for name, data := range map[string]string{
+		"no types":          `{"types": []}`,
+		"unknown field":     `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}], "typo": 1}`,
+		"missing dot":       `{"types": [{"extension": "txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}]}`,
+		"duplicate":         `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}, {"extension": ".TXT", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}]}`,
+		"undetectable type": `{"types": [{"extension": ".exe", "sniffed_mime": "application/x-msdownload", "category": "file", "max_size": 1}]}`,
+		"previews of text":  `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1, "previews": true}]}`,
+		"no max size":       `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file"}]}`,
+		"bad retention":     `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}], "retention_days": {"file": 0}}`,
+		"bad archive limit": `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}], "archives": {"max_entries": -1}}`,
+		"bad role rule":     `{"types": [{"extension": ".txt", "sniffed_mime": "text/plain", "category": "file", "max_size": 1}], "roles": {"r": {"allow": [{"extension": ".zip"}]}}}`,
+		"not json":          `types: []`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: policy accepted", name)
+		}
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	store, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Policy().Check("voice.opus", 1, ""); err != nil {
+		t.Errorf("default policy refuses Opus: %v", err)
+	}
+	store.Watch(time.Millisecond) // Returns at once without a policy file
+}
+
+func TestCheckContentRefusesSpoofedExtensions(t *testing.T) {
+	store, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		fileName string
+		head     string
+		err      error
+	}{
+		{"photo.jpg", "\xFF\xD8\xFF\xE0", nil},
+		{"photo.jpg", "\x89PNG\r\n\x1A\n", ErrContentMismatch},
+		{"photo.png", "MZ\x90\x00", ErrContentMismatch},
+		{"notes.txt", "plain notes\n", nil},
+		{"notes.txt", "%PDF-1.7\n", ErrContentMismatch},
+		{"notes.txt", "caf\xE9 au lait", ErrContentMismatch},
+		{"report.pdf", "plain notes\n", ErrContentMismatch},
+		{"backup.zip", "PK\x03\x04", nil},
+		{"backup.zip", "\x1F\x8B\x08", ErrContentMismatch},
+	} {
+		rule, err := store.Policy().Check(test.fileName, 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rule.CheckContent([]byte(test.head)); !errors.Is(err, test.err) {
+			t.Errorf("CheckContent of %s starting with %q = %v, want %v", test.fileName, test.head, err, test.err)
+		}
+	}
+}
+
+// writePolicy writes a policy file with the given modification time
+func writePolicy(t *testing.T, path, data string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	start := time.Now().Add(-time.Hour)
+	writePolicy(t, path, testPolicy, start)
+
+	store, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Policy().Check("backup.zip", 1, ""); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("zip accepted before the reload: %v", err)
+	}
+
+	// A valid new version is activated
+	changed := strings.Replace(testPolicy, `"types": [`, `"types": [
+    {"extension": ".zip", "sniffed_mime": "application/zip", "category": "file", "max_size": 100},`, 1)
+	writePolicy(t, path, changed, start.Add(time.Minute))
+	store.checkForChanges()
+	if _, err := store.Policy().Check("backup.zip", 1, ""); err != nil {
+		t.Fatalf("zip refused after the reload: %v", err)
+	}
+
+	// An invalid version is ignored and the previous policy stays active
+	writePolicy(t, path, `{"types": []}`, start.Add(2*time.Minute))
+	store.checkForChanges()
+	if _, err := store.Policy().Check("backup.zip", 1, ""); err != nil {
+		t.Fatalf("invalid version replaced the policy: %v", err)
+	}
+
+	// An unchanged file is not read again, even when its content differs
+	if err := os.WriteFile(path, []byte(testPolicy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, start.Add(2*time.Minute), start.Add(2*time.Minute))
+	store.checkForChanges()
+	if _, err := store.Policy().Check("backup.zip", 1, ""); err != nil {
+		t.Fatalf("unchanged file was read again: %v", err)
+	}
+
+	// A removed file keeps the policy
+	os.Remove(path)
+	store.checkForChanges()
+	if store.Policy() == nil {
+		t.Fatal("policy lost after the file was removed")
+	}
+}
+
+func TestLoadRejectsInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, path, `{"types": []}`, time.Now())
+	if _, err := Load(path); err == nil {
+		t.Error("Load accepted an invalid policy")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Load accepted a missing file")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package policy
+
+import (
+	_ "embed" // Embeds the default policy
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPolicy is used when no policy file is configured
+//
+//go:embed default.json
+var defaultPolicy []byte
+
+// Store holds the active policy and reloads it when its file changes
+type Store struct {
+	path    string
+	current atomic.Pointer[Policy]
+	modTime time.Time
+}
+
+// Load reads the policy file at path, or the built-in default policy when path is empty
+func Load(path string) (*Store, error) {
+	store := &Store{path: path}
+	if path == "" {
+		policy, err := Parse(defaultPolicy)
+		if err != nil {
+			return nil, err
+		}
+		store.current.Store(policy)
+		return store, nil
+	}
+	if err := store.reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Policy returns the active policy
+func (s *Store) Policy() *Policy {
+	return s.current.Load()
+}
+
+// Watch checks the policy file for changes every interval and activates the new policy. An invalid
+// file is logged and the previous policy stays active.
+func (s *Store) Watch(interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	for range time.Tick(interval) {
+		s.checkForChanges()
+	}
+}
+
+// checkForChanges reloads the policy file when it was modified since it was last read
+func (s *Store) checkForChanges() {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		log.Printf("[ERROR] Failed to check file type policy %s: %v", s.path, err)
+		return
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err := s.reload(); err != nil {
+		log.Printf("[ERROR] Failed to reload file type policy %s, keeping the previous one: %v", s.path, err)
+		return
+	}
+	log.Printf("[INFO] File type policy reloaded from %s", s.path)
+}
+
+// reload reads and activates the policy file
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	// The file is only read again once it changes, even when this version is invalid
+	s.modTime = info.ModTime()
+	policy, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	s.current.Store(policy)
+	return nil
+}
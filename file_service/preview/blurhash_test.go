@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package preview
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func solidImage(width, height int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// Reference values from a separate implementation of the BlurHash algorithm, for 32x32 images that
+// are hashed without being resized
+func TestBlurHash(t *testing.T) {
+	split := image.NewRGBA(image.Rect(0, 0, 32, 24))
+	gradient := image.NewRGBA(image.Rect(0, 0, 32, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			if x >= 16 {
+				split.Set(x, y, color.White)
+			} else {
+				split.Set(x, y, color.Black)
+			}
+			gradient.Set(x, y, color.RGBA{uint8(x * 8), uint8(y * 10), 128, 255})
+		}
+	}
+
+	for _, test := range []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{"red", solidImage(32, 32, color.RGBA{255, 0, 0, 255}), "L9TI:j|cfQ|c|co1fQo1fQfQfQfQ"},
+		{"white", solidImage(32, 32, color.White), "L9TSUA~qfQ~q~qoffQoffQfQfQfQ"},
+		{"blue", solidImage(32, 32, color.RGBA{30, 144, 255, 255}), "L93f%^k]fQk]k]flfQflfQfQfQfQ"},
+		{"black and white halves", split, "L~Lqe900Rj-;t7WBayj[fQfQfQfQ"},
+		{"gradient", gradient, "LxH27k2swxX8mHWWjtf7gJfjfQfj"},
+	} {
+		if got := BlurHash(test.img); got != test.want {
+			t.Errorf("BlurHash of %s = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBlurHashOfLargeImages(t *testing.T) {
+	// Large images are reduced first; a solid image keeps its color
+	for _, size := range []image.Point{{640, 480}, {3, 200}, {4000, 10}} {
+		got := BlurHash(solidImage(size.X, size.Y, color.RGBA{30, 144, 255, 255}))
+		if len(got) != 28 || got[2:6] != "3f%^" {
+			t.Errorf("BlurHash of a blue %v image = %s, want the average color 3f%%^", size, got)
+		}
+	}
+}
+
+func TestEncodeBase83(t *testing.T) {
+	for _, test := range []struct {
+		value, length int
+		want          string
+	}{
+		{0, 1, "0"},
+		{82, 1, "~"},
+		{83, 2, "10"},
+		{21, 1, "L"},
+		{3429, 2, "fQ"},
+		{83*83*83*83 - 1, 4, "~~~~"},
+	} {
+		if got := encodeBase83(test.value, test.length); got != test.want {
+			t.Errorf("encodeBase83(%d, %d) = %s, want %s", test.value, test.length, got, test.want)
+		}
+	}
+}
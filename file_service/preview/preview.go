@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder
+	"io"
+	"slices"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register the WebP decoder
+)
+
+// MaxPixels bounds the size of images that are decoded, so a small file that declares huge
+// dimensions cannot exhaust memory
+const MaxPixels = 50 * 1000 * 1000
+
+// jpegQuality is the encoding quality of the generated variants
+const jpegQuality = 80
+
+// Variant is a resized copy of an image, bounded by MaxSide on its longest side
+type Variant struct {
+	Name    string
+	MaxSide int
+}
+
+// Variants lists the resized copies generated for every uploaded image
+var Variants = []Variant{
+	{Name: "thumbnail", MaxSide: 320},
+	{Name: "medium", MaxSide: 1280},
+}
+
+// DecodableTypes lists the MIME types of the images previews can be generated for
+var DecodableTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// CanDecode reports whether previews can be generated for images of a MIME type
+func CanDecode(mimeType string) bool {
+	return slices.Contains(DecodableTypes, mimeType)
+}
+
+// ErrTooLarge is returned when an image has more than MaxPixels pixels
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+// Decode reads an image of one of the DecodableTypes after checking its dimensions against MaxPixels
+func Decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Dimensions returns the width and height declared in an image header without decoding the pixels
+func Dimensions(r io.Reader) (int, int, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// Resize scales an image down so its longest side is at most maxSide, keeping the aspect ratio.
+// Images that are already small enough are returned unchanged.
+func Resize(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*maxSide/width)
+		width = maxSide
+	} else {
+		width = max(1, width*maxSide/height)
+		height = maxSide
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+	return resized
+}
+
+// EncodeJPEG encodes an image as JPEG. Transparent areas are flattened onto white.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
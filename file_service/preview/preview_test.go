@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader is the start of a PNG declaring the given dimensions, without any pixels
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 2 // 8-bit RGB
+
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestResize(t *testing.T) {
+	for _, test := range []struct {
+		width, height, maxSide int
+		wantWidth, wantHeight  int
+	}{
+		{2000, 1500, 320, 320, 240},
+		{1500, 2000, 320, 240, 320},
+		{1280, 1280, 320, 320, 320},
+		{5000, 10, 320, 320, 1},
+		{10, 5000, 1280, 2, 1280},
+		{300, 200, 320, 300, 200},
+		{320, 100, 320, 320, 100},
+	} {
+		resized := Resize(image.NewRGBA(image.Rect(0, 0, test.width, test.height)), test.maxSide)
+		if bounds := resized.Bounds(); bounds.Dx() != test.wantWidth || bounds.Dy() != test.wantHeight {
+			t.Errorf("Resize(%dx%d, %d) = %dx%d, want %dx%d", test.width, test.height, test.maxSide, bounds.Dx(), bounds.Dy(), test.wantWidth, test.wantHeight)
+		}
+	}
+}
+
+func TestVariantsOfAnImage(t *testing.T) {
+	img := solidImage(2000, 1500, color.RGBA{200, 40, 40, 255})
+	decoded, err := Decode(encodePNG(t, img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, variant := range Variants {
+		encoded, err := EncodeJPEG(Resize(decoded, variant.MaxSide))
+		if err != nil {
+			t.Fatal(err)
+		}
+		thumbnail, err := jpeg.Decode(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("%s is not a JPEG: %v", variant.Name, err)
+		}
+		bounds := thumbnail.Bounds()
+		if bounds.Dx() != variant.MaxSide || bounds.Dy() != variant.MaxSide*3/4 {
+			t.Errorf("%s is %dx%d, want %dx%d", variant.Name, bounds.Dx(), bounds.Dy(), variant.MaxSide, variant.MaxSide*3/4)
+		}
+		r, g, b, _ := thumbnail.At(bounds.Dx()/2, bounds.Dy()/2).RGBA()
+		if r>>8 < 190 || g>>8 > 55 || b>>8 > 55 {
+			t.Errorf("%s has the color %d,%d,%d in its center", variant.Name, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestEncodeJPEGFlattensTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, color.NRGBA{0, 0, 0, 255})
+		}
+	}
+	encoded, err := EncodeJPEG(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat, err := jpeg.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := flat.At(2, 8).RGBA(); r>>8 > 30 || g>>8 > 30 || b>>8 > 30 {
+		t.Errorf("opaque black became %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+	if r, g, b, _ := flat.At(13, 8).RGBA(); r>>8 < 225 || g>>8 < 225 || b>>8 < 225 {
+		t.Errorf("transparent area became %d,%d,%d instead of white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	if _, err := Decode(pngHeader(10000, 10000)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("decoding a 10000x10000 image = %v, want %v", err, ErrTooLarge)
+	}
+	if _, err := Decode([]byte("not an image")); err == nil {
+		t.Error("decoding text succeeded")
+	}
+	width, height, err := Dimensions(bytes.NewReader(pngHeader(10000, 20)))
+	if err != nil || width != 10000 || height != 20 {
+		t.Errorf("Dimensions = %dx%d, %v", width, height, err)
+	}
+	img, err := Decode(encodePNG(t, solidImage(30, 20, color.White)))
+	if err != nil || img.Bounds().Dx() != 30 || img.Bounds().Dy() != 20 {
+		t.Errorf("Decode = %v, %v", img.Bounds(), err)
+	}
+}
@@ -20,13 +20,52 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"urulink.com/file_service/handlers"
-	"urulink.com/file_service/middleware"
+	"urulink.com/platform/auth"
 )
 
 func SetRoutes(app *fiber.App) {
 	handler := handlers.Init()
-	authRoutes := app.Group("/", middleware.HttpAuth(&handler))
+
+	// Request bodies are streamed; skip what a handler left unread before the next request
+	app.Use(handlers.DiscardUnreadBody)
+
+	// Presigned URLs of the local storage backend carry their own signature instead of a login
+	app.Get("/storage/*", handler.GetPresignedObject)
+	app.Put("/storage/*", handler.PutPresignedObject)
+	app.Post("/storage/*", handler.PostPresignedObject)
+
+	// File links issued by POST /files/urls carry their own signature as well
+	app.Get("/links/:id", handler.GetLinkedFile)
+	app.Get("/links/:id/preview/:variant", handler.GetLinkedPreview)
+
+	// Browsers send the tus preflight without credentials
+	app.Options("/tus", handler.TusOptions)
+
+	authRoutes := app.Group("/", auth.HttpAuth(handler.EnvManger.AuthServiceUrl))
 	authRoutes.Post("/upload", handler.UploadFile)
+	authRoutes.Get("/files", handler.ListFiles)
+	authRoutes.Post("/files/urls", handler.GetFileUrls)
+	authRoutes.Get("/files/:id", handler.DownloadFile)
+	authRoutes.Get("/files/:id/metadata", handler.GetFileMetadata)
+	authRoutes.Get("/files/:id/preview/:variant", handler.GetFilePreview)
+	authRoutes.Delete("/files/:id", handler.DeleteFile)
+	authRoutes.Get("/policy", handler.GetPolicy)
+	authRoutes.Get("/usage", handler.GetUsage)
+	authRoutes.Put("/usage/:user_id", handler.SetUserQuota)
+	authRoutes.Put("/quota-groups/:name", handler.SetGroupQuota)
+	authRoutes.Post("/uploads/presign", handler.PresignUpload)
+	authRoutes.Post("/uploads/:id/complete", handler.CompleteUpload)
+	authRoutes.Post("/tus", handler.TusCreate)
+	authRoutes.Head("/tus/:id", handler.TusHead)
+	authRoutes.Patch("/tus/:id", handler.TusPatch)
+	authRoutes.Delete("/tus/:id", handler.TusDelete)
+	go handler.ExpireUploads(time.Hour)
+	go handler.Policies.Watch(10 * time.Second)
+	if handler.GCInterval > 0 {
+		go handler.ScheduleGC(handler.GCInterval)
+	}
 }
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// clamdChunkSize is the size of the chunks streamed to clamd; clamd itself limits the total
+// stream length with StreamMaxLength
+const clamdChunkSize = 64 * 1024
+
+// Clamd scans content with a clamd daemon using its INSTREAM command
+type Clamd struct {
+	Network string // "tcp" or "unix"
+	Address string // host:port or socket path
+}
+
+// NewClamd parses a clamd address: tcp://host:port, unix:///path/to/clamd.sock or a plain host:port
+func NewClamd(address string) (*Clamd, error) {
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		return &Clamd{Network: "unix", Address: strings.TrimPrefix(address, "unix://")}, nil
+	case strings.HasPrefix(address, "tcp://"):
+		return &Clamd{Network: "tcp", Address: strings.TrimPrefix(address, "tcp://")}, nil
+	case strings.Contains(address, "://"):
+		return nil, fmt.Errorf("unsupported clamd address %s", address)
+	default:
+		return &Clamd{Network: "tcp", Address: address}, nil
+	}
+}
+
+// Scan streams content to clamd and parses its verdict
+func (s *Clamd) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// A write fails when clamd stops reading, e.g. past its size limit; its reply tells why
+	writeErr := writeInstream(conn, content)
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		if writeErr != nil {
+			return Result{}, writeErr
+		}
+		return Result{}, err
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// writeInstream sends the INSTREAM command: chunks prefixed by their length, ended by an empty chunk
+func writeInstream(conn net.Conn, content io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(content, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <signature> FOUND" and "<reason> ERROR"
+func parseClamdReply(reply string) (Result, error) {
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return Result{Infected: true, Signature: signature}, nil
+	case strings.HasSuffix(reply, "OK"):
+		return Result{}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return Result{}, fmt.Errorf("%w: %s", ErrUnscannable, strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return Result{}, fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+}
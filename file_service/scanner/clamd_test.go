@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClamdReply(t *testing.T) {
+	for _, test := range []struct {
+		reply       string
+		result      Result
+		unscannable bool
+		fails       bool
+	}{
+		{reply: "stream: OK", result: Result{}},
+		{reply: "stream: Eicar-Test-Signature FOUND", result: Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+		{reply: "stream: Win.Trojan.Agent-1 FOUND", result: Result{Infected: true, Signature: "Win.Trojan.Agent-1"}},
+		{reply: "INSTREAM size limit exceeded. ERROR", unscannable: true},
+		{reply: "UNKNOWN COMMAND", fails: true},
+		{reply: "", fails: true},
+	} {
+		result, err := parseClamdReply(test.reply)
+		switch {
+		case test.unscannable:
+			if !errors.Is(err, ErrUnscannable) {
+				t.Errorf("parseClamdReply(%q) error = %v, want ErrUnscannable", test.reply, err)
+			}
+		case test.fails:
+			if err == nil || errors.Is(err, ErrUnscannable) {
+				t.Errorf("parseClamdReply(%q) error = %v, want an unexpected reply error", test.reply, err)
+			}
+		case err != nil || result != test.result:
+			t.Errorf("parseClamdReply(%q) = %+v, %v; want %+v", test.reply, result, err, test.result)
+		}
+	}
+}
+
+func TestNewClamd(t *testing.T) {
+	for _, test := range []struct{ address, network, target string }{
+		{"tcp://clamav:3310", "tcp", "clamav:3310"},
+		{"unix:///run/clamd.sock", "unix", "/run/clamd.sock"},
+		{"localhost:3310", "tcp", "localhost:3310"},
+	} {
+		clamd, err := NewClamd(test.address)
+		if err != nil || clamd.Network != test.network || clamd.Address != test.target {
+			t.Errorf("NewClamd(%q) = %+v, %v", test.address, clamd, err)
+		}
+	}
+	if _, err := NewClamd("http://clamav:3310"); err == nil {
+		t.Error("NewClamd accepted an http address")
+	}
+}
+
+// serveClamd answers one INSTREAM command with reply and returns the content it received
+func serveClamd(t *testing.T, reply string) (*Clamd, <-chan []byte) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		command := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var content bytes.Buffer
+		for {
+			var length uint32
+			if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+				return
+			}
+			if length == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, conn, int64(length)); err != nil {
+				return
+			}
+		}
+		received <- content.Bytes()
+		conn.Write([]byte(reply + "\x00"))
+	}()
+	return &Clamd{Network: "tcp", Address: listener.Addr().String()}, received
+}
+
+func TestClamdScanStreamsChunks(t *testing.T) {
+	clamd, received := serveClamd(t, "stream: Eicar-Test-Signature FOUND")
+	// More than two chunks, the last one partial
+	content := bytes.Repeat([]byte("x"), 2*clamdChunkSize+100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := clamd.Scan(ctx, bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Fatalf("Scan = %+v, want the EICAR signature", result)
+	}
+	if got := <-received; !bytes.Equal(got, content) {
+		t.Fatalf("clamd received %d bytes, want %d", len(got), len(content))
+	}
+}
+
+func TestClamdScanReportsErrors(t *testing.T) {
+	clamd, _ := serveClamd(t, "INSTREAM size limit exceeded. ERROR")
+	_, err := clamd.Scan(context.Background(), strings.NewReader("content"))
+	if !errors.Is(err, ErrUnscannable) {
+		t.Fatalf("Scan error = %v, want ErrUnscannable", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scanner
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+)
+
+// zipOf returns a zip archive holding one entry with data
+func zipOf(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	entry, err := archive.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Write(data)
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFakeDetectsEicar(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		content  []byte
+		infected bool
+	}{
+		{"clean", []byte("hello world"), false},
+		{"eicar", eicar, true},
+		{"zipped eicar", zipOf(t, "eicar.com", eicar), true},
+		{"nested zip", zipOf(t, "inner.zip", zipOf(t, "eicar.com", eicar)), true},
+		{"clean zip", zipOf(t, "notes.txt", []byte("notes")), false},
+	} {
+		result, err := Fake{}.Scan(context.Background(), bytes.NewReader(test.content))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if result.Infected != test.infected {
+			t.Errorf("%s: infected = %v, want %v", test.name, result.Infected, test.infected)
+		}
+		if test.infected && result.Signature != eicarSignature {
+			t.Errorf("%s: signature = %q", test.name, result.Signature)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrUnscannable is returned when the scanner received the content but refused to scan it, for
+// example because it exceeds the stream size limit of clamd. Scanning it again gives the same answer.
+var ErrUnscannable = errors.New("content cannot be scanned")
+
+// Result is the verdict of a scan
+type Result struct {
+	Infected  bool
+	Signature string // Name of the detected malware; empty for clean content
+}
+
+// Scanner checks content for malware. Implementations must accept archives and scan their entries.
+type Scanner interface {
+	Scan(ctx context.Context, content io.Reader) (Result, error)
+}
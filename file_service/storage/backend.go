@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"urulink.com/file_service/models"
+)
+
+// Errors shared by the storage backends
+var (
+	ErrNotFound     = errors.New("object not found")
+	ErrNoSuchUpload = errors.New("multipart upload not found")
+	ErrNotSupported = errors.New("operation not supported by the storage backend")
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	ContentType  string
+	Metadata     map[string]string
+	LastModified time.Time
+}
+
+// Storage is implemented by every backend files can be kept in: MinIO or another S3 compatible
+// service, or a local directory for small deployments.
+type Storage interface {
+	// UploadFile stores fileSize bytes read from fileData under objectName
+	UploadFile(ctx context.Context, fileData io.Reader, objectName string, fileSize int64, contentType string, metadata map[string]string) error
+	// StatFile describes an object; it returns ErrNotFound when the object does not exist
+	StatFile(ctx context.Context, objectName string) (ObjectInfo, error)
+	// DownloadFile opens a stream over the bytes start..end (inclusive) of an object
+	DownloadFile(ctx context.Context, objectName string, start, end int64) (io.ReadCloser, error)
+	// OpenFile opens a stream over a whole object and returns its size
+	OpenFile(ctx context.Context, objectName string) (io.ReadCloser, int64, error)
+	// DeleteFile removes an object; removing a missing object is not an error
+	DeleteFile(ctx context.Context, objectName string) error
+	// ListFiles lists the objects whose name starts with prefix
+	ListFiles(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+	// GeneratePresignedURL returns a URL that downloads an object without further authentication
+	GeneratePresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error)
+	// PresignedPutURL returns a URL accepting one PUT of an object and the headers the request must carry
+	PresignedPutURL(ctx context.Context, objectName, contentType string, size int64, expiry time.Duration) (string, map[string]string, error)
+	// PresignedPostPolicy returns the URL and form fields of a browser POST upload of an object
+	PresignedPostPolicy(ctx context.Context, objectName, contentType string, size int64, expiry time.Duration) (string, map[string]string, error)
+
+	// NewMultipartUpload starts assembling an object from parts and returns the upload ID
+	NewMultipartUpload(ctx context.Context, objectName, contentType string, metadata map[string]string) (string, error)
+	// UploadPart stores one part of a multipart upload and returns its ETag
+	UploadPart(ctx context.Context, objectName, uploadId string, partNumber int, data []byte) (string, error)
+	// CompleteMultipartUpload assembles the parts into the final object
+	CompleteMultipartUpload(ctx context.Context, objectName, uploadId string, parts []models.UploadPart) error
+	// AbortMultipartUpload discards a multipart upload; it returns ErrNoSuchUpload when it does not exist
+	AbortMultipartUpload(ctx context.Context, objectName, uploadId string) error
+}
+
+// Both backends implement Storage
+var (
+	_ Storage = (*MinioStorage)(nil)
+	_ Storage = (*LocalStorage)(nil)
+)
+
+// IsNotFound reports whether a storage error means the object does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsNoSuchUpload reports whether a storage error means the multipart upload no longer exists.
+func IsNoSuchUpload(err error) bool {
+	return errors.Is(err, ErrNoSuchUpload)
+}
@@ -0,0 +1,413 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"urulink.com/file_service/models"
+)
+
+// LocalStorage keeps objects as files in a directory. Presigned URLs point back to the file
+// service, which checks their HMAC signature before serving or accepting the object.
+type LocalStorage struct {
+	Root       string // Directory holding objects, their metadata and multipart uploads
+	PublicUrl  string // Base URL of the file service, used in presigned URLs
+	SigningKey []byte // Key of the presigned URL signatures
+}
+
+// localMetadata is stored next to each object
+type localMetadata struct {
+	ContentType string            `json:"content_type"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// localUpload is stored in the directory of each multipart upload
+type localUpload struct {
+	ObjectName string        `json:"object_name"`
+	Meta       localMetadata `json:"meta"`
+}
+
+// tempPrefix marks files being written; they are skipped when listing
+const tempPrefix = ".tmp-"
+
+// InitLocal prepares the directory layout of a local storage backend.
+func InitLocal(root, publicUrl, signingKey string) (*LocalStorage, error) {
+	if signingKey == "" {
+		return nil, errors.New("a signing key is required for presigned URLs")
+	}
+	for _, dir := range []string{"objects", "meta", "uploads"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o750); err != nil {
+			return nil, err
+		}
+	}
+	return &LocalStorage{
+		Root:       root,
+		PublicUrl:  strings.TrimRight(publicUrl, "/"),
+		SigningKey: []byte(signingKey),
+	}, nil
+}
+
+// objectPath returns the file of an object, refusing names that would leave the objects directory
+func (ls *LocalStorage) objectPath(dir, objectName string) (string, error) {
+	clean := path.Clean(objectName)
+	if objectName == "" || clean != objectName || strings.HasPrefix(clean, "/") || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	return filepath.Join(ls.Root, dir, filepath.FromSlash(clean)), nil
+}
+
+// UploadFile writes the object to a temporary file and renames it into place, so readers never
+// see a partial object.
+func (ls *LocalStorage) UploadFile(ctx context.Context, fileData io.Reader, objectName string, fileSize int64, contentType string, metadata map[string]string) error {
+	objectFile, err := ls.objectPath("objects", objectName)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(objectFile, func(f *os.File) error {
+		written, err := io.Copy(f, fileData)
+		if err != nil {
+			return err
+		}
+		if written != fileSize {
+			return fmt.Errorf("received %d bytes, expected %d", written, fileSize)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	metaFile, _ := ls.objectPath("meta", objectName)
+	meta, err := json.Marshal(localMetadata{ContentType: contentType, Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(metaFile, func(f *os.File) error {
+		_, err := f.Write(meta)
+		return err
+	})
+}
+
+// StatFile returns the size, content type and user metadata of an object.
+func (ls *LocalStorage) StatFile(ctx context.Context, objectName string) (ObjectInfo, error) {
+	objectFile, err := ls.objectPath("objects", objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	stat, err := os.Stat(objectFile)
+	if err != nil {
+		return ObjectInfo{}, localError(err)
+	}
+
+	info := ObjectInfo{Name: objectName, Size: stat.Size(), LastModified: stat.ModTime()}
+	metaFile, _ := ls.objectPath("meta", objectName)
+	if data, err := os.ReadFile(metaFile); err == nil {
+		var meta localMetadata
+		if err := json.Unmarshal(data, &meta); err == nil {
+			info.ContentType = meta.ContentType
+			info.Metadata = meta.Metadata
+		}
+	}
+	return info, nil
+}
+
+// DownloadFile opens a stream over the bytes start..end (inclusive) of an object.
+func (ls *LocalStorage) DownloadFile(ctx context.Context, objectName string, start, end int64) (io.ReadCloser, error) {
+	objectFile, err := ls.objectPath("objects", objectName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(objectFile)
+	if err != nil {
+		return nil, localError(err)
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, end-start+1), f}, nil
+}
+
+// OpenFile opens a stream over a whole object and returns its size.
+func (ls *LocalStorage) OpenFile(ctx context.Context, objectName string) (io.ReadCloser, int64, error) {
+	objectFile, err := ls.objectPath("objects", objectName)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(objectFile)
+	if err != nil {
+		return nil, 0, localError(err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, stat.Size(), nil
+}
+
+// DeleteFile removes an object and its metadata.
+func (ls *LocalStorage) DeleteFile(ctx context.Context, objectName string) error {
+	objectFile, err := ls.objectPath("objects", objectName)
+	if err != nil {
+		return err
+	}
+	metaFile, _ := ls.objectPath("meta", objectName)
+	for _, file := range []string{objectFile, metaFile} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListFiles lists the objects whose name starts with prefix.
+func (ls *LocalStorage) ListFiles(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objectsDir := filepath.Join(ls.Root, "objects")
+	var objects []ObjectInfo
+	err := filepath.WalkDir(objectsDir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return err
+		}
+		rel, err := filepath.Rel(objectsDir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Name: name, Size: stat.Size(), LastModified: stat.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+// GeneratePresignedURL returns a signed URL under which the file service serves the object.
+func (ls *LocalStorage) GeneratePresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	return ls.presign("GET", objectName, "", 0, expiry), nil
+}
+
+// PresignedPutURL returns a signed URL under which the file service accepts one PUT of the object.
+// Content type and size are part of the signature.
+func (ls *LocalStorage) PresignedPutURL(ctx context.Context, objectName, contentType string, size int64, expiry time.Duration) (string, map[string]string, error) {
+	headers := map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.FormatInt(size, 10),
+	}
+	return ls.presign("PUT", objectName, contentType, size, expiry), headers, nil
+}
+
+// PresignedPostPolicy returns a signed URL under which the file service accepts one multipart form
+// upload of the object in its "file" field. Content type and size are part of the signature and
+// of the URL, so the request can be checked before its body is read.
+func (ls *LocalStorage) PresignedPostPolicy(ctx context.Context, objectName, contentType string, size int64, expiry time.Duration) (string, map[string]string, error) {
+	fields := map[string]string{
+		"Content-Type": contentType,
+	}
+	return ls.presign("POST", objectName, contentType, size, expiry), fields, nil
+}
+
+// presign builds a URL of the object with an expiry time and a signature over the request
+func (ls *LocalStorage) presign(method, objectName, contentType string, size int64, expiry time.Duration) string {
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	if method == "POST" {
+		query.Set("content_type", contentType)
+		query.Set("size", strconv.FormatInt(size, 10))
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", ls.signature(method, objectName, contentType, size, expires))
+	return ls.PublicUrl + "/storage/" + (&url.URL{Path: objectName}).EscapedPath() + "?" + query.Encode()
+}
+
+// signature is the hex encoded HMAC-SHA256 of a presigned request
+func (ls *LocalStorage) signature(method, objectName, contentType string, size int64, expires int64) string {
+	mac := hmac.New(sha256.New, ls.SigningKey)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d\n%d", method, objectName, contentType, size, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPresigned checks a request made to a presigned URL: the signature must match the
+// method, object, content type and size of the request, and the URL must not have expired.
+// Content type and size are only signed for PUT and POST requests.
+func (ls *LocalStorage) VerifyPresigned(method, objectName, contentType string, size int64, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	if method != "PUT" && method != "POST" {
+		contentType, size = "", 0
+	}
+	expected := ls.signature(method, objectName, contentType, size, expiresAt)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// NewMultipartUpload creates a directory collecting the parts of an upload.
+func (ls *LocalStorage) NewMultipartUpload(ctx context.Context, objectName, contentType string, metadata map[string]string) (string, error) {
+	if _, err := ls.objectPath("objects", objectName); err != nil {
+		return "", err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadId := hex.EncodeToString(id)
+	uploadDir := filepath.Join(ls.Root, "uploads", uploadId)
+	if err := os.Mkdir(uploadDir, 0o750); err != nil {
+		return "", err
+	}
+
+	upload, err := json.Marshal(localUpload{ObjectName: objectName, Meta: localMetadata{ContentType: contentType, Metadata: metadata}})
+	if err != nil {
+		return "", err
+	}
+	return uploadId, os.WriteFile(filepath.Join(uploadDir, "upload.json"), upload, 0o640)
+}
+
+// UploadPart stores one part of a multipart upload; its ETag is the SHA-256 of the part.
+func (ls *LocalStorage) UploadPart(ctx context.Context, objectName, uploadId string, partNumber int, data []byte) (string, error) {
+	uploadDir, err := ls.uploadDir(uploadId)
+	if err != nil {
+		return "", err
+	}
+	partFile := filepath.Join(uploadDir, strconv.Itoa(partNumber))
+	if err := writeFileAtomic(partFile, func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	}); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CompleteMultipartUpload concatenates the parts into the object and removes the upload.
+func (ls *LocalStorage) CompleteMultipartUpload(ctx context.Context, objectName, uploadId string, parts []models.UploadPart) error {
+	uploadDir, err := ls.uploadDir(uploadId)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(uploadDir, "upload.json"))
+	if err != nil {
+		return err
+	}
+	var upload localUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return err
+	}
+
+	var readers []io.Reader
+	var size int64
+	for _, part := range parts {
+		f, err := os.Open(filepath.Join(uploadDir, strconv.Itoa(part.Number)))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		readers = append(readers, f)
+		size += stat.Size()
+	}
+
+	if err := ls.UploadFile(ctx, io.MultiReader(readers...), objectName, size, upload.Meta.ContentType, upload.Meta.Metadata); err != nil {
+		return err
+	}
+	return os.RemoveAll(uploadDir)
+}
+
+// AbortMultipartUpload removes an upload and its parts.
+func (ls *LocalStorage) AbortMultipartUpload(ctx context.Context, objectName, uploadId string) error {
+	uploadDir, err := ls.uploadDir(uploadId)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(uploadDir)
+}
+
+// uploadDir returns the directory of an existing multipart upload
+func (ls *LocalStorage) uploadDir(uploadId string) (string, error) {
+	if _, err := hex.DecodeString(uploadId); err != nil || uploadId == "" {
+		return "", ErrNoSuchUpload
+	}
+	uploadDir := filepath.Join(ls.Root, "uploads", uploadId)
+	if _, err := os.Stat(uploadDir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrNoSuchUpload
+		}
+		return "", err
+	}
+	return uploadDir, nil
+}
+
+// writeFileAtomic writes a file through a temporary file in the same directory
+func writeFileAtomic(file string, write func(f *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(file), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // No-op once renamed
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// localError maps file system errors to the shared storage errors
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"urulink.com/file_service/models"
+)
+
+func newTestLocal(t *testing.T) *LocalStorage {
+	t.Helper()
+	local, err := InitLocal(t.TempDir(), "https://files.example", "signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return local
+}
+
+func TestLocalStorageObjects(t *testing.T) {
+	local := newTestLocal(t)
+	ctx := context.Background()
+	data := []byte("hello local storage")
+
+	if err := local.UploadFile(ctx, bytes.NewReader(data), "dir/a.txt", int64(len(data)), "text/plain", map[string]string{"Owner": "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := local.StatFile(ctx, "dir/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len(data)) || info.ContentType != "text/plain" || info.Metadata["Owner"] != "u1" {
+		t.Fatalf("unexpected object info %+v", info)
+	}
+
+	reader, err := local.DownloadFile(ctx, "dir/a.txt", 6, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(part) != "local" {
+		t.Fatalf("range read %q, want %q", part, "local")
+	}
+
+	objects, err := local.ListFiles(ctx, "dir/")
+	if err != nil || len(objects) != 1 || objects[0].Name != "dir/a.txt" {
+		t.Fatalf("ListFiles() = %+v, %v", objects, err)
+	}
+
+	if err := local.DeleteFile(ctx, "dir/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.DeleteFile(ctx, "dir/a.txt"); err != nil {
+		t.Fatalf("deleting a missing object failed: %v", err)
+	}
+	if _, err := local.StatFile(ctx, "dir/a.txt"); !IsNotFound(err) {
+		t.Fatalf("StatFile() of a deleted object = %v, want ErrNotFound", err)
+	}
+	if _, err := local.DownloadFile(ctx, "dir/a.txt", 0, 1); !IsNotFound(err) {
+		t.Fatalf("DownloadFile() of a deleted object = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStorageRejectsInvalidNames(t *testing.T) {
+	local := newTestLocal(t)
+	for _, name := range []string{"", "../escape", "a/../../b", "/abs", "a//b"} {
+		if err := local.UploadFile(context.Background(), strings.NewReader("x"), name, 1, "text/plain", nil); err == nil {
+			t.Errorf("UploadFile(%q) succeeded", name)
+		}
+	}
+}
+
+func TestLocalStorageSizeMismatch(t *testing.T) {
+	local := newTestLocal(t)
+	if err := local.UploadFile(context.Background(), strings.NewReader("abc"), "a", 5, "text/plain", nil); err == nil {
+		t.Fatal("UploadFile() accepted fewer bytes than declared")
+	}
+	if _, err := local.StatFile(context.Background(), "a"); !IsNotFound(err) {
+		t.Fatalf("a failed upload left an object behind: %v", err)
+	}
+}
+
+func TestLocalStorageMultipart(t *testing.T) {
+	local := newTestLocal(t)
+	ctx := context.Background()
+	uploadId, err := local.NewMultipartUpload(ctx, "big.bin", "application/octet-stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []models.UploadPart
+	for i, chunk := range []string{"first-", "second-", "third"} {
+		etag, err := local.UploadPart(ctx, "big.bin", uploadId, i+1, []byte(chunk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, models.UploadPart{Number: i + 1, ETag: etag})
+	}
+	if err := local.CompleteMultipartUpload(ctx, "big.bin", uploadId, parts); err != nil {
+		t.Fatal(err)
+	}
+	reader, size, err := local.OpenFile(ctx, "big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "first-second-third" || size != int64(len(data)) {
+		t.Fatalf("assembled object %q of size %d", data, size)
+	}
+	if err := local.AbortMultipartUpload(ctx, "big.bin", uploadId); !IsNoSuchUpload(err) {
+		t.Fatalf("AbortMultipartUpload() of a completed upload = %v, want ErrNoSuchUpload", err)
+	}
+}
+
+// presignedQuery returns the path and query of a presigned URL
+func presignedQuery(t *testing.T, rawUrl string) (string, url.Values) {
+	t.Helper()
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Path, parsed.Query()
+}
+
+func TestLocalStoragePresignedUrls(t *testing.T) {
+	local := newTestLocal(t)
+	ctx := context.Background()
+
+	getUrl, _ := local.GeneratePresignedURL(ctx, "a b.txt", time.Minute)
+	path, query := presignedQuery(t, getUrl)
+	if path != "/storage/a b.txt" {
+		t.Fatalf("unexpected path %q", path)
+	}
+	if !local.VerifyPresigned("GET", "a b.txt", "", 0, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("valid GET signature refused")
+	}
+	if local.VerifyPresigned("GET", "other.txt", "", 0, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("GET signature accepted for another object")
+	}
+	if local.VerifyPresigned("PUT", "a b.txt", "", 0, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("GET signature accepted for a PUT")
+	}
+
+	putUrl, headers, _ := local.PresignedPutURL(ctx, "a.png", "image/png", 42, time.Minute)
+	_, query = presignedQuery(t, putUrl)
+	if headers["Content-Type"] != "image/png" || headers["Content-Length"] != "42" {
+		t.Fatalf("unexpected PUT headers %v", headers)
+	}
+	if !local.VerifyPresigned("PUT", "a.png", "image/png", 42, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("valid PUT signature refused")
+	}
+	if local.VerifyPresigned("PUT", "a.png", "image/png", 43, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("PUT signature accepted for another size")
+	}
+
+	postUrl, fields, _ := local.PresignedPostPolicy(ctx, "a.png", "image/png", 42, time.Minute)
+	_, query = presignedQuery(t, postUrl)
+	if fields["Content-Type"] != "image/png" || query.Get("content_type") != "image/png" || query.Get("size") != "42" {
+		t.Fatalf("unexpected POST fields %v and query %v", fields, query)
+	}
+	if !local.VerifyPresigned("POST", "a.png", "image/png", 42, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("valid POST signature refused")
+	}
+	if local.VerifyPresigned("POST", "a.png", "text/plain", 42, query.Get("expires"), query.Get("signature")) {
+		t.Fatal("POST signature accepted for another content type")
+	}
+
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	if local.VerifyPresigned("GET", "a b.txt", "", 0, expired, local.signature("GET", "a b.txt", "", 0, time.Now().Add(-time.Minute).Unix())) {
+		t.Fatal("expired signature accepted")
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/minio/minio-go/v7"
+	"urulink.com/file_service/models"
+)
+
+// MinPartSize is the smallest part size accepted by S3 multipart uploads, except for the last part.
+const MinPartSize = 5 * 1024 * 1024
+
+// NewMultipartUpload starts a multipart upload for an object and returns its upload ID.
+func (ms *MinioStorage) NewMultipartUpload(ctx context.Context, objectName, contentType string, metadata map[string]string) (string, error) {
+	return ms.Core.NewMultipartUpload(ctx, ms.BucketName, objectName, minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: metadata,
+	})
+}
+
+// UploadPart uploads one part of a multipart upload and returns its ETag.
+func (ms *MinioStorage) UploadPart(ctx context.Context, objectName, uploadId string, partNumber int, data []byte) (string, error) {
+	part, err := ms.Core.PutObjectPart(ctx, ms.BucketName, objectName, uploadId, partNumber,
+		bytes.NewReader(data), int64(len(data)), minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", minioError(err)
+	}
+	return part.ETag, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the final object.
+func (ms *MinioStorage) CompleteMultipartUpload(ctx context.Context, objectName, uploadId string, parts []models.UploadPart) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	_, err := ms.Core.CompleteMultipartUpload(ctx, ms.BucketName, objectName, uploadId, completeParts, minio.PutObjectOptions{})
+	return minioError(err)
+}
+
+// AbortMultipartUpload discards a multipart upload and every part uploaded so far.
+func (ms *MinioStorage) AbortMultipartUpload(ctx context.Context, objectName, uploadId string) error {
+	return minioError(ms.Core.AbortMultipartUpload(ctx, ms.BucketName, objectName, uploadId))
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// PresignedPutURL returns a URL that accepts a single PUT of the object. Content-Type and
+// Content-Length are part of the signature, so the client cannot send a different type or size.
+func (ms *MinioStorage) PresignedPutURL(ctx context.Context, objectName, contentType string, size int64, expiry time.Duration) (string, map[string]string, error) {
+	headers := map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.FormatInt(size, 10),
+	}
+	signedHeaders := http.Header{}
+	for name, value := range headers {
+		signedHeaders.Set(name, value)
+	}
+
+	presignedURL, err := ms.Client.PresignHeader(ctx, http.MethodPut, ms.BucketName, objectName, expiry, url.Values{}, signedHeaders)
+	if err != nil {
+		return "", nil, err
+	}
+	return presignedURL.String(), headers, nil
+}
+
+// PresignedPostPolicy returns the URL and form fields of a browser POST upload of the object,
+// restricted by policy to the given content type and exact size.
+func (ms *MinioStorage) PresignedPostPolicy(ctx context.Context, objectName, contentType string, size int64, expiry time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(ms.BucketName); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetKey(objectName); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expiry)); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentLengthRange(size, size); err != nil {
+		return "", nil, err
+	}
+
+	presignedURL, formData, err := ms.Client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return "", nil, err
+	}
+	return presignedURL.String(), formData, nil
+}
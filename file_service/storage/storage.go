@@ -27,17 +27,20 @@ import (
 )
 
 // MinioStorage defines a struct for MinIO storage, holding the client and bucket name.
+// Core exposes the low-level multipart API used by resumable uploads.
 type MinioStorage struct {
 	Client     *minio.Client
+	Core       *minio.Core
 	BucketName string
 }
 
-// InitMinio initializes a MinIO client and creates a bucket if it doesn't exist.
-func InitMinio(url, accessKey, secretKey, bucketName string, ctx context.Context) (*MinioStorage, error) {
+// InitMinio initializes a MinIO (or other S3 compatible) client and creates a bucket if it doesn't exist.
+func InitMinio(url, accessKey, secretKey, bucketName string, secure bool, region string, ctx context.Context) (*MinioStorage, error) {
 	// Create a new MinIO client with the provided URL and credentials
 	minioClient, err := minio.New(url, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""), // Use static credentials
-		Secure: false,                                             // Set to true for HTTPS
+		Secure: secure,                                            // Use HTTPS
+		Region: region,
 	})
 	if err != nil {
 		return nil, err
@@ -51,7 +54,7 @@ func InitMinio(url, accessKey, secretKey, bucketName string, ctx context.Context
 
 	// If the bucket does not exist, create it
 	if !existsk {
-		err = minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: "us-east-1"}) // Default region
+		err = minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: region})
 		if err != nil {
 			return nil, err
 		}
@@ -60,6 +63,7 @@ func InitMinio(url, accessKey, secretKey, bucketName string, ctx context.Context
 	// Return a pointer to MinioStorage with the initialized client and bucket name
 	return &MinioStorage{
 		Client:     minioClient,
+		Core:       &minio.Core{Client: minioClient},
 		BucketName: bucketName,
 	}, nil
 }
@@ -21,6 +21,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/url"
 	"time"
@@ -29,24 +30,80 @@ import (
 )
 
 // UploadFile uploads a file to the MinIO storage using the provided context, file data, object name, and file size.
-func (ms MinioStorage) UploadFile(ctx context.Context, fileData io.Reader, objectName string, fileSize int64) error {
+// The content type and user metadata are stored with the object and returned by StatFile.
+func (ms MinioStorage) UploadFile(ctx context.Context, fileData io.Reader, objectName string, fileSize int64, contentType string, metadata map[string]string) error {
 	// PutObject uploads the file to the specified bucket with the provided object name and file data.
-	_, err := ms.Client.PutObject(ctx, ms.BucketName, objectName, fileData, fileSize, minio.PutObjectOptions{})
+	_, err := ms.Client.PutObject(ctx, ms.BucketName, objectName, fileData, fileSize, minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: metadata,
+	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// DownloadFile downloads a file from MinIO storage to the specified destination path.
-func (ms *MinioStorage) DownloadFile(ctx context.Context, objectName string, destPath string) error {
-	// FGetObject retrieves the object from the specified bucket and saves it to the local file system at the destination path.
-	err := ms.Client.FGetObject(ctx, ms.BucketName, objectName, destPath, minio.GetObjectOptions{})
+// StatFile returns the size, content type and user metadata of an object.
+func (ms *MinioStorage) StatFile(ctx context.Context, objectName string) (ObjectInfo, error) {
+	info, err := ms.Client.StatObject(ctx, ms.BucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
-		return err
+		return ObjectInfo{}, minioError(err)
 	}
+	return objectInfo(info), nil
+}
 
-	return nil
+// DownloadFile opens a stream over the bytes start..end (inclusive) of an object.
+// The caller must close the returned reader.
+func (ms *MinioStorage) DownloadFile(ctx context.Context, objectName string, start, end int64) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(start, end); err != nil {
+		return nil, err
+	}
+	object, err := ms.Client.GetObject(ctx, ms.BucketName, objectName, opts)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	// GetObject streams the object lazily; Stat sends the request so a missing object fails here
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, minioError(err)
+	}
+	return object, nil
+}
+
+// OpenFile opens a stream over a whole object and returns its size. The caller must close the returned reader.
+func (ms *MinioStorage) OpenFile(ctx context.Context, objectName string) (io.ReadCloser, int64, error) {
+	object, err := ms.Client.GetObject(ctx, ms.BucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, minioError(err)
+	}
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, 0, minioError(err)
+	}
+	return object, info.Size, nil
+}
+
+// DeleteFile removes an object from the bucket. Removing a missing object is not an error.
+func (ms *MinioStorage) DeleteFile(ctx context.Context, objectName string) error {
+	err := minioError(ms.Client.RemoveObject(ctx, ms.BucketName, objectName, minio.RemoveObjectOptions{}))
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ListFiles lists the objects whose name starts with prefix.
+func (ms *MinioStorage) ListFiles(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for info := range ms.Client.ListObjects(ctx, ms.BucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, objectInfo(info))
+	}
+	return objects, nil
 }
 
 // GeneratePresignedURL generates a presigned URL for accessing the specified object in MinIO for a limited time.
@@ -60,3 +117,28 @@ func (ms *MinioStorage) GeneratePresignedURL(ctx context.Context, objectName str
 
 	return presignedURL.String(), nil
 }
+
+// objectInfo converts the MinIO description of an object
+func objectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Name:         info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		Metadata:     info.UserMetadata,
+		LastModified: info.LastModified,
+	}
+}
+
+// minioError maps the MinIO error codes callers act on to the shared storage errors
+func minioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	case "NoSuchUpload":
+		return fmt.Errorf("%w: %v", ErrNoSuchUpload, err)
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package worker
+
+// Pool runs jobs on a fixed number of goroutines so background processing never competes
+// with uploads for more than that many CPUs
+type Pool[J any] struct {
+	jobs chan J
+}
+
+// NewPool starts workers goroutines calling process for each submitted job. At most queueSize
+// jobs wait for a free worker.
+func NewPool[J any](workers, queueSize int, process func(J)) *Pool[J] {
+	pool := &Pool[J]{jobs: make(chan J, queueSize)}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range pool.jobs {
+				process(job)
+			}
+		}()
+	}
+	return pool
+}
+
+// Submit queues a job without blocking; it reports false when the queue is full
+func (p *Pool[J]) Submit(job J) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"gorm.io/gorm"
+	"urulink.com/message_service/models"
+)
+
+// insertAttachments stores the attachments of a message that was just created in the same transaction
+func insertAttachments(tx *gorm.DB, msg *models.DirectMessage) error {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	for i := range msg.Attachments {
+		msg.Attachments[i].MessageId = msg.Id
+		msg.Attachments[i].Position = i
+	}
+	return tx.Table("message_attachment").Create(&msg.Attachments).Error
+}
+
+// LoadAttachments fills the attachments of the given messages with a single query
+func (data Database) LoadAttachments(messages []models.DirectMessage) error {
+	var ids []int64
+	index := make(map[int64]int, len(messages))
+	for i, msg := range messages {
+		if msg.ContentType == "files" || msg.ContentType == "voice" {
+			ids = append(ids, msg.Id)
+			index[msg.Id] = i
+			messages[i].Attachments = nil
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var attachments []models.Attachment
+	result := data.Db.Table("message_attachment").
+		Raw("SELECT * FROM message_attachment WHERE message_id IN ? ORDER BY message_id, position", ids).Scan(&attachments)
+	if result.Error != nil {
+		return result.Error
+	}
+	for _, attachment := range attachments {
+		i := index[attachment.MessageId]
+		messages[i].Attachments = append(messages[i].Attachments, attachment)
+	}
+	return nil
+}
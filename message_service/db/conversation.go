@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"gorm.io/gorm"
+	"urulink.com/message_service/models"
+)
+
+// previewLength is the maximum number of characters kept as the last message preview
+const previewLength = 100
+
+// upsertConversations records a stored message as the latest message of the conversation
+// for both participants, increasing the unread count of the receiver only.
+func upsertConversations(tx *gorm.DB, msg *models.DirectMessage) error {
+	preview := messagePreview(msg.Content, msg.ContentType)
+
+	query := "INSERT INTO conversation (user_id, peer_id, last_message_id, last_message_preview, last_content_type, " +
+		"last_sender_id, unread_count, muted, archived, pinned, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, false, false, false, ?) " +
+		"ON DUPLICATE KEY UPDATE last_message_id = VALUES(last_message_id), last_message_preview = VALUES(last_message_preview), " +
+		"last_content_type = VALUES(last_content_type), last_sender_id = VALUES(last_sender_id), " +
+		"unread_count = unread_count + VALUES(unread_count), updated_at = VALUES(updated_at)"
+
+	// The sender has obviously read their own message
+	err := tx.Exec(query, msg.SenderID, msg.ReceiverID, msg.Id, preview, msg.ContentType, msg.SenderID, 0, msg.CreatedAt).Error
+	if err != nil || msg.SenderID == msg.ReceiverID {
+		return err
+	}
+
+	return tx.Exec(query, msg.ReceiverID, msg.SenderID, msg.Id, preview, msg.ContentType, msg.SenderID, 1, msg.CreatedAt).Error
+}
+
+// messagePreview shortens message content for the conversation list; file and voice messages
+// are rendered by clients from last_content_type.
+func messagePreview(content, contentType string) string {
+	if contentType == "files" || contentType == "voice" {
+		return ""
+	}
+	runes := []rune(content)
+	if len(runes) > previewLength {
+		return string(runes[:previewLength])
+	}
+	return content
+}
+
+// GetConversations returns the conversations of a user, pinned ones first, then most recently updated.
+// Archived conversations are only included when requested.
+func (data Database) GetConversations(userId string, includeArchived bool) ([]models.Conversation, error) {
+	var conversations []models.Conversation
+	query := "SELECT * FROM conversation WHERE user_id = ?"
+	if !includeArchived {
+		query += " AND archived = false"
+	}
+	query += " ORDER BY pinned DESC, updated_at DESC"
+
+	result := data.Db.Table("conversation").Raw(query, userId).Scan(&conversations)
+	return conversations, result.Error
+}
+
+// MarkConversationRead resets the unread count of the user's conversation with a peer.
+func (data Database) MarkConversationRead(userId, peerId string) error {
+	return data.Db.Exec("UPDATE conversation SET unread_count = 0 WHERE user_id = ? AND peer_id = ?", userId, peerId).Error
+}
+
+// UpdateConversationSettings applies the mute, archive and pin flags present in the input.
+// It returns gorm.ErrRecordNotFound when the user has no conversation with the peer.
+func (data Database) UpdateConversationSettings(userId, peerId string, settings models.ConversationSettingsInput) error {
+	updates := map[string]interface{}{}
+	if settings.Muted != nil {
+		updates["muted"] = *settings.Muted
+	}
+	if settings.Archived != nil {
+		updates["archived"] = *settings.Archived
+	}
+	if settings.Pinned != nil {
+		updates["pinned"] = *settings.Pinned
+	}
+
+	// RowsAffected only counts changed rows, so muting an already muted conversation
+	// affects none; look the conversation up first instead
+	var count int64
+	err := data.Db.Table("conversation").Where("user_id = ? AND peer_id = ?", userId, peerId).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	return data.Db.Table("conversation").Where("user_id = ? AND peer_id = ?", userId, peerId).Updates(updates).Error
+}
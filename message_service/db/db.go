@@ -20,12 +20,14 @@
 package db
 
 import (
+	"errors"
 	"log"
 	"time"
 
-	"gorm.io/driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	gormmysql "gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"urulink.go/message_service/models"
+	"urulink.com/message_service/models"
 )
 
 // Database struct holds the GORM DB connection instance
@@ -37,7 +39,7 @@ type Database struct {
 // Takes a DSN (Data Source Name) for MySQL, returns a Database instance or an error
 func UruLinkInit(dsn string) (*Database, error) {
 	// Open a new MySQL connection using the provided DSN
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(gormmysql.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -61,39 +63,113 @@ func UruLinkInit(dsn string) (*Database, error) {
 	}, nil
 }
 
-// GetMessageByReceiverId retrieves all messages between the given sender and receiver IDs
+// GetMessageByReceiverId retrieves the main conversation between the given sender and receiver IDs
+// Thread replies are left out and thread roots carry their reply count
 // Orders messages by creation time in ascending order, returns a list of DirectMessage models
 func (data Database) GetMessageByReceiverId(senderId, receiverId string) ([]models.DirectMessage, error) {
 	var messages []models.DirectMessage
 	// Query the database for messages between sender and receiver
 	results := data.Db.Table("direct_message").
-		Raw("SELECT * FROM direct_message WHERE (sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?) ORDER BY created_at ASC",
+		Raw("SELECT m.*, COALESCE(t.reply_count, 0) AS reply_count FROM direct_message m LEFT JOIN thread t ON t.root_id = m.id "+
+			"WHERE ((m.sender_id = ? AND m.receiver_id = ?) OR (m.sender_id = ? AND m.receiver_id = ?)) AND m.thread_id = 0 ORDER BY m.created_at ASC",
 			senderId, receiverId, receiverId, senderId).Scan(&messages)
+	if results.Error != nil {
+		return nil, results.Error
+	}
+	return messages, data.LoadAttachments(messages)
+}
+
+// GetRecentMessages retrieves the limit most recently stored messages, oldest first
+func (data Database) GetRecentMessages(limit int) ([]models.DirectMessage, error) {
+	var messages []models.DirectMessage
+	results := data.Db.Table("direct_message").
+		Raw("SELECT * FROM (SELECT * FROM direct_message ORDER BY id DESC LIMIT ?) AS m ORDER BY id ASC", limit).Scan(&messages)
 	return messages, results.Error
 }
 
+// maxDeadlockRetries is how many times CreateNewMsg retries a transaction chosen as a deadlock victim
+const maxDeadlockRetries = 3
+
 // CreateNewMsg creates a new message entry in the database within a transaction
+// Assigns the next sender and receiver sequence numbers before inserting, so both
+// users observe the message at a monotonic position in their own sequence.
+// A transaction rolled back by MySQL deadlock detection is retried, so the message is not lost
+func (data Database) CreateNewMsg(msg *models.DirectMessage) error {
+	var err error
+	for attempt := 0; attempt <= maxDeadlockRetries; attempt++ {
+		if err = data.createNewMsg(msg); !isDeadlock(err) {
+			return err
+		}
+		log.Println("Retrying message creation after deadlock:", err)
+		// The failed attempt may already have been assigned an id by the insert
+		msg.Id = 0
+	}
+	return err
+}
+
+// createNewMsg stores the message, its attachments and the thread or conversation updates in one transaction
 // Rolls back the transaction if an error occurs or if a panic is recovered
-func (data Database) CreateNewMsg(msg models.DirectMessage) error {
+func (data Database) createNewMsg(msg *models.DirectMessage) error {
 	// Begin a new transaction
 	tx := data.Db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			// Log the panic and rollback the transaction if panic occurs
-			log.Println("Recovered in CreateNewUser:", r)
+			log.Println("Recovered in CreateNewMsg:", r)
 			tx.Rollback()
 		}
 	}()
 
+	// Reserve the next sequence number for the sender and the receiver.
+	// The counters are locked in user id order, so concurrent A->B and B->A
+	// sends take the row locks in the same order and cannot deadlock each other.
+	first, second := &msg.SenderSeq, &msg.ReceiverSeq
+	firstId, secondId := msg.SenderID, msg.ReceiverID
+	if secondId < firstId {
+		first, second = second, first
+		firstId, secondId = secondId, firstId
+	}
+	var err error
+	if *first, err = nextUserSeq(tx, firstId); err != nil {
+		log.Println("Error reserving sequence:", err)
+		tx.Rollback()
+		return err
+	}
+	if *second, err = nextUserSeq(tx, secondId); err != nil {
+		log.Println("Error reserving sequence:", err)
+		tx.Rollback()
+		return err
+	}
+
 	// Attempt to create a new record in the 'direct_message' table
-	result := tx.Table("direct_message").Create(&msg)
+	result := tx.Table("direct_message").Create(msg)
 	if result.Error != nil {
 		// Log and rollback the transaction if an error occurs
-		log.Println("Error creating user:", result.Error)
+		log.Println("Error creating message:", result.Error)
 		tx.Rollback()
 		return result.Error
 	}
 
+	// Attachments are stored with the message so a file message is never visible without its files
+	if err := insertAttachments(tx, msg); err != nil {
+		log.Println("Error storing attachments:", err)
+		tx.Rollback()
+		return err
+	}
+
+	// Thread replies update the thread; other messages keep the conversation list of both participants up to date
+	if msg.ThreadId != 0 {
+		if err := upsertThread(tx, msg); err != nil {
+			log.Println("Error updating thread:", err)
+			tx.Rollback()
+			return err
+		}
+	} else if err := upsertConversations(tx, msg); err != nil {
+		log.Println("Error updating conversations:", err)
+		tx.Rollback()
+		return err
+	}
+
 	// Commit the transaction if no errors occur during message creation
 	if err := tx.Commit().Error; err != nil {
 		// Log and rollback the transaction if commit fails
@@ -105,3 +181,23 @@ func (data Database) CreateNewMsg(msg models.DirectMessage) error {
 	// Return nil if the message was successfully created and transaction committed
 	return nil
 }
+
+// nextUserSeq increments and returns the sequence counter of a user inside the given transaction.
+// The row is created on first use; LAST_INSERT_ID(expr) makes the new value readable on the same connection.
+func nextUserSeq(tx *gorm.DB, userId string) (int64, error) {
+	err := tx.Exec("INSERT INTO user_sequence (user_id, last_seq) VALUES (?, LAST_INSERT_ID(1)) "+
+		"ON DUPLICATE KEY UPDATE last_seq = LAST_INSERT_ID(last_seq + 1)", userId).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	err = tx.Raw("SELECT LAST_INSERT_ID()").Scan(&seq).Error
+	return seq, err
+}
+
+// isDeadlock reports whether err is the MySQL error returned to the victim of a deadlock
+func isDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1213
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+// HasFileAccess reports whether the user sent or received a message carrying the given file
+// as an attachment. Attachments are keyed by the file ID of the file service; the legacy
+// file_path column holds the URLs returned by old uploads and never matches a file ID.
+func (data Database) HasFileAccess(userId, fileId string) (bool, error) {
+	var count int64
+	result := data.Db.Table("message_attachment").
+		Raw("SELECT COUNT(*) FROM message_attachment a JOIN direct_message m ON m.id = a.message_id "+
+			"WHERE a.file_id = ? AND (m.sender_id = ? OR m.receiver_id = ?)", fileId, userId, userId).
+		Scan(&count)
+	return count > 0, result.Error
+}
+
+// GetReferencedFiles returns which of the given files are attached to a stored message
+func (data Database) GetReferencedFiles(fileIds []string) ([]string, error) {
+	var referenced []string
+	result := data.Db.Raw("SELECT DISTINCT file_id FROM message_attachment WHERE file_id IN ?", fileIds).Scan(&referenced)
+	return referenced, result.Error
+}
@@ -0,0 +1,47 @@
+-- Adds per-user message sequences to a direct_message table created before they existed.
+-- schema.sql only creates missing tables, so existing databases run this once before
+-- the new message service is started. Existing messages are numbered in the order they
+-- were stored, and user_sequence continues from the highest number of every user.
+
+ALTER TABLE direct_message
+    ADD COLUMN sender_seq   BIGINT NOT NULL DEFAULT 0 AFTER status,
+    ADD COLUMN receiver_seq BIGINT NOT NULL DEFAULT 0 AFTER sender_seq;
+
+CREATE TABLE IF NOT EXISTS user_sequence (
+    user_id  VARCHAR(64) PRIMARY KEY,
+    last_seq BIGINT NOT NULL
+);
+
+-- Every message takes one position in the sequence of its sender (side 0) and one in the
+-- sequence of its receiver (side 1); a message sent to oneself takes two, like new messages.
+CREATE TEMPORARY TABLE message_seq_backfill AS
+SELECT id, side, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at, id, side) AS seq
+FROM (
+    SELECT id, sender_id AS user_id, created_at, 0 AS side FROM direct_message
+    UNION ALL
+    SELECT id, receiver_id AS user_id, created_at, 1 AS side FROM direct_message
+) AS positions;
+
+UPDATE direct_message m JOIN message_seq_backfill s ON s.id = m.id AND s.side = 0
+SET m.sender_seq = s.seq;
+
+UPDATE direct_message m JOIN message_seq_backfill s ON s.id = m.id AND s.side = 1
+SET m.receiver_seq = s.seq;
+
+DROP TEMPORARY TABLE message_seq_backfill;
+
+INSERT INTO user_sequence (user_id, last_seq)
+SELECT user_id, COUNT(*)
+FROM (
+    SELECT sender_id AS user_id FROM direct_message
+    UNION ALL
+    SELECT receiver_id AS user_id FROM direct_message
+) AS positions
+GROUP BY user_id
+ON DUPLICATE KEY UPDATE last_seq = GREATEST(user_sequence.last_seq, VALUES(last_seq));
+
+ALTER TABLE direct_message
+    ALTER COLUMN sender_seq DROP DEFAULT,
+    ALTER COLUMN receiver_seq DROP DEFAULT,
+    ADD INDEX idx_direct_message_sender_seq (sender_id, sender_seq),
+    ADD INDEX idx_direct_message_receiver_seq (receiver_id, receiver_seq);
@@ -0,0 +1,7 @@
+-- Adds replies and threads to a direct_message table created before they existed.
+-- Existing messages are neither replies nor thread replies, so the defaults are kept.
+
+ALTER TABLE direct_message
+    ADD COLUMN reply_to  BIGINT NOT NULL DEFAULT 0 AFTER receiver_seq,
+    ADD COLUMN thread_id BIGINT NOT NULL DEFAULT 0 AFTER reply_to,
+    ADD INDEX idx_direct_message_thread (thread_id, id);
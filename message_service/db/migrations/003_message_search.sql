@@ -0,0 +1,6 @@
+-- Adds the FULLTEXT index used by the mysql search backend to an existing direct_message
+-- table. MySQL builds the index from every stored message, which can take a while on large
+-- tables; until it exists, searches fail with error 1191.
+
+ALTER TABLE direct_message
+    ADD FULLTEXT INDEX ft_direct_message_content (content);
@@ -0,0 +1,46 @@
+-- Fills the conversation list from the messages stored before it existed. The message
+-- service only updates conversations when a message is stored, so without this script
+-- existing users see the conversations of new messages only. Run it after
+-- 002_message_threads.sql; conversations already recorded by the new service are kept.
+--
+-- Messages have no read state, so the unread count of a conversation is the number of
+-- messages received from the peer after the user last wrote to them.
+
+CREATE TABLE IF NOT EXISTS conversation (
+    user_id              VARCHAR(64)  NOT NULL,
+    peer_id              VARCHAR(64)  NOT NULL,
+    last_message_id      BIGINT       NOT NULL,
+    last_message_preview VARCHAR(400) NOT NULL DEFAULT '',
+    last_content_type    VARCHAR(32)  NOT NULL,
+    last_sender_id       VARCHAR(64)  NOT NULL,
+    unread_count         INT          NOT NULL DEFAULT 0,
+    muted                BOOLEAN      NOT NULL DEFAULT false,
+    archived             BOOLEAN      NOT NULL DEFAULT false,
+    pinned               BOOLEAN      NOT NULL DEFAULT false,
+    updated_at           BIGINT       NOT NULL,
+    PRIMARY KEY (user_id, peer_id),
+    INDEX idx_conversation_list (user_id, archived, pinned, updated_at)
+);
+
+-- Every message outside of a thread belongs to the conversation of its sender (sent = 1)
+-- and to the one of its receiver (sent = 0), like new messages.
+INSERT INTO conversation (user_id, peer_id, last_message_id, last_message_preview, last_content_type,
+                          last_sender_id, unread_count, muted, archived, pinned, updated_at)
+SELECT c.user_id, c.peer_id, m.id,
+       CASE WHEN m.content_type IN ('files', 'voice') THEN '' ELSE LEFT(m.content, 100) END,
+       m.content_type, m.sender_id,
+       (SELECT COUNT(*) FROM direct_message r
+        WHERE r.sender_id = c.peer_id AND r.receiver_id = c.user_id AND r.thread_id = 0
+          AND r.id > c.last_sent_id AND c.user_id <> c.peer_id),
+       false, false, false, m.created_at
+FROM (
+    SELECT user_id, peer_id, MAX(id) AS last_id, MAX(CASE WHEN sent = 1 THEN id ELSE 0 END) AS last_sent_id
+    FROM (
+        SELECT sender_id AS user_id, receiver_id AS peer_id, id, 1 AS sent FROM direct_message WHERE thread_id = 0
+        UNION ALL
+        SELECT receiver_id AS user_id, sender_id AS peer_id, id, 0 AS sent FROM direct_message WHERE thread_id = 0
+    ) AS sides
+    GROUP BY user_id, peer_id
+) AS c
+JOIN direct_message m ON m.id = c.last_id
+ON DUPLICATE KEY UPDATE conversation.user_id = conversation.user_id;
@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"time"
+
+	"urulink.com/message_service/models"
+)
+
+// AddReaction stores a reaction of a user on a message. Reactions are unique per
+// (message, user, emoji); added is false when the reaction already existed.
+func (data Database) AddReaction(messageId int64, userId, emoji string) (bool, error) {
+	result := data.Db.Exec("INSERT IGNORE INTO message_reaction (message_id, user_id, emoji, created_at) VALUES (?, ?, ?, ?)",
+		messageId, userId, emoji, time.Now().Unix())
+	return result.RowsAffected > 0, result.Error
+}
+
+// RemoveReaction deletes a reaction of a user on a message; removed is false when there was none.
+func (data Database) RemoveReaction(messageId int64, userId, emoji string) (bool, error) {
+	result := data.Db.Exec("DELETE FROM message_reaction WHERE message_id = ? AND user_id = ? AND emoji = ?",
+		messageId, userId, emoji)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetReactions returns the reactions of a message grouped by emoji, in order of first use,
+// with the reacting users of each emoji in the order they reacted.
+func (data Database) GetReactions(messageId int64) ([]models.ReactionSummary, error) {
+	var rows []struct {
+		UserId string
+		Emoji  string
+	}
+	result := data.Db.Table("message_reaction").
+		Raw("SELECT user_id, emoji FROM message_reaction WHERE message_id = ? ORDER BY created_at ASC, user_id ASC", messageId).Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	reactions := []models.ReactionSummary{}
+	indexByEmoji := map[string]int{}
+	for _, row := range rows {
+		index, ok := indexByEmoji[row.Emoji]
+		if !ok {
+			index = len(reactions)
+			indexByEmoji[row.Emoji] = index
+			reactions = append(reactions, models.ReactionSummary{Emoji: row.Emoji, Users: []string{}})
+		}
+		reactions[index].Count++
+		reactions[index].Users = append(reactions[index].Users, row.UserId)
+	}
+	return reactions, nil
+}
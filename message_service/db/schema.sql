@@ -0,0 +1,96 @@
+-- Schema of the tables owned by message_service (MySQL).
+
+CREATE TABLE IF NOT EXISTS direct_message (
+    id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+    sender_id    VARCHAR(64)  NOT NULL,
+    receiver_id  VARCHAR(64)  NOT NULL,
+    content      TEXT         NOT NULL,
+    content_type VARCHAR(32)  NOT NULL,
+    file_path    VARCHAR(1024) NOT NULL DEFAULT '',
+    status       INT          NOT NULL,
+    sender_seq   BIGINT       NOT NULL,
+    receiver_seq BIGINT       NOT NULL,
+    reply_to     BIGINT       NOT NULL DEFAULT 0,
+    thread_id    BIGINT       NOT NULL DEFAULT 0,
+    created_at   BIGINT       NOT NULL,
+    INDEX idx_direct_message_pair (sender_id, receiver_id, created_at),
+    INDEX idx_direct_message_sender_seq (sender_id, sender_seq),
+    INDEX idx_direct_message_receiver_seq (receiver_id, receiver_seq),
+    INDEX idx_direct_message_thread (thread_id, id),
+    FULLTEXT INDEX ft_direct_message_content (content)
+);
+
+-- Per-user monotonic counter used to assign sender_seq/receiver_seq.
+CREATE TABLE IF NOT EXISTS user_sequence (
+    user_id  VARCHAR(64) PRIMARY KEY,
+    last_seq BIGINT NOT NULL
+);
+
+-- Last sequence number seen by each device of a user.
+CREATE TABLE IF NOT EXISTS sync_cursor (
+    user_id    VARCHAR(64) NOT NULL,
+    device_id  VARCHAR(128) NOT NULL,
+    last_seq   BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY (user_id, device_id)
+);
+
+-- Conversation list of every user, updated on each stored message.
+CREATE TABLE IF NOT EXISTS conversation (
+    user_id              VARCHAR(64)  NOT NULL,
+    peer_id              VARCHAR(64)  NOT NULL,
+    last_message_id      BIGINT       NOT NULL,
+    last_message_preview VARCHAR(400) NOT NULL DEFAULT '',
+    last_content_type    VARCHAR(32)  NOT NULL,
+    last_sender_id       VARCHAR(64)  NOT NULL,
+    unread_count         INT          NOT NULL DEFAULT 0,
+    muted                BOOLEAN      NOT NULL DEFAULT false,
+    archived             BOOLEAN      NOT NULL DEFAULT false,
+    pinned               BOOLEAN      NOT NULL DEFAULT false,
+    updated_at           BIGINT       NOT NULL,
+    PRIMARY KEY (user_id, peer_id),
+    INDEX idx_conversation_list (user_id, archived, pinned, updated_at)
+);
+
+-- Reply counters of threads, keyed by the id of the root message.
+CREATE TABLE IF NOT EXISTS thread (
+    root_id       BIGINT PRIMARY KEY,
+    reply_count   INT    NOT NULL,
+    last_reply_id BIGINT NOT NULL,
+    last_reply_at BIGINT NOT NULL
+);
+
+-- Last thread reply read by each user.
+CREATE TABLE IF NOT EXISTS thread_read (
+    root_id      BIGINT      NOT NULL,
+    user_id      VARCHAR(64) NOT NULL,
+    last_read_id BIGINT      NOT NULL,
+    PRIMARY KEY (root_id, user_id)
+);
+
+-- Emoji reactions, one row per (message, user, emoji).
+CREATE TABLE IF NOT EXISTS message_reaction (
+    message_id BIGINT       NOT NULL,
+    user_id    VARCHAR(64)  NOT NULL,
+    emoji      VARCHAR(64)  NOT NULL,
+    created_at BIGINT       NOT NULL,
+    PRIMARY KEY (message_id, user_id, emoji)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- Files attached to a message; metadata is copied from the file service when the message is sent.
+CREATE TABLE IF NOT EXISTS message_attachment (
+    message_id    BIGINT        NOT NULL,
+    position      INT           NOT NULL,
+    file_id       VARCHAR(64)   NOT NULL,
+    name          VARCHAR(255)  NOT NULL,
+    size          BIGINT        NOT NULL,
+    mime_type     VARCHAR(128)  NOT NULL,
+    width         INT           NOT NULL DEFAULT 0,
+    height        INT           NOT NULL DEFAULT 0,
+    blur_hash     VARCHAR(128)  NOT NULL DEFAULT '',
+    has_thumbnail BOOLEAN       NOT NULL DEFAULT false,
+    duration_ms   BIGINT        NOT NULL DEFAULT 0,
+    waveform      VARBINARY(64),
+    PRIMARY KEY (message_id, position),
+    INDEX idx_message_attachment_file (file_id)
+);
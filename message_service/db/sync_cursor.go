@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"time"
+
+	"urulink.com/message_service/models"
+)
+
+// GetMessagesSince retrieves up to limit messages sent or received by the user whose
+// position in the user's sequence is greater than lastSeq, ordered by that position.
+// Received and sent messages are read by two range scans of the seq indexes, so only the
+// rows of one batch are sorted. A message sent to oneself is read once, at its receiver_seq.
+func (data Database) GetMessagesSince(userId string, lastSeq int64, limit int) ([]models.DirectMessage, error) {
+	var messages []models.DirectMessage
+	results := data.Db.Table("direct_message").
+		Raw("SELECT * FROM ("+
+			"(SELECT * FROM direct_message WHERE receiver_id = ? AND receiver_seq > ? ORDER BY receiver_seq LIMIT ?) "+
+			"UNION ALL "+
+			"(SELECT * FROM direct_message WHERE sender_id = ? AND sender_seq > ? AND receiver_id <> ? ORDER BY sender_seq LIMIT ?)"+
+			") AS m ORDER BY CASE WHEN receiver_id = ? THEN receiver_seq ELSE sender_seq END ASC LIMIT ?",
+			userId, lastSeq, limit, userId, lastSeq, userId, limit, userId, limit).Scan(&messages)
+	if results.Error != nil {
+		return nil, results.Error
+	}
+	return messages, data.LoadAttachments(messages)
+}
+
+// GetSyncCursor returns the last sequence number acknowledged by a device, or 0 if the device never synced.
+func (data Database) GetSyncCursor(userId, deviceId string) (int64, error) {
+	var lastSeq int64
+	result := data.Db.Table("sync_cursor").
+		Raw("SELECT last_seq FROM sync_cursor WHERE user_id = ? AND device_id = ?", userId, deviceId).Scan(&lastSeq)
+	return lastSeq, result.Error
+}
+
+// UpdateSyncCursor stores the last sequence number seen by a device.
+// The cursor only moves forward, so late or duplicated acknowledgements are harmless.
+func (data Database) UpdateSyncCursor(userId, deviceId string, lastSeq int64) error {
+	return data.Db.Exec("INSERT INTO sync_cursor (user_id, device_id, last_seq, updated_at) VALUES (?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE last_seq = GREATEST(last_seq, VALUES(last_seq)), updated_at = VALUES(updated_at)",
+		userId, deviceId, lastSeq, time.Now().Unix()).Error
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package db
+
+import (
+	"gorm.io/gorm"
+	"urulink.com/message_service/models"
+)
+
+// GetMessageById retrieves a single message; the returned message has Id 0 when it does not exist.
+func (data Database) GetMessageById(id int64) (models.DirectMessage, error) {
+	var message models.DirectMessage
+	result := data.Db.Table("direct_message").Raw("SELECT * FROM direct_message WHERE id = ?", id).Scan(&message)
+	if result.Error != nil || message.Id == 0 {
+		return message, result.Error
+	}
+
+	messages := []models.DirectMessage{message}
+	err := data.LoadAttachments(messages)
+	return messages[0], err
+}
+
+// upsertThread counts a stored reply in its thread and marks the thread as read for the author of the reply.
+func upsertThread(tx *gorm.DB, msg *models.DirectMessage) error {
+	err := tx.Exec("INSERT INTO thread (root_id, reply_count, last_reply_id, last_reply_at) VALUES (?, 1, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE reply_count = reply_count + 1, last_reply_id = VALUES(last_reply_id), last_reply_at = VALUES(last_reply_at)",
+		msg.ThreadId, msg.Id, msg.CreatedAt).Error
+	if err != nil {
+		return err
+	}
+	return markThreadRead(tx, msg.ThreadId, msg.SenderID, msg.Id)
+}
+
+// markThreadRead moves the read position of a user in a thread forward to lastReadId.
+func markThreadRead(tx *gorm.DB, rootId int64, userId string, lastReadId int64) error {
+	return tx.Exec("INSERT INTO thread_read (root_id, user_id, last_read_id) VALUES (?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE last_read_id = GREATEST(last_read_id, VALUES(last_read_id))",
+		rootId, userId, lastReadId).Error
+}
+
+// GetThreadSummary returns the counters, participants and unread state of a thread for a user.
+func (data Database) GetThreadSummary(rootId int64, userId string) (models.ThreadSummary, error) {
+	summary := models.ThreadSummary{RootId: rootId}
+	result := data.Db.Table("thread").
+		Raw("SELECT root_id, reply_count, last_reply_id, last_reply_at FROM thread WHERE root_id = ?", rootId).Scan(&summary)
+	if result.Error != nil {
+		return summary, result.Error
+	}
+	summary.RootId = rootId
+
+	// Participants are the author of the root plus everyone who replied
+	result = data.Db.Table("direct_message").
+		Raw("SELECT DISTINCT sender_id FROM direct_message WHERE id = ? OR thread_id = ?", rootId, rootId).Scan(&summary.Participants)
+	if result.Error != nil {
+		return summary, result.Error
+	}
+
+	result = data.Db.Table("direct_message").
+		Raw("SELECT COUNT(*) FROM direct_message WHERE thread_id = ? AND sender_id <> ? "+
+			"AND id > COALESCE((SELECT last_read_id FROM thread_read WHERE root_id = ? AND user_id = ?), 0)",
+			rootId, userId, rootId, userId).Scan(&summary.UnreadCount)
+	return summary, result.Error
+}
+
+// GetThreadMessages returns up to limit replies of a thread older than beforeId (0 for the newest),
+// in ascending order so a page can be appended as is.
+func (data Database) GetThreadMessages(rootId, beforeId int64, limit int) ([]models.DirectMessage, error) {
+	var messages []models.DirectMessage
+	query := "SELECT * FROM direct_message WHERE thread_id = ?"
+	args := []interface{}{rootId}
+	if beforeId != 0 {
+		query += " AND id < ?"
+		args = append(args, beforeId)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	result := data.Db.Table("direct_message").Raw(query, args...).Scan(&messages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// Reverse the newest-first page into chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, data.LoadAttachments(messages)
+}
+
+// MarkThreadRead marks every reply of a thread as read for the user.
+func (data Database) MarkThreadRead(rootId int64, userId string) error {
+	var lastReplyId int64
+	result := data.Db.Table("thread").Raw("SELECT last_reply_id FROM thread WHERE root_id = ?", rootId).Scan(&lastReplyId)
+	if result.Error != nil || lastReplyId == 0 {
+		return result.Error
+	}
+	return markThreadRead(data.Db, rootId, userId, lastReplyId)
+}
@@ -20,12 +20,12 @@
 package env
 
 import (
-	"log"
-	"os"
+	"urulink.com/platform/config"
 )
 
 // EnvManager struct holds configuration values loaded from environment variables
 type EnvManger struct {
+	AuthServiceUrl       string
 	FilesServiceUrl      string
 	RabbitMQHost         string
 	RabbitMQUser         string
@@ -41,6 +41,9 @@ type EnvManger struct {
 	RedisHost            string
 	RedisPort            string
 	RedisPassword        string
+	SearchBackend        string // Full-text search backend: "mysql" (default) or "memory" (recent messages only, for development)
+	CustomEmoji          string // Comma-separated workspace custom emoji shortcodes, e.g. ":party_parrot:"
+	ServiceToken         string // Secret the file service presents on internal calls; they are refused when empty
 }
 
 // NewEnv initializes a new EnvManager instance, loading environment variables
@@ -48,41 +51,34 @@ type EnvManger struct {
 func NewEnv() *EnvManger {
 	env := &EnvManger{}
 
-	// Helper function to load a single environment variable by name
-	// Takes the variable name and a pointer to the target field in EnvManager
-	loadEnv := func(envVar string, target *string) {
-		// Lookup the environment variable value
-		value, ok := os.LookupEnv(envVar)
-		// Log a fatal error if the environment variable is not found
-		if !ok {
-			log.Fatalf("Environment variable %s not found", envVar)
-		}
-		// Set the target field in EnvManager to the variable's value
-		*target = value
-	}
-
 	// Load RabbitMQ configuration values
-	loadEnv("RABBITMQ_HOST", &env.RabbitMQHost)
-	loadEnv("RABBITMQ_USER", &env.RabbitMQUser)
-	loadEnv("RABBITMQ_PASSWORD", &env.RabbitMQPassword)
-	loadEnv("RABBITMQ_PORT", &env.RabbitMQPort)
-	loadEnv("RABBITMQ_EXCHANGE_NAME", &env.RabbitMQExchangeName)
-	loadEnv("RABBITMQ_QUEUE_NAME", &env.RabbitMQQueueName)
+	config.Required("RABBITMQ_HOST", &env.RabbitMQHost)
+	config.Required("RABBITMQ_USER", &env.RabbitMQUser)
+	config.Required("RABBITMQ_PASSWORD", &env.RabbitMQPassword)
+	config.Required("RABBITMQ_PORT", &env.RabbitMQPort)
+	config.Required("RABBITMQ_EXCHANGE_NAME", &env.RabbitMQExchangeName)
+	config.Required("RABBITMQ_QUEUE_NAME", &env.RabbitMQQueueName)
 
-	// Load Files Service URL
-	loadEnv("URULINK_FILES_SERVICE", &env.DBHost)
+	// Load the URLs of the other services
+	config.Required("URULINK_AUTH_SERVICE", &env.AuthServiceUrl)
+	config.Required("URULINK_FILES_SERVICE", &env.FilesServiceUrl)
 
 	// Load Database configuration values
-	loadEnv("DB_HOST", &env.DBHost)
-	loadEnv("DB_USER", &env.DBUser)
-	loadEnv("DB_PASSWORD", &env.DBPassword)
-	loadEnv("DB_NAME", &env.DBName)
-	loadEnv("DB_PORT", &env.DBPort)
+	config.Required("DB_HOST", &env.DBHost)
+	config.Required("DB_USER", &env.DBUser)
+	config.Required("DB_PASSWORD", &env.DBPassword)
+	config.Required("DB_NAME", &env.DBName)
+	config.Required("DB_PORT", &env.DBPort)
 
 	// Load Redis configuration values
-	loadEnv("REDIS_HOST", &env.RedisHost)
-	loadEnv("REDIS_PASSWORD", &env.RedisPassword)
-	loadEnv("REDIS_PORT", &env.RedisPort)
+	config.Required("REDIS_HOST", &env.RedisHost)
+	config.Required("REDIS_PASSWORD", &env.RedisPassword)
+	config.Required("REDIS_PORT", &env.RedisPort)
+
+	// Load optional feature configuration values
+	config.Optional("SEARCH_BACKEND", &env.SearchBackend, "mysql")
+	config.Optional("CUSTOM_EMOJI", &env.CustomEmoji, "")
+	config.Optional("SERVICE_TOKEN", &env.ServiceToken, "")
 
 	// Return the populated EnvManager instance
 	return env
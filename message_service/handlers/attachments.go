@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"urulink.com/message_service/models"
+	"urulink.com/platform/httpclient"
+)
+
+// maxAttachments is the maximum number of files a single message can carry
+const maxAttachments = 10
+
+// resolveAttachments checks that every file of a "files" or "voice" message was uploaded by the sender
+// and was not rejected by the malware scan, and returns the attachments describing them in the given order.
+// Files still being scanned are accepted; the file service refuses to serve them until they are clean.
+func (h Handler) resolveAttachments(msgInput models.DirectMessageInput, userId, accessToken string) ([]models.Attachment, error) {
+	switch msgInput.ContentType {
+	case "files":
+		if len(msgInput.FileIds) == 0 {
+			return nil, errors.New("files messages require at least one file id")
+		}
+		if len(msgInput.FileIds) > maxAttachments {
+			return nil, fmt.Errorf("a message can carry at most %d files", maxAttachments)
+		}
+	case "voice":
+		// A voice note is a single recording, played back inline by clients
+		if len(msgInput.FileIds) != 1 {
+			return nil, errors.New("voice messages require exactly one file id")
+		}
+	default:
+		if len(msgInput.FileIds) > 0 {
+			return nil, errors.New("file_ids are only allowed in files and voice messages")
+		}
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(msgInput.FileIds))
+	attachments := make([]models.Attachment, 0, len(msgInput.FileIds))
+	for _, fileId := range msgInput.FileIds {
+		if fileId == "" || seen[fileId] {
+			return nil, errors.New("file ids must be unique and not empty")
+		}
+		seen[fileId] = true
+
+		file, err := h.fileMetadata(fileId, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		if file.OwnerId != userId {
+			return nil, fmt.Errorf("file %s was not uploaded by the sender", fileId)
+		}
+		if file.ScanStatus == "infected" || file.ScanStatus == "failed" {
+			return nil, fmt.Errorf("file %s was rejected by the malware scan", fileId)
+		}
+		if msgInput.ContentType == "voice" && !strings.HasPrefix(file.MimeType, "audio/") {
+			return nil, fmt.Errorf("file %s is not an audio file", fileId)
+		}
+
+		attachments = append(attachments, models.Attachment{
+			FileId:       file.Id,
+			Name:         file.OriginalName,
+			Size:         file.Size,
+			MimeType:     file.MimeType,
+			Width:        file.Width,
+			Height:       file.Height,
+			BlurHash:     file.BlurHash,
+			HasThumbnail: file.PreviewStatus != "" && file.PreviewStatus != "failed",
+			DurationMs:   file.DurationMs,
+			Waveform:     file.Waveform,
+		})
+	}
+	return attachments, nil
+}
+
+// fileMetadata asks the file service for the metadata of a file on behalf of the user owning accessToken
+func (h Handler) fileMetadata(fileId, accessToken string) (models.FileMetadata, error) {
+	statusCode, body, err := httpclient.Call(fiber.MethodGet, h.EnvManger.FilesServiceUrl+"/files/"+url.PathEscape(fileId)+"/metadata", accessToken, nil)
+	if err != nil {
+		return models.FileMetadata{}, err
+	}
+	switch statusCode {
+	case 200:
+		return httpclient.Decode[models.FileMetadata](body)
+	case 400, 403, 404:
+		return models.FileMetadata{}, fmt.Errorf("file %s not found", fileId)
+	}
+	return models.FileMetadata{}, fmt.Errorf("unexpected status code %d from file service", statusCode)
+}
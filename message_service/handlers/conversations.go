@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"urulink.com/message_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// GetConversations returns the conversation list of the authenticated user.
+// Archived conversations are included with ?archived=true.
+func (h Handler) GetConversations(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	conversations, err := h.Database.GetConversations(userJwtInfo.Uid, c.QueryBool("archived"))
+	if err != nil {
+		logs.ConnError(nil, "Failed to retrieve conversations", err)
+		return response.HandleError(c, 500, "failed to retrieve conversations")
+	}
+	if conversations == nil {
+		conversations = []models.Conversation{}
+	}
+	return response.HandleInformation(c, 200, conversations)
+}
+
+// MarkConversationRead resets the unread count of the conversation with :peer_id.
+func (h Handler) MarkConversationRead(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	if err := h.Database.MarkConversationRead(userJwtInfo.Uid, c.Params("peer_id")); err != nil {
+		logs.ConnError(nil, "Failed to mark conversation as read", err)
+		return response.HandleError(c, 500, "failed to mark conversation as read")
+	}
+	return c.SendStatus(200)
+}
+
+// UpdateConversationSettings mutes, archives or pins the conversation with :peer_id.
+func (h Handler) UpdateConversationSettings(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	var settings models.ConversationSettingsInput
+	if err := c.BodyParser(&settings); err != nil {
+		return response.HandleError(c, 400, "invalid request body")
+	}
+
+	err := h.Database.UpdateConversationSettings(userJwtInfo.Uid, c.Params("peer_id"), settings)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.HandleError(c, 404, "conversation not found")
+	}
+	if err != nil {
+		logs.ConnError(nil, "Failed to update conversation settings", err)
+		return response.HandleError(c, 500, "failed to update conversation settings")
+	}
+	return c.SendStatus(200)
+}
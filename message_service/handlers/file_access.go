@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/message_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// CheckFileAccess answers 200 when the authenticated user exchanged the file :file_id in one of
+// their conversations and 403 otherwise. The file service calls it before serving downloads.
+func (h Handler) CheckFileAccess(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	allowed, err := h.Database.HasFileAccess(userJwtInfo.Uid, c.Params("file_id"))
+	if err != nil {
+		logs.ConnError(nil, "Failed to check file access", err)
+		return response.HandleError(c, 500, "failed to check file access")
+	}
+	if !allowed {
+		return response.HandleError(c, 403, "access denied")
+	}
+	return c.SendStatus(200)
+}
+
+// maxReferenceCheck is the maximum number of files GetReferencedFiles checks per request
+const maxReferenceCheck = 1000
+
+// GetReferencedFiles answers the file service with the files of {"file_ids": [...]} that are
+// attached to a message, so its garbage collector keeps them
+func (h Handler) GetReferencedFiles(c *fiber.Ctx) error {
+	var input models.FileIdList
+	if err := c.BodyParser(&input); err != nil {
+		return response.HandleError(c, 400, "invalid request body")
+	}
+	if len(input.FileIds) > maxReferenceCheck {
+		return response.HandleError(c, 400, "too many file ids")
+	}
+
+	referenced := []string{}
+	if len(input.FileIds) > 0 {
+		found, err := h.Database.GetReferencedFiles(input.FileIds)
+		if err != nil {
+			logs.ConnError(nil, "Failed to check file references", err)
+			return response.HandleError(c, 500, "failed to check file references")
+		}
+		referenced = append(referenced, found...)
+	}
+	return response.HandleInformation(c, 200, models.FileIdList{FileIds: referenced})
+}
@@ -23,10 +23,12 @@ import (
 	"context"
 	"fmt"
 
-	"urulink.go/message_service/db"
-	"urulink.go/message_service/env"
-	"urulink.go/message_service/rabbitmq"
-	"urulink.go/message_service/redis"
+	"urulink.com/message_service/db"
+	"urulink.com/message_service/env"
+	"urulink.com/message_service/helper"
+	"urulink.com/message_service/rabbitmq"
+	"urulink.com/message_service/redis"
+	"urulink.com/message_service/search"
 )
 
 // Handler struct contains references to various services and clients needed by the application
@@ -37,6 +39,8 @@ type Handler struct {
 	Ctx            context.Context           // Context for managing request lifetimes
 	RabbitMQClient *rabbitmq.RabbitMQManager // RabbitMQ client manager instance
 	MaxWorkers     int                       // Maximum number of worker goroutines
+	SearchIndex    search.SearchIndex        // Full-text index used by message search
+	CustomEmoji    map[string]bool           // Workspace custom emoji shortcodes accepted as reactions
 }
 
 // Init initializes the Handler with necessary service connections and configurations
@@ -76,6 +80,28 @@ func Init() Handler {
 		panic("failed to connect to rabbitmq server!")
 	}
 
+	// Select the full-text search backend
+	switch env.SearchBackend {
+	case "mysql":
+		handlers_data.SearchIndex = search.NewMySQLIndex(handlers_data.Database.Db)
+	case "memory":
+		// The index lives in the process, so it is rebuilt from the most recent stored messages
+		index := search.NewMemoryIndex(search.MemoryIndexCapacity)
+		messages, err := handlers_data.Database.GetRecentMessages(search.MemoryIndexCapacity)
+		if err != nil {
+			panic("failed to load messages into the search index!")
+		}
+		for _, msg := range messages {
+			index.Index(context.Background(), msg)
+		}
+		handlers_data.SearchIndex = index
+	default:
+		panic("unknown SEARCH_BACKEND: " + env.SearchBackend)
+	}
+
+	// Load the custom emoji accepted as reactions
+	handlers_data.CustomEmoji = helper.ParseCustomEmoji(env.CustomEmoji)
+
 	// Set the maximum number of workers for concurrent processing
 	handlers_data.MaxWorkers = 10
 
@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/message_service/helper"
+	"urulink.com/message_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// errMessageNotFound is returned when a message does not exist or the user cannot see it
+var errMessageNotFound = errors.New("message not found")
+
+// visibleMessage loads a message the user sent or received
+func (h Handler) visibleMessage(messageId int64, userId string) (models.DirectMessage, error) {
+	msg, err := h.Database.GetMessageById(messageId)
+	if err != nil {
+		return models.DirectMessage{}, err
+	}
+	if msg.Id == 0 || (msg.SenderID != userId && msg.ReceiverID != userId) {
+		return models.DirectMessage{}, errMessageNotFound
+	}
+	return msg, nil
+}
+
+// react adds or removes a reaction and, when it changed anything, broadcasts a
+// reaction_added or reaction_removed event to the other participant of the message.
+func (h Handler) react(ctx context.Context, userId string, messageId int64, emoji string, add bool) error {
+	if err := helper.ValidateEmoji(emoji, h.CustomEmoji); err != nil {
+		return err
+	}
+
+	msg, err := h.visibleMessage(messageId, userId)
+	if err != nil {
+		return err
+	}
+
+	var eventType string
+	var changed bool
+	if add {
+		eventType = "reaction_added"
+		changed, err = h.Database.AddReaction(messageId, userId, emoji)
+	} else {
+		eventType = "reaction_removed"
+		changed, err = h.Database.RemoveReaction(messageId, userId, emoji)
+	}
+	if err != nil || !changed {
+		return err
+	}
+
+	reactions, err := h.Database.GetReactions(messageId)
+	if err != nil {
+		return err
+	}
+
+	peerId := msg.SenderID
+	if peerId == userId {
+		peerId = msg.ReceiverID
+	}
+	if peerId == userId {
+		return nil
+	}
+
+	event := models.ReactionEvent{MessageId: messageId, UserId: userId, Emoji: emoji, Reactions: reactions}
+	return h.publishEvent(ctx, peerId, eventType, event)
+}
+
+// GetReactions returns the aggregated reactions of a message the authenticated user can see.
+func (h Handler) GetReactions(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	messageId, err := c.ParamsInt("message_id")
+	if err != nil || messageId <= 0 {
+		return response.HandleError(c, 400, "invalid message id")
+	}
+
+	if _, err := h.visibleMessage(int64(messageId), userJwtInfo.Uid); err != nil {
+		if errors.Is(err, errMessageNotFound) {
+			return response.HandleError(c, 404, err.Error())
+		}
+		logs.ConnError(nil, "Failed to retrieve message", err)
+		return response.HandleError(c, 500, "failed to retrieve message")
+	}
+
+	reactions, err := h.Database.GetReactions(int64(messageId))
+	if err != nil {
+		logs.ConnError(nil, "Failed to retrieve reactions", err)
+		return response.HandleError(c, 500, "failed to retrieve reactions")
+	}
+	return response.HandleInformation(c, 200, reactions)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/message_service/models"
+	"urulink.com/message_service/search"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// SearchMessages searches the messages of the conversations the authenticated user belongs to.
+// Query parameters: q (text, "quoted" phrases), peer_id, sender_id, content_type, from, to (unix time) and limit.
+func (h Handler) SearchMessages(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	query := search.Query{
+		UserId:      userJwtInfo.Uid,
+		Text:        c.Query("q"),
+		PeerId:      c.Query("peer_id"),
+		SenderId:    c.Query("sender_id"),
+		ContentType: c.Query("content_type"),
+		From:        int64(c.QueryInt("from")),
+		To:          int64(c.QueryInt("to")),
+		Limit:       c.QueryInt("limit"),
+	}
+	if terms, phrases := search.ParseText(query.Text); len(terms) == 0 && len(phrases) == 0 {
+		return response.HandleError(c, 400, "search text is required")
+	}
+
+	hits, err := h.SearchIndex.Search(c.Context(), query)
+	if err != nil {
+		logs.ConnError(nil, "Failed to search messages", err)
+		return response.HandleError(c, 500, "failed to search messages")
+	}
+
+	// The index only knows the message rows, attachments are loaded from the database
+	messages := make([]models.DirectMessage, len(hits))
+	for i, hit := range hits {
+		messages[i] = hit.Message
+	}
+	if err := h.Database.LoadAttachments(messages); err != nil {
+		logs.ConnError(nil, "Failed to load attachments of search hits", err)
+		return response.HandleError(c, 500, "failed to search messages")
+	}
+	for i := range hits {
+		hits[i].Message = messages[i]
+	}
+	return response.HandleInformation(c, 200, hits)
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/message_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
+)
+
+// threadPageSize is the default and maximum number of replies returned per thread page
+const threadPageSize = 50
+
+// inConversation reports whether a message was exchanged between the two users
+func inConversation(msg models.DirectMessage, userId, peerId string) bool {
+	return (msg.SenderID == userId && msg.ReceiverID == peerId) || (msg.SenderID == peerId && msg.ReceiverID == userId)
+}
+
+// validateReferences checks that the quoted message and the thread root of a new message exist in
+// the conversation between the two users, and that threads are only started from top-level messages.
+func (h Handler) validateReferences(msgInput models.DirectMessageInput, userId, receiverId string) error {
+	if msgInput.ReplyTo != 0 {
+		parent, err := h.Database.GetMessageById(msgInput.ReplyTo)
+		if err != nil {
+			return err
+		}
+		if parent.Id == 0 || !inConversation(parent, userId, receiverId) {
+			return errors.New("replied message not found in conversation")
+		}
+	}
+
+	if msgInput.ThreadId != 0 {
+		root, err := h.Database.GetMessageById(msgInput.ThreadId)
+		if err != nil {
+			return err
+		}
+		if root.Id == 0 || !inConversation(root, userId, receiverId) {
+			return errors.New("thread root not found in conversation")
+		}
+		if root.ThreadId != 0 {
+			return errors.New("thread root must be a top-level message")
+		}
+	}
+	return nil
+}
+
+// publishThreadReply notifies the receiver that a thread got a new reply
+func (h Handler) publishThreadReply(ctx context.Context, msg models.DirectMessage, receiverId string) {
+	summary, err := h.Database.GetThreadSummary(msg.ThreadId, receiverId)
+	if err != nil {
+		logs.ConnError(nil, "Failed to load thread summary", err)
+		return
+	}
+
+	if err := h.publishEvent(ctx, receiverId, "thread_reply", models.ThreadReply{Thread: summary, Message: msg}); err != nil {
+		logs.ConnError(nil, "Failed to send thread reply via RabbitMQ", err)
+	}
+}
+
+// threadRoot loads the root message of :root_id and makes sure the user belongs to its conversation.
+// On failure it returns the status code and error message to answer with.
+func (h Handler) threadRoot(c *fiber.Ctx, userId string) (models.DirectMessage, int, string) {
+	rootId, err := c.ParamsInt("root_id")
+	if err != nil || rootId <= 0 {
+		return models.DirectMessage{}, 400, "invalid thread id"
+	}
+
+	root, err := h.Database.GetMessageById(int64(rootId))
+	if err != nil {
+		logs.ConnError(nil, "Failed to retrieve thread root", err)
+		return models.DirectMessage{}, 500, "failed to retrieve thread"
+	}
+	if root.Id == 0 || root.ThreadId != 0 || (root.SenderID != userId && root.ReceiverID != userId) {
+		return models.DirectMessage{}, 404, "thread not found"
+	}
+	return root, 0, ""
+}
+
+// GetThread returns the reply count, participants and unread state of a thread.
+func (h Handler) GetThread(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	root, status, errMsg := h.threadRoot(c, userJwtInfo.Uid)
+	if status != 0 {
+		return response.HandleError(c, status, errMsg)
+	}
+
+	summary, err := h.Database.GetThreadSummary(root.Id, userJwtInfo.Uid)
+	if err != nil {
+		logs.ConnError(nil, "Failed to retrieve thread summary", err)
+		return response.HandleError(c, 500, "failed to retrieve thread")
+	}
+	return response.HandleInformation(c, 200, summary)
+}
+
+// GetThreadMessages returns a page of thread replies in chronological order.
+// Pass ?before_id=<id of the oldest loaded reply> to load older replies, and ?limit to size the page.
+func (h Handler) GetThreadMessages(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	root, status, errMsg := h.threadRoot(c, userJwtInfo.Uid)
+	if status != 0 {
+		return response.HandleError(c, status, errMsg)
+	}
+
+	limit := c.QueryInt("limit", threadPageSize)
+	if limit <= 0 || limit > threadPageSize {
+		limit = threadPageSize
+	}
+
+	messages, err := h.Database.GetThreadMessages(root.Id, int64(c.QueryInt("before_id")), limit)
+	if err != nil {
+		logs.ConnError(nil, "Failed to retrieve thread messages", err)
+		return response.HandleError(c, 500, "failed to retrieve thread messages")
+	}
+	if messages == nil {
+		messages = []models.DirectMessage{}
+	}
+	return response.HandleInformation(c, 200, messages)
+}
+
+// MarkThreadRead marks every reply of a thread as read for the authenticated user.
+func (h Handler) MarkThreadRead(c *fiber.Ctx) error {
+	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
+
+	root, status, errMsg := h.threadRoot(c, userJwtInfo.Uid)
+	if status != 0 {
+		return response.HandleError(c, status, errMsg)
+	}
+
+	if err := h.Database.MarkThreadRead(root.Id, userJwtInfo.Uid); err != nil {
+		logs.ConnError(nil, "Failed to mark thread as read", err)
+		return response.HandleError(c, 500, "failed to mark thread as read")
+	}
+	return c.SendStatus(200)
+}
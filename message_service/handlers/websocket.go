@@ -23,14 +23,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
-
-	"urulink.go/message_service/helper"
-	"urulink.go/message_service/models"
-	"urulink.go/message_service/response"
+	"urulink.com/message_service/models"
+	"urulink.com/platform/logs"
+	"urulink.com/platform/response"
 )
 
 // WebSocketHandler handles incoming WebSocket connections and manages real-time messaging
@@ -40,6 +39,7 @@ func (h Handler) WebSocketHandler(c *websocket.Conn) {
 	// Extract JWT information for the user and retrieve user ID
 	userJwtInfo := c.Locals("userJwtInfo").(models.ClientsLoginResponse)
 	userId := userJwtInfo.Uid
+	accessToken, _ := c.Locals("accessToken").(string)
 	receiverId := c.Query("receiver_id")
 	if receiverId == "" {
 		// Return an error if receiver ID is not provided
@@ -62,7 +62,7 @@ func (h Handler) WebSocketHandler(c *websocket.Conn) {
 
 	// Add client to Redis, storing their connection information
 	if err := h.RedisClient.AddClient(ctx, userId, c); err != nil {
-		helper.LogError(c, "Failed to add client", err)
+		logs.ConnError(c.RemoteAddr(), "Failed to add client", err)
 		response.HandleWebSocketError(c, "Failed to add client")
 		return
 	}
@@ -70,26 +70,39 @@ func (h Handler) WebSocketHandler(c *websocket.Conn) {
 	// Retrieve message history for the user and send it over WebSocket
 	messages, err := h.Database.GetMessageByReceiverId(userId, receiverId)
 	if err != nil {
-		helper.LogError(c, "Failed to retrieve message history", err)
+		logs.ConnError(c.RemoteAddr(), "Failed to retrieve message history", err)
 		response.HandleWebSocketError(c, "Failed to retrieve message history")
 		return
 	}
 
+	// Writes happen from the read loop and from the RabbitMQ listener, so they are serialized
+	var writeMu sync.Mutex
+	send := func(data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return c.WriteMessage(websocket.TextMessage, data)
+	}
+
 	for _, msg := range messages {
 		messageData, _ := json.Marshal(msg)
-		if err := c.WriteMessage(websocket.TextMessage, messageData); err != nil {
-			helper.LogError(c, "Failed to send historical messages", err)
+		if err := send(messageData); err != nil {
+			logs.ConnError(c.RemoteAddr(), "Failed to send historical messages", err)
 			response.HandleWebSocketError(c, "Failed to send historical messages")
 			return
 		}
 	}
 
+	// Opening the conversation marks everything received from the peer as read
+	if err := h.Database.MarkConversationRead(userId, receiverId); err != nil {
+		logs.ConnError(c.RemoteAddr(), "Failed to mark conversation as read", err)
+	}
+
 	// Channel to queue incoming messages for processing by workers
 	jobs := make(chan models.DirectMessageInput, 100)
 
 	// Start worker goroutines to process messages
 	for w := 0; w < h.MaxWorkers; w++ {
-		go h.worker(ctx, jobs, userId, receiverId)
+		go h.worker(ctx, jobs, userId, receiverId, accessToken)
 	}
 
 	// Goroutine to listen for RabbitMQ messages and forward them to WebSocket
@@ -97,13 +110,13 @@ func (h Handler) WebSocketHandler(c *websocket.Conn) {
 		h.RabbitMQClient.ListenForMessages(ctx, userId, func(message []byte) error {
 			var msg models.DirectMessage
 			if err := json.Unmarshal(message, &msg); err != nil {
-				helper.LogError(c, "Failed to unmarshal incoming message", err)
+				logs.ConnError(c.RemoteAddr(), "Failed to unmarshal incoming message", err)
 				return err
 			}
 			// Forward message to WebSocket if it is intended for the current user
 			if msg.ReceiverID == userId {
-				if err := c.WriteMessage(websocket.TextMessage, message); err != nil {
-					helper.LogError(c, "Failed to send message", err)
+				if err := send(message); err != nil {
+					logs.ConnError(c.RemoteAddr(), "Failed to send message", err)
 					return err
 				}
 			}
@@ -113,104 +126,235 @@ func (h Handler) WebSocketHandler(c *websocket.Conn) {
 
 	// Main loop to receive messages from the WebSocket client
 	for {
-		var msgInput models.DirectMessageInput
-		if err := c.ReadJSON(&msgInput); err != nil {
-			helper.LogError(c, "Failed to read WebSocket message", err)
+		var request models.WebSocketRequest
+		if err := c.ReadJSON(&request); err != nil {
+			logs.ConnError(c.RemoteAddr(), "Failed to read WebSocket message", err)
 			return
 		}
-		// Send received message to jobs channel for further processing
-		jobs <- msgInput
+
+		switch request.Type {
+		case "", "message":
+			// Send received message to jobs channel for further processing
+			jobs <- request.DirectMessageInput
+		case "sync":
+			// Stream everything the device has not seen yet across all conversations
+			if err := h.syncMessages(userId, request, send); err != nil {
+				logs.ConnError(c.RemoteAddr(), "Failed to sync messages", err)
+				response.HandleWebSocketError(c, "Failed to sync messages")
+			}
+		case "conversations":
+			// Send the non-archived conversation list with last message previews and unread counts
+			if err := h.sendConversations(userId, send); err != nil {
+				logs.ConnError(c.RemoteAddr(), "Failed to send conversations", err)
+				response.HandleWebSocketError(c, "Failed to retrieve conversations")
+			}
+		case "reaction_add", "reaction_remove":
+			// Reactions are idempotent; the other participant is notified only on change
+			if err := h.react(ctx, userId, request.MessageId, request.Emoji, request.Type == "reaction_add"); err != nil {
+				logs.ConnError(c.RemoteAddr(), "Failed to update reaction", err)
+				response.HandleWebSocketError(c, "Failed to update reaction")
+			}
+		case "ack":
+			// Advance the device cursor after the client processed live messages
+			if err := h.Database.UpdateSyncCursor(userId, deviceIdOrDefault(request.DeviceId), request.LastSeq); err != nil {
+				logs.ConnError(c.RemoteAddr(), "Failed to update sync cursor", err)
+				response.HandleWebSocketError(c, "Failed to update sync cursor")
+			}
+		default:
+			response.HandleWebSocketError(c, "Unknown request type")
+		}
 	}
 }
 
+// syncBatchSize is the number of messages loaded from the database per sync round trip
+const syncBatchSize = 200
+
+// syncMessages sends every message newer than the device cursor, in the user's sequence order,
+// followed by a sync_done frame carrying the new cursor which is also persisted for the device.
+func (h Handler) syncMessages(userId string, request models.WebSocketRequest, send func([]byte) error) error {
+	deviceId := deviceIdOrDefault(request.DeviceId)
+
+	// An explicit last_seq wins over the stored cursor, e.g. after the client lost its local store
+	lastSeq := request.LastSeq
+	if lastSeq == 0 {
+		storedSeq, err := h.Database.GetSyncCursor(userId, deviceId)
+		if err != nil {
+			return err
+		}
+		lastSeq = storedSeq
+	}
+
+	for {
+		messages, err := h.Database.GetMessagesSince(userId, lastSeq, syncBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			messageData, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if err := send(messageData); err != nil {
+				return err
+			}
+			lastSeq = userSeq(msg, userId)
+		}
+
+		if len(messages) < syncBatchSize {
+			break
+		}
+	}
+
+	if err := h.Database.UpdateSyncCursor(userId, deviceId, lastSeq); err != nil {
+		return err
+	}
+
+	doneData, err := json.Marshal(models.SyncDone{Type: "sync_done", LastSeq: lastSeq})
+	if err != nil {
+		return err
+	}
+	logs.Event("Sync completed", map[string]interface{}{
+		"userId":   userId,
+		"deviceId": deviceId,
+		"lastSeq":  lastSeq,
+	})
+	return send(doneData)
+}
+
+// sendConversations sends the non-archived conversation list of the user as a single frame
+func (h Handler) sendConversations(userId string, send func([]byte) error) error {
+	conversations, err := h.Database.GetConversations(userId, false)
+	if err != nil {
+		return err
+	}
+	if conversations == nil {
+		conversations = []models.Conversation{}
+	}
+
+	listData, err := json.Marshal(models.ConversationList{Type: "conversations", Conversations: conversations})
+	if err != nil {
+		return err
+	}
+	return send(listData)
+}
+
+// userSeq returns the position of a message in the given user's sequence
+func userSeq(msg models.DirectMessage, userId string) int64 {
+	if msg.ReceiverID == userId {
+		return msg.ReceiverSeq
+	}
+	return msg.SenderSeq
+}
+
+// deviceIdOrDefault falls back to a shared cursor for clients that do not identify their device
+func deviceIdOrDefault(deviceId string) string {
+	if deviceId == "" {
+		return "default"
+	}
+	return deviceId
+}
+
 // worker function processes messages from the jobs channel
-func (h Handler) worker(ctx context.Context, jobs <-chan models.DirectMessageInput, senderId, receiverId string) {
+// accessToken is the Authorization header of the sender, used to validate attachments with the file service
+func (h Handler) worker(ctx context.Context, jobs <-chan models.DirectMessageInput, senderId, receiverId, accessToken string) {
 	for msgInput := range jobs {
-		h.processMessage(ctx, msgInput, senderId, receiverId)
+		h.processMessage(ctx, msgInput, senderId, receiverId, accessToken)
 	}
 }
 
 // processMessage processes a single message by creating, saving, and optionally sending it
-func (h Handler) processMessage(ctx context.Context, msgInput models.DirectMessageInput, senderId, receiverId string) {
+func (h Handler) processMessage(ctx context.Context, msgInput models.DirectMessageInput, senderId, receiverId, accessToken string) {
 	// Check if the receiver is online using Redis
 	isOnline := h.RedisClient.IsClientConnected(ctx, receiverId)
 	msgStatus := 2 // Offline by default
 	if isOnline {
 		msgStatus = 1 // Set status to online if receiver is connected
 	}
-	helper.LogInfo("Receiver online status", map[string]interface{}{
+	logs.Event("Receiver online status", map[string]interface{}{
 		"receiverId": receiverId,
 		"isOnline":   isOnline,
 	})
 
 	// Create and save message in database
-	msg, err := h.createMessage(msgInput, msgStatus, senderId, receiverId)
+	msg, err := h.createMessage(msgInput, msgStatus, senderId, receiverId, accessToken)
 	if err != nil {
-		helper.LogError(nil, "Failed to create and store message", err)
+		logs.ConnError(nil, "Failed to create and store message", err)
+		return
+	}
+
+	// Thread replies reach the receiver as a thread_reply event carrying the updated thread
+	if msg.ThreadId != 0 {
+		h.publishThreadReply(ctx, msg, receiverId)
 		return
 	}
 
 	// Marshal the message into JSON format for transmission
 	msgBytes, err := json.Marshal(msg)
 	if err != nil {
-		helper.LogError(nil, "Failed to marshal message", err)
+		logs.ConnError(nil, "Failed to marshal message", err)
 		return
 	}
 
 	// Send message via RabbitMQ to the intended receiver
 	if err := h.RabbitMQClient.PublishMessage(ctx, receiverId, msgBytes, h.EnvManger); err != nil {
-		helper.LogError(nil, "Failed to send message via RabbitMQ", err)
+		logs.ConnError(nil, "Failed to send message via RabbitMQ", err)
 	}
 }
 
-// createMessage constructs a message object and saves it to the database
-func (h Handler) createMessage(msgInput models.DirectMessageInput, msgStatus int, userId, receiverId string) (models.DirectMessage, error) {
-	var c *fiber.Ctx
-	var conn *websocket.Conn
+// publishEvent sends a server-initiated event to a user through the same RabbitMQ path as messages
+func (h Handler) publishEvent(ctx context.Context, receiverId, eventType string, data interface{}) error {
+	eventBytes, err := json.Marshal(models.Event{Type: eventType, ReceiverID: receiverId, Data: data})
+	if err != nil {
+		return err
+	}
+	return h.RabbitMQClient.PublishMessage(ctx, receiverId, eventBytes, h.EnvManger)
+}
 
-	helper.LogInfo("Creating message", map[string]interface{}{
+// createMessage constructs a message object and saves it to the database
+func (h Handler) createMessage(msgInput models.DirectMessageInput, msgStatus int, userId, receiverId, accessToken string) (models.DirectMessage, error) {
+	logs.Event("Creating message", map[string]interface{}{
 		"senderId":   userId,
 		"receiverId": receiverId,
 		"status":     msgStatus,
 	})
 
-	var msg models.DirectMessage
-	// If the message includes files, upload them and store the file path
-	if msgInput.ContentType == "files" {
-		statusCode, body, err := helper.AgentSendFile(c, h.EnvManger.FilesServiceUrl+"/upload", msgInput.Content)
-		if err != nil {
-			helper.LogError(conn, "Failed to upload files", err)
-			return models.DirectMessage{}, errors.New("failed to upload files")
-		}
-		if statusCode != 200 {
-			helper.LogError(conn, "File upload failed", errors.New("invalid status code"))
-			return models.DirectMessage{}, errors.New("file upload failed")
-		}
-		// Decode and save the uploaded file path
-		filePath, err := helper.AgentResponse[string](c, body)
-		if err != nil {
-			helper.LogError(conn, "Failed to decode file response", err)
-			return models.DirectMessage{}, errors.New("failed to decode file response")
-		}
-		msg.FilePath = filePath
-		helper.LogInfo("File upload processed successfully", nil)
+	// Quoted messages and thread roots must belong to this conversation
+	if err := h.validateReferences(msgInput, userId, receiverId); err != nil {
+		logs.ConnError(nil, "Invalid message reference", err)
+		return models.DirectMessage{}, err
+	}
+
+	// Files are uploaded to the file service beforehand; the message only references them
+	attachments, err := h.resolveAttachments(msgInput, userId, accessToken)
+	if err != nil {
+		logs.ConnError(nil, "Invalid message attachments", err)
+		return models.DirectMessage{}, err
 	}
 
 	// Populate message fields
-	msg = models.DirectMessage{
+	msg := models.DirectMessage{
 		SenderID:    userId,
 		ReceiverID:  receiverId,
 		Content:     msgInput.Content,
 		ContentType: msgInput.ContentType,
+		ReplyTo:     msgInput.ReplyTo,
+		ThreadId:    msgInput.ThreadId,
 		Status:      msgStatus,
 		CreatedAt:   time.Now().Unix(),
+		Attachments: attachments,
 	}
 
 	// Save message to database
-	if err := h.Database.CreateNewMsg(msg); err != nil {
-		helper.LogError(nil, "Failed to save message in database", err)
+	if err := h.Database.CreateNewMsg(&msg); err != nil {
+		logs.ConnError(nil, "Failed to save message in database", err)
 		return models.DirectMessage{}, errors.New("failed to save message in database")
 	}
-	helper.LogInfo("Message saved to database successfully", nil)
+	logs.Event("Message saved to database successfully", nil)
+
+	// Make the message searchable; a failure here must not lose the stored message
+	if err := h.SearchIndex.Index(h.Ctx, msg); err != nil {
+		logs.ConnError(nil, "Failed to index message for search", err)
+	}
 	return msg, nil
 }
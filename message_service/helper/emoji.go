@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxEmojiBytes bounds the length of a reaction, long enough for family and flag sequences
+const maxEmojiBytes = 64
+
+// ParseCustomEmoji turns a comma-separated list of ":shortcode:" entries into a lookup set.
+func ParseCustomEmoji(list string) map[string]bool {
+	customEmoji := map[string]bool{}
+	for _, shortcode := range strings.Split(list, ",") {
+		shortcode = strings.TrimSpace(shortcode)
+		if isShortcode(shortcode) {
+			customEmoji[shortcode] = true
+		}
+	}
+	return customEmoji
+}
+
+// ValidateEmoji accepts a single Unicode emoji sequence (including skin tones, ZWJ sequences,
+// flags and keycaps) or one of the workspace custom emoji shortcodes. Several emoji in a row,
+// such as "👍👍", are rejected.
+func ValidateEmoji(emoji string, customEmoji map[string]bool) error {
+	if emoji == "" || len(emoji) > maxEmojiBytes || !utf8.ValidString(emoji) {
+		return errors.New("invalid emoji")
+	}
+	if strings.HasPrefix(emoji, ":") {
+		if customEmoji[emoji] {
+			return nil
+		}
+		return errors.New("unknown custom emoji")
+	}
+
+	// An emoji is one element, or several joined by zero width joiners, with nothing after it
+	runes := []rune(emoji)
+	end, ok := emojiElement(runes, 0)
+	for ok && end < len(runes) && runes[end] == 0x200D {
+		end, ok = emojiElement(runes, end+1)
+	}
+	if !ok || end != len(runes) {
+		return errors.New("invalid emoji")
+	}
+	return nil
+}
+
+// emojiElement reads the emoji element starting at runes[i]: a keycap, a flag or a pictograph
+// with its modifiers. It returns the index following the element and whether one was found.
+func emojiElement(runes []rune, i int) (int, bool) {
+	if i >= len(runes) {
+		return i, false
+	}
+	r := runes[i]
+	j := i + 1
+	switch {
+	case r >= '0' && r <= '9', r == '#', r == '*':
+		// Keycap bases must be followed by an optional variation selector and U+20E3
+		if j < len(runes) && runes[j] == 0xFE0F {
+			j++
+		}
+		if j < len(runes) && runes[j] == 0x20E3 {
+			return j + 1, true
+		}
+		return i, false
+	case isRegionalIndicator(r):
+		// Country flags are made of exactly two regional indicators
+		if j < len(runes) && isRegionalIndicator(runes[j]) {
+			return j + 1, true
+		}
+		return i, false
+	case r == 0x1F3F4 && j < len(runes) && isTag(runes[j]):
+		// Subdivision flags: black flag, tag characters and the cancel tag
+		for j < len(runes) && isTag(runes[j]) {
+			j++
+		}
+		if j < len(runes) && runes[j] == 0xE007F {
+			return j + 1, true
+		}
+		return i, false
+	case isPictograph(r):
+		// A skin tone modifier or a variation selector may follow the pictograph
+		if j < len(runes) && (isSkinTone(runes[j]) || runes[j] == 0xFE0F || runes[j] == 0xFE0E) {
+			j++
+		}
+		return j, true
+	}
+	return i, false
+}
+
+// isRegionalIndicator reports whether a rune is one of the letters flags are spelled with
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isSkinTone reports whether a rune is a skin tone modifier
+func isSkinTone(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+// isTag reports whether a rune is a tag character of a subdivision flag, the cancel tag excluded
+func isTag(r rune) bool {
+	return r >= 0xE0020 && r <= 0xE007E
+}
+
+// isPictograph reports whether a rune belongs to the blocks emoji are drawn from
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Mahjong, cards, enclosed, symbols and pictographs, emoticons, transport, flags
+		return !isSkinTone(r) && !isRegionalIndicator(r)
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // Miscellaneous technical (watch, hourglass, ...)
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // Arrows and stars
+		return true
+	case r >= 0x2190 && r <= 0x21FF, r >= 0x2934 && r <= 0x2935: // Arrows
+		return true
+	case r == 0x00A9 || r == 0x00AE || r == 0x203C || r == 0x2049 || r == 0x2122 || r == 0x2139 || r == 0x24C2:
+		return true
+	case r >= 0x25AA && r <= 0x25FE, r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
+
+// isShortcode reports whether s looks like ":name:" with lowercase letters, digits, '_', '+' or '-'
+func isShortcode(s string) bool {
+	if len(s) < 3 || s[0] != ':' || s[len(s)-1] != ':' {
+		return false
+	}
+	for _, r := range s[1 : len(s)-1] {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' && r != '+' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package helper
+
+import "testing"
+
+func TestValidateEmoji(t *testing.T) {
+	custom := ParseCustomEmoji(":party_parrot:, :shipit:,not-a-shortcode")
+	tests := []struct {
+		name  string
+		emoji string
+		valid bool
+	}{
+		{"pictograph", "👍", true},
+		{"symbol with variation selector", "❤️", true},
+		{"skin tone", "👍🏽", true},
+		{"ZWJ family", "👨‍👩‍👧‍👦", true},
+		{"ZWJ with variation selectors", "🏳️‍🌈", true},
+		{"ZWJ with skin tones", "🧑🏽‍🤝‍🧑🏻", true},
+		{"ZWJ with gender sign", "🏌️‍♂️", true},
+		{"country flag", "🇮🇶", true},
+		{"subdivision flag", "🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", true},
+		{"keycap", "1️⃣", true},
+		{"keycap without variation selector", "#⃣", true},
+		{"custom emoji", ":party_parrot:", true},
+		{"second custom emoji", ":shipit:", true},
+		{"empty", "", false},
+		{"plain text", "ok", false},
+		{"digit without keycap", "1", false},
+		{"emoji followed by text", "👍ok", false},
+		{"text followed by emoji", "ok👍", false},
+		{"several emoji", "👍👍👍", false},
+		{"two flags", "🇮🇶🇺🇸", false},
+		{"emoji with space", "👍 ", false},
+		{"single regional indicator", "🇮", false},
+		{"lone skin tone", "🏽", false},
+		{"lone joiner", "‍", false},
+		{"trailing joiner", "👍‍", false},
+		{"leading joiner", "‍👍", false},
+		{"unterminated subdivision flag", "🏴\U000E0067\U000E0062", false},
+		{"unknown custom emoji", ":unknown:", false},
+		{"invalid shortcode", "not-a-shortcode", false},
+		{"invalid UTF-8", "\xf0\x9f\x91", false},
+		{"too long", "👍" + string(make([]byte, 64)), false},
+	}
+	for _, tt := range tests {
+		err := ValidateEmoji(tt.emoji, custom)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: ValidateEmoji(%q) = %v, want valid %v", tt.name, tt.emoji, err, tt.valid)
+		}
+	}
+}
+
+func TestParseCustomEmoji(t *testing.T) {
+	custom := ParseCustomEmoji(" :a_b: ,:c+d:,:e-f:,:UPPER:,::,plain,")
+	for _, shortcode := range []string{":a_b:", ":c+d:", ":e-f:"} {
+		if !custom[shortcode] {
+			t.Errorf("%s missing", shortcode)
+		}
+	}
+	if len(custom) != 3 {
+		t.Errorf("parsed %v", custom)
+	}
+}
@@ -19,36 +19,11 @@
 
 package helper
 
-import (
-	"math/rand"
-	"time"
-)
-
-const (
-	// numericChars defines the set of characters to use for generating random strings.
-	numericChars = "0123456789abcdefghijklmnopqrstuvwxyz"
-)
-
-// generateRandomString creates a random string of specified length using the provided characters.
-// chars: string of characters to choose from.
-// length: desired length of the generated string.
-func generateRandomString(chars string, length int) string {
-	// Initialize a new random source based on the current time in nanoseconds for unique randomness.
-	source := rand.NewSource(time.Now().UnixNano())
-	randomGenerator := rand.New(source)
-
-	// Build the result string by randomly selecting characters from the input set.
-	var result string
-	for i := 0; i < length; i++ {
-		index := randomGenerator.Intn(len(chars)) // Select a random index
-		result += string(chars[index])            // Append character at the index to the result
-	}
-	return result
-}
+import "urulink.com/platform/ids"
 
 // GenerateConnId generates a unique connection ID of predefined length using numeric and alphabetic characters.
 // Returns a random string ID of length 14.
 func GenerateConnId() string {
 	const uidLength = 14
-	return generateRandomString(numericChars, uidLength)
+	return ids.New(ids.Alphanumeric, uidLength)
 }
@@ -23,10 +23,13 @@ import (
 	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"urulink.go/message_service/routes"
+	"urulink.com/message_service/routes"
+	"urulink.com/platform/logs"
 )
 
 func main() {
+	logs.Setup("service.log")
+
 	app := fiber.New()
 
 	routes.SetRoutes(app)
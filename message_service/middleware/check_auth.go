@@ -20,14 +20,10 @@
 package middleware
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
-	"urulink.go/message_service/handlers"
-	"urulink.go/message_service/helper"
-	"urulink.go/message_service/models"
+	"urulink.com/message_service/handlers"
+	"urulink.com/platform/auth"
 )
 
 // WebSocketConnection validates a WebSocket connection request with authorization check before allowing the upgrade.
@@ -39,38 +35,15 @@ func WebSocketConnection(h *handlers.Handler) fiber.Handler {
 			return fiber.ErrUpgradeRequired
 		}
 
-		// Retrieve the authorization service URL from environment variables
-		authUrl, ok := os.LookupEnv("URULINK_AUTH_SERVICE")
-		if !ok {
-			// Panic if the authorization service environment variable is not set
-			panic("URULINK_AUTH_SERVICE Env not found !")
-		}
-
-		// Get the access token from the request headers
-		accessToken := c.Get("Authorization")
-
-		// Set up a POST request to the auth service to verify the user's login status
-		agent := fiber.Post(authUrl + "/check-login")
-		agent.Set("Authorization", accessToken) // Add Authorization header with the access token
-
-		// Execute the request and capture the response
-		statusCode, body, errs := agent.Bytes()
-		// If there are any errors or the status code is not 200, respond with "Unauthorized"
-		if len(errs) > 0 || statusCode != 200 {
-			fmt.Println(errs, statusCode)
-			return c.Status(401).SendString("Unauthorized requests 1")
-		}
-
-		// Parse the response to extract the user's JWT information
-		userJwtInfo, err := helper.AgentResponse[models.ClientsLoginResponse](c, body)
+		userJwtInfo, err := auth.CheckLogin(h.EnvManger.AuthServiceUrl, c.Get("Authorization"))
 		if err != nil {
-			// If parsing fails, respond with "Unauthorized"
-			return c.Status(401).SendString("Unauthorized requests 2")
+			return c.Status(401).SendString("Unauthorized requests")
 		}
 
 		// Store the user information in the context for access in WebSocket handlers
 		c.Locals("userJwtInfo", userJwtInfo)
-		c.Locals("allowed", true) // Mark the connection as authorized
+		c.Locals("accessToken", c.Get("Authorization")) // Forwarded to the file service to validate attachments
+		c.Locals("allowed", true)                       // Mark the connection as authorized
 
 		// Proceed with the next middleware or route handler
 		return c.Next()
@@ -19,22 +19,164 @@
 
 package models
 
-type ClientsLoginResponse struct {
-	Uid      string `json:"uid"`
-	Username string `json:"username"`
-}
+import (
+	"urulink.com/platform/auth"
+)
+
+// ClientsLoginResponse is the user an access token belongs to, as reported by the auth service
+type ClientsLoginResponse = auth.User
 
 type DirectMessageInput struct {
-	ContentType string `json:"content_type"`
-	Content     string `json:"content"`
+	ContentType string   `json:"content_type"`
+	Content     string   `json:"content"`
+	ReplyTo     int64    `json:"reply_to"`  // Optional: id of the message being quoted
+	ThreadId    int64    `json:"thread_id"` // Optional: id of the root message of the thread to post in
+	FileIds     []string `json:"file_ids"`  // Files uploaded to the file service, required for content_type "files" and "voice"
+}
+
+// WebSocketRequest is the envelope for every frame a client sends over the WebSocket.
+// Type selects the action; an empty type is treated as a plain direct message so
+// existing clients that only send content_type/content keep working.
+type WebSocketRequest struct {
+	Type string `json:"type"`
+	DirectMessageInput
+	DeviceId  string `json:"device_id"`  // Device whose sync cursor is read or advanced
+	LastSeq   int64  `json:"last_seq"`   // Last sequence number the device has seen
+	MessageId int64  `json:"message_id"` // Message targeted by a reaction
+	Emoji     string `json:"emoji"`      // Unicode emoji or custom :shortcode: of a reaction
 }
 
 type DirectMessage struct {
+	Id          int64  `json:"id"`
 	SenderID    string `json:"sender_id"`
 	ReceiverID  string `json:"receiver_id"`
 	Content     string `json:"content"`
 	ContentType string `json:"content_type"`
-	FilePath    string `json:"file_path"`
+	FilePath    string `json:"file_path,omitempty"` // Legacy single file reference; new file messages use Attachments
 	Status      int    `json:"status"`
+	SenderSeq   int64  `json:"sender_seq"`            // Position of the message in the sender's sequence
+	ReceiverSeq int64  `json:"receiver_seq"`          // Position of the message in the receiver's sequence
+	ReplyTo     int64  `json:"reply_to"`              // Id of the quoted message, 0 when not a reply
+	ThreadId    int64  `json:"thread_id"`             // Id of the thread root, 0 for the main conversation
+	ReplyCount  int    `json:"reply_count" gorm:"->"` // Number of thread replies, filled for roots in history
 	CreatedAt   int64  `json:"created_at"`
+
+	Attachments []Attachment `json:"attachments,omitempty" gorm:"-"` // Files of a "files" or "voice" message, in the order they were sent
+}
+
+// Attachment describes a file attached to a message, as reported by the file service when it was sent
+type Attachment struct {
+	MessageId    int64  `json:"-"`
+	Position     int    `json:"-"`
+	FileId       string `json:"file_id"`
+	Name         string `json:"name"`
+	Size         int64  `json:"size"`
+	MimeType     string `json:"mime_type"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	BlurHash     string `json:"blur_hash,omitempty"`
+	HasThumbnail bool   `json:"has_thumbnail"` // The file service serves /files/:id/preview/thumbnail for it
+	DurationMs   int64  `json:"duration_ms,omitempty"`
+	Waveform     []byte `json:"waveform,omitempty"` // Peak amplitudes (0-255) of equal parts of a recording, base64 in JSON
+}
+
+// FileIdList is the request and response body of the reference check of the file service
+type FileIdList struct {
+	FileIds []string `json:"file_ids"`
+}
+
+// FileMetadata is the subset of the file service metadata used to validate and describe attachments
+type FileMetadata struct {
+	Id            string `json:"id"`
+	OwnerId       string `json:"owner_id"`
+	OriginalName  string `json:"original_name"`
+	Size          int64  `json:"size"`
+	MimeType      string `json:"mime_type"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	BlurHash      string `json:"blur_hash"`
+	PreviewStatus string `json:"preview_status"`
+	ScanStatus    string `json:"scan_status"`
+	DurationMs    int64  `json:"duration_ms"`
+	Waveform      []byte `json:"waveform"`
+}
+
+// SyncDone is sent after a sync request has streamed every pending message.
+type SyncDone struct {
+	Type    string `json:"type"`
+	LastSeq int64  `json:"last_seq"`
+}
+
+// Conversation is one row of a user's conversation list. It is maintained incrementally
+// every time a message is stored, so listing never scans direct_message.
+type Conversation struct {
+	UserId             string `json:"-"`
+	PeerId             string `json:"peer_id"`
+	LastMessageId      int64  `json:"last_message_id"`
+	LastMessagePreview string `json:"last_message_preview"`
+	LastContentType    string `json:"last_content_type"`
+	LastSenderId       string `json:"last_sender_id"`
+	UnreadCount        int    `json:"unread_count"`
+	Muted              bool   `json:"muted"`
+	Archived           bool   `json:"archived"`
+	Pinned             bool   `json:"pinned"`
+	UpdatedAt          int64  `json:"updated_at"`
+}
+
+// ConversationSettingsInput changes the per-user flags of a conversation; nil fields are left untouched.
+type ConversationSettingsInput struct {
+	Muted    *bool `json:"muted"`
+	Archived *bool `json:"archived"`
+	Pinned   *bool `json:"pinned"`
+}
+
+// ConversationList is the WebSocket frame answering a conversations request.
+type ConversationList struct {
+	Type          string         `json:"type"`
+	Conversations []Conversation `json:"conversations"`
+}
+
+// SearchHit is a message matching a search query with a highlighted snippet of its content.
+type SearchHit struct {
+	Message DirectMessage `json:"message"`
+	Snippet string        `json:"snippet"`
+}
+
+// ThreadSummary describes a thread rooted at a message, as seen by one user.
+type ThreadSummary struct {
+	RootId       int64    `json:"root_id"`
+	ReplyCount   int      `json:"reply_count"`
+	LastReplyId  int64    `json:"last_reply_id"`
+	LastReplyAt  int64    `json:"last_reply_at"`
+	Participants []string `json:"participants" gorm:"-"` // Author of the root and everyone who replied
+	UnreadCount  int      `json:"unread_count" gorm:"-"` // Replies from others the user has not read yet
+}
+
+// ThreadReply is the payload of a thread_reply event.
+type ThreadReply struct {
+	Thread  ThreadSummary `json:"thread"`
+	Message DirectMessage `json:"message"`
+}
+
+// Event is a server-initiated WebSocket frame published through RabbitMQ.
+// ReceiverID routes it the same way it routes a DirectMessage.
+type Event struct {
+	Type       string      `json:"type"`
+	ReceiverID string      `json:"receiver_id"`
+	Data       interface{} `json:"data"`
+}
+
+// ReactionSummary aggregates the reactions of one emoji on a message.
+type ReactionSummary struct {
+	Emoji string   `json:"emoji"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// ReactionEvent is the payload of reaction_added and reaction_removed events.
+type ReactionEvent struct {
+	MessageId int64             `json:"message_id"`
+	UserId    string            `json:"user_id"`
+	Emoji     string            `json:"emoji"`
+	Reactions []ReactionSummary `json:"reactions"`
 }
@@ -24,8 +24,8 @@ import (
 	"fmt"
 
 	"github.com/streadway/amqp"
-	"urulink.go/message_service/env"
-	"urulink.go/message_service/helper"
+	"urulink.com/message_service/env"
+	"urulink.com/platform/logs"
 )
 
 // PublishMessage sends a message to the specified RabbitMQ exchange with the given user ID as the routing key.
@@ -58,7 +58,7 @@ func (rm *RabbitMQManager) ListenForMessages(ctx context.Context, userId string,
 	)
 	if err != nil {
 		// Log an error if message consumption fails
-		helper.LogError(nil, fmt.Sprintf("Failed to consume messages for userId %s", userId), err)
+		logs.ConnError(nil, fmt.Sprintf("Failed to consume messages for userId %s", userId), err)
 		return
 	}
 
@@ -69,19 +69,19 @@ func (rm *RabbitMQManager) ListenForMessages(ctx context.Context, userId string,
 			err := handler(msg.Body)
 			if err != nil {
 				// Log an error and requeue the message if the handler returns an error
-				helper.LogError(nil, fmt.Sprintf("Error processing message for userId %s", userId), err)
+				logs.ConnError(nil, fmt.Sprintf("Error processing message for userId %s", userId), err)
 				msg.Nack(false, true) // negative acknowledgment to requeue the message
 			} else {
 				// Acknowledge the message if processing is successful
 				if err := msg.Ack(false); err != nil {
-					helper.LogError(nil, fmt.Sprintf("Failed to acknowledge message for userId %s", userId), err)
+					logs.ConnError(nil, fmt.Sprintf("Failed to acknowledge message for userId %s", userId), err)
 				}
 			}
 		}
 	}()
 
 	// Log that the message listener has started for the user
-	helper.LogInfo("Started listening for messages", map[string]interface{}{"userId": userId})
+	logs.Event("Started listening for messages", map[string]interface{}{"userId": userId})
 }
 
 // CancelConsume stops consuming messages for the specified consumer tag in RabbitMQ.
@@ -92,7 +92,7 @@ func (rm *RabbitMQManager) CancelConsume(consumerTag string) error {
 		return fmt.Errorf("[CancelConsume] Failed to cancel consumer %s: %v", consumerTag, err)
 	}
 	// Log the successful cancellation of the consumer
-	helper.LogInfo("Consumer cancelled successfully", map[string]interface{}{
+	logs.Event("Consumer cancelled successfully", map[string]interface{}{
 		"consumerTag": consumerTag,
 	})
 	return nil
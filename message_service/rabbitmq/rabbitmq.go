@@ -23,7 +23,7 @@ import (
 	"fmt"
 
 	"github.com/streadway/amqp"
-	"urulink.go/message_service/env"
+	"urulink.com/message_service/env"
 )
 
 // RabbitMQManager manages the connection and channel to RabbitMQ
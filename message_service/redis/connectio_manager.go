@@ -23,7 +23,8 @@ import (
 	"context"
 
 	"github.com/gofiber/websocket/v2"
-	"urulink.go/message_service/helper"
+	"urulink.com/message_service/helper"
+	"urulink.com/platform/logs"
 )
 
 // AddClient adds a new WebSocket client connection to Redis with a unique connection ID
@@ -40,7 +41,7 @@ func (cm *RedisManager) AddClient(ctx context.Context, userId string, conn *webs
 	}
 
 	// Log the addition of the client to Redis for tracking purposes
-	helper.LogInfo("Client added to Redis", map[string]interface{}{
+	logs.Event("Client added to Redis", map[string]interface{}{
 		"userId":       userId,       // ID of the user being added
 		"connectionID": connectionID, // unique connection ID generated
 	})
@@ -69,7 +70,7 @@ func (cm *RedisManager) RemoveClient(ctx context.Context, userId string) error {
 	}
 
 	// Log the removal of the client from Redis for tracking purposes
-	helper.LogInfo("Client removed from Redis", map[string]interface{}{
+	logs.Event("Client removed from Redis", map[string]interface{}{
 		"userId": userId, // ID of the user being removed
 	})
 	return nil
@@ -24,7 +24,7 @@ import (
 	"log"
 
 	"github.com/redis/go-redis/v9"
-	"urulink.go/message_service/env"
+	"urulink.com/message_service/env"
 )
 
 // RedisManager struct to manage Redis client and message channel
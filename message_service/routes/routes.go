@@ -22,8 +22,9 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
-	"urulink.go/message_service/handlers"
-	"urulink.go/message_service/middleware"
+	"urulink.com/message_service/handlers"
+	"urulink.com/message_service/middleware"
+	"urulink.com/platform/auth"
 )
 
 func SetRoutes(app *fiber.App) {
@@ -31,4 +32,18 @@ func SetRoutes(app *fiber.App) {
 
 	app.Get("/ws", middleware.WebSocketConnection(&handler), websocket.New(handler.WebSocketHandler))
 
+	authRoutes := app.Group("/", auth.HttpAuth(handler.EnvManger.AuthServiceUrl))
+	authRoutes.Get("/conversations", handler.GetConversations)
+	authRoutes.Post("/conversations/:peer_id/read", handler.MarkConversationRead)
+	authRoutes.Put("/conversations/:peer_id", handler.UpdateConversationSettings)
+	authRoutes.Get("/search", handler.SearchMessages)
+	authRoutes.Get("/threads/:root_id", handler.GetThread)
+	authRoutes.Get("/threads/:root_id/messages", handler.GetThreadMessages)
+	authRoutes.Post("/threads/:root_id/read", handler.MarkThreadRead)
+	authRoutes.Get("/messages/:message_id/reactions", handler.GetReactions)
+	authRoutes.Get("/files/:file_id/access", handler.CheckFileAccess)
+
+	internalRoutes := app.Group("/internal", auth.ServiceAuth(handler.EnvManger.ServiceToken))
+	internalRoutes.Post("/files/referenced", handler.GetReferencedFiles)
+
 }
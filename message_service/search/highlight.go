@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Highlight markers wrapped around every match in a snippet
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// snippetContext is the number of characters kept on each side of the first match
+const snippetContext = 60
+
+// Highlight returns a snippet of content centred on the first match of the terms or phrases,
+// with every match wrapped in HighlightStart/HighlightEnd. Matching is case-insensitive and
+// follows the search backends: terms match the start of a word, phrases match whole words.
+// The content of the snippet is HTML-escaped, so the markers are the only markup in it.
+func Highlight(content string, terms, phrases []string) string {
+	// Collect the byte ranges of every match on a lower-cased copy. Lower-casing changes the
+	// byte length of a few runes; fall back to case-sensitive matching so offsets stay valid
+	lower := strings.ToLower(content)
+	if len(lower) != len(content) {
+		lower = content
+	}
+	marked := make([]bool, len(content))
+	first := -1
+	mark := func(needle string, wholeWord bool) {
+		if needle == "" {
+			return
+		}
+		for offset := 0; offset < len(lower); {
+			index := strings.Index(lower[offset:], needle)
+			if index < 0 {
+				break
+			}
+			start, end := offset+index, offset+index+len(needle)
+			offset = start + 1
+			if !wordBoundaryBefore(lower, start) || (wholeWord && !wordBoundaryAfter(lower, end)) {
+				continue
+			}
+			for i := start; i < end; i++ {
+				marked[i] = true
+			}
+			if first < 0 || start < first {
+				first = start
+			}
+			offset = end
+		}
+	}
+	for _, phrase := range phrases {
+		mark(phrase, true)
+	}
+	for _, term := range terms {
+		mark(term, false)
+	}
+
+	// Cut a window around the first match, aligned on rune boundaries
+	start, end := 0, len(content)
+	if first > snippetContext {
+		start = first - snippetContext
+	}
+	if first >= 0 && first+snippetContext*2 < len(content) {
+		end = first + snippetContext*2
+	} else if first < 0 && snippetContext*2 < len(content) {
+		end = snippetContext * 2
+	}
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+	// Write the window as runs of marked and unmarked bytes, escaping each run
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			snippet.WriteString(HighlightStart + html.EscapeString(content[i:j]) + HighlightEnd)
+		} else {
+			snippet.WriteString(html.EscapeString(content[i:j]))
+		}
+		i = j
+	}
+	if end < len(content) {
+		snippet.WriteString("…")
+	}
+	return snippet.String()
+}
+
+// wordBoundaryBefore reports whether a word can start at byte offset i of text
+func wordBoundaryBefore(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return isSeparator(r)
+}
+
+// wordBoundaryAfter reports whether a word can end at byte offset i of text
+func wordBoundaryAfter(text string, i int) bool {
+	if i == len(text) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return isSeparator(r)
+}
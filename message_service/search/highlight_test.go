@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		phrases []string
+		want    string
+	}{
+		{"term prefix", "Stations and nations", []string{"station"}, nil, "<mark>Station</mark>s and nations"},
+		{"every occurrence", "go go gopher", []string{"go"}, nil, "<mark>go</mark> <mark>go</mark> <mark>go</mark>pher"},
+		{"phrase of whole words", "the station, the stationary", nil, []string{"the station"}, "<mark>the station</mark>, the stationary"},
+		{"no match", "hello", []string{"bye"}, nil, "hello"},
+		{"escapes content", `<img src=x onerror="alert(1)"> hi`, []string{"hi"}, nil, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>hi</mark>"},
+		{"escapes matches", "a&b", []string{"a"}, nil, "<mark>a</mark>&amp;b"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Highlight(test.content, test.terms, test.phrases); got != test.want {
+				t.Fatalf("Highlight() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHighlightWindow(t *testing.T) {
+	content := strings.Repeat("é", 100) + " needle " + strings.Repeat("x", 200)
+	got := Highlight(content, []string{"needle"}, nil)
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Fatalf("snippet is not cut around the match: %q", got)
+	}
+	if !strings.Contains(got, "<mark>needle</mark>") {
+		t.Fatalf("match not highlighted: %q", got)
+	}
+	if !strings.HasPrefix(strings.TrimPrefix(got, "…"), "é") {
+		t.Fatalf("snippet not cut on a rune boundary: %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"urulink.com/message_service/models"
+)
+
+// MemoryIndexCapacity is the number of messages a MemoryIndex created by the service keeps
+const MemoryIndexCapacity = 100000
+
+// MemoryIndex is an in-process index that keeps messages in memory and matches them like the
+// MySQL FULLTEXT backend: a term matches the start of a word and a phrase matches whole words.
+// It needs no external service, which makes it suitable for tests and small local deployments.
+// It holds at most capacity messages, dropping the oldest indexed one when full, and is empty
+// when created: the service fills it with the most recent stored messages at startup.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	capacity int
+	messages []models.DirectMessage
+	next     int // Slot overwritten by the next message once the index is full
+}
+
+// NewMemoryIndex creates an empty in-memory search index holding at most capacity messages
+func NewMemoryIndex(capacity int) *MemoryIndex {
+	return &MemoryIndex{capacity: capacity}
+}
+
+// Index adds a message to the in-memory index, replacing the oldest one when the index is full
+func (mi *MemoryIndex) Index(ctx context.Context, msg models.DirectMessage) error {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+	if len(mi.messages) < mi.capacity {
+		mi.messages = append(mi.messages, msg)
+		return nil
+	}
+	if mi.capacity > 0 {
+		mi.messages[mi.next] = msg
+		mi.next = (mi.next + 1) % mi.capacity
+	}
+	return nil
+}
+
+// Search returns the indexed messages containing every term and phrase, most recent first
+func (mi *MemoryIndex) Search(ctx context.Context, query Query) ([]models.SearchHit, error) {
+	terms, phrases := ParseText(query.Text)
+
+	mi.mu.RLock()
+	var matches []models.DirectMessage
+	for _, msg := range mi.messages {
+		if scopeMatches(msg, query) && matchesAll(msg.Content, terms, phrases) {
+			matches = append(matches, msg)
+		}
+	}
+	mi.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].CreatedAt > matches[j].CreatedAt
+	})
+	if limit := limitOrDefault(query.Limit); len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	hits := make([]models.SearchHit, 0, len(matches))
+	for _, msg := range matches {
+		hits = append(hits, models.SearchHit{Message: msg, Snippet: Highlight(msg.Content, terms, phrases)})
+	}
+	return hits, nil
+}
+
+// matchesAll reports whether content matches every term and phrase, ignoring case.
+// Terms are prefixes of a word and phrases are sequences of whole words, as in the boolean
+// mode query of MySQLIndex.
+func matchesAll(content string, terms, phrases []string) bool {
+	words := strings.FieldsFunc(strings.ToLower(content), isSeparator)
+	joined := " " + strings.Join(words, " ") + " "
+	for _, phrase := range phrases {
+		if !strings.Contains(joined, " "+phrase+" ") {
+			return false
+		}
+	}
+	for _, term := range terms {
+		if !hasWordWithPrefix(words, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasWordWithPrefix reports whether one of the words starts with prefix
+func hasWordWithPrefix(words []string, prefix string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"urulink.com/message_service/models"
+)
+
+func newTestIndex(t *testing.T) *MemoryIndex {
+	index := NewMemoryIndex(MemoryIndexCapacity)
+	messages := []models.DirectMessage{
+		{Id: 1, SenderID: "alice", ReceiverID: "bob", Content: "See you at the station", ContentType: "text", CreatedAt: 100},
+		{Id: 2, SenderID: "bob", ReceiverID: "alice", Content: "Stationary shop is closed", ContentType: "text", CreatedAt: 200},
+		{Id: 3, SenderID: "carol", ReceiverID: "alice", Content: "station at noon", ContentType: "text", CreatedAt: 300},
+		{Id: 4, SenderID: "carol", ReceiverID: "dave", Content: "the station", ContentType: "text", CreatedAt: 400},
+		{Id: 5, SenderID: "alice", ReceiverID: "bob", Content: "the nation", ContentType: "text", CreatedAt: 500},
+	}
+	for _, msg := range messages {
+		if err := index.Index(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return index
+}
+
+func hitIds(hits []models.SearchHit) []int64 {
+	ids := []int64{}
+	for _, hit := range hits {
+		ids = append(ids, hit.Message.Id)
+	}
+	return ids
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	index := newTestIndex(t)
+	tests := []struct {
+		name  string
+		query Query
+		want  []int64
+	}{
+		{"prefix match, most recent first", Query{UserId: "alice", Text: "station"}, []int64{3, 2, 1}},
+		{"no infix match", Query{UserId: "alice", Text: "ation"}, []int64{}},
+		{"phrase of whole words", Query{UserId: "alice", Text: `"at the station"`}, []int64{1}},
+		{"phrase does not match a prefix", Query{UserId: "alice", Text: `"the stat"`}, []int64{}},
+		{"every term required", Query{UserId: "alice", Text: "station noon"}, []int64{3}},
+		{"scoped to the user", Query{UserId: "dave", Text: "station"}, []int64{4}},
+		{"peer filter", Query{UserId: "alice", Text: "station", PeerId: "bob"}, []int64{2, 1}},
+		{"sender filter", Query{UserId: "alice", Text: "station", SenderId: "bob"}, []int64{2}},
+		{"time range", Query{UserId: "alice", Text: "station", From: 150, To: 250}, []int64{2}},
+		{"limit", Query{UserId: "alice", Text: "station", Limit: 1}, []int64{3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits, err := index.Search(context.Background(), test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := hitIds(hits)
+			if len(got) != len(test.want) {
+				t.Fatalf("got hits %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got hits %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryIndexSnippet(t *testing.T) {
+	index := NewMemoryIndex(MemoryIndexCapacity)
+	msg := models.DirectMessage{Id: 1, SenderID: "alice", ReceiverID: "bob", Content: "<b>station</b>"}
+	if err := index.Index(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	hits, err := index.Search(context.Background(), Query{UserId: "alice", Text: "station"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Snippet != "&lt;b&gt;<mark>station</mark>&lt;/b&gt;" {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+}
+
+func TestMemoryIndexCapacity(t *testing.T) {
+	index := NewMemoryIndex(3)
+	for id := int64(1); id <= 5; id++ {
+		msg := models.DirectMessage{Id: id, SenderID: "alice", ReceiverID: "bob", Content: "station", CreatedAt: id * 100}
+		if err := index.Index(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The two oldest messages were dropped to make room for the newest ones
+	hits, err := index.Search(context.Background(), Query{UserId: "alice", Text: "station"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hitIds(hits); !reflect.DeepEqual(got, []int64{5, 4, 3}) {
+		t.Errorf("hits = %v, want [5 4 3]", got)
+	}
+}
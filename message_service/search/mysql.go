@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+	"urulink.com/message_service/models"
+)
+
+// MySQLIndex searches direct_message through its FULLTEXT index on content.
+// MySQL maintains the index on insert, so Index has nothing to do.
+type MySQLIndex struct {
+	Db *gorm.DB
+}
+
+// NewMySQLIndex creates a search index backed by the message database
+func NewMySQLIndex(db *gorm.DB) *MySQLIndex {
+	return &MySQLIndex{Db: db}
+}
+
+// Index is a no-op: the FULLTEXT index is updated by MySQL itself
+func (mi *MySQLIndex) Index(ctx context.Context, msg models.DirectMessage) error {
+	return nil
+}
+
+// Search runs a boolean-mode FULLTEXT query in which every term and phrase is required
+func (mi *MySQLIndex) Search(ctx context.Context, query Query) ([]models.SearchHit, error) {
+	terms, phrases := ParseText(query.Text)
+	sql, args := searchStatement(query, terms, phrases)
+
+	var messages []models.DirectMessage
+	if err := mi.Db.WithContext(ctx).Table("direct_message").Raw(sql, args...).Scan(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	hits := make([]models.SearchHit, 0, len(messages))
+	for _, msg := range messages {
+		hits = append(hits, models.SearchHit{Message: msg, Snippet: Highlight(msg.Content, terms, phrases)})
+	}
+	return hits, nil
+}
+
+// searchStatement builds the SQL and arguments selecting the messages matching a query
+func searchStatement(query Query, terms, phrases []string) (string, []interface{}) {
+	sql := "SELECT * FROM direct_message WHERE (sender_id = ? OR receiver_id = ?)"
+	args := []interface{}{query.UserId, query.UserId}
+
+	if against := booleanQuery(terms, phrases); against != "" {
+		sql += " AND MATCH(content) AGAINST(? IN BOOLEAN MODE)"
+		args = append(args, against)
+	}
+	if query.PeerId != "" {
+		sql += " AND (sender_id = ? OR receiver_id = ?)"
+		args = append(args, query.PeerId, query.PeerId)
+	}
+	if query.SenderId != "" {
+		sql += " AND sender_id = ?"
+		args = append(args, query.SenderId)
+	}
+	if query.ContentType != "" {
+		sql += " AND content_type = ?"
+		args = append(args, query.ContentType)
+	}
+	if query.From != 0 {
+		sql += " AND created_at >= ?"
+		args = append(args, query.From)
+	}
+	if query.To != 0 {
+		sql += " AND created_at <= ?"
+		args = append(args, query.To)
+	}
+	sql += " ORDER BY created_at DESC LIMIT ?"
+	args = append(args, limitOrDefault(query.Limit))
+	return sql, args
+}
+
+// booleanQuery builds the AGAINST expression: +term* for words (prefix match) and +"..." for phrases.
+// ParseText only keeps letters and digits, so no boolean operator can leak in from user input.
+func booleanQuery(terms, phrases []string) string {
+	var parts []string
+	for _, phrase := range phrases {
+		parts = append(parts, "+\""+phrase+"\"")
+	}
+	for _, term := range terms {
+		parts = append(parts, "+"+term+"*")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBooleanQuery(t *testing.T) {
+	terms, phrases := ParseText(`hello "big world" +x-y*`)
+	if got, want := booleanQuery(terms, phrases), `+"big world" +hello* +x* +y*`; got != want {
+		t.Fatalf("booleanQuery() = %q, want %q", got, want)
+	}
+	if got := booleanQuery(nil, nil); got != "" {
+		t.Fatalf("booleanQuery() of empty text = %q", got)
+	}
+}
+
+func TestSearchStatement(t *testing.T) {
+	query := Query{UserId: "alice", Text: "station", PeerId: "bob", SenderId: "bob", ContentType: "text", From: 1, To: 2, Limit: 500}
+	terms, phrases := ParseText(query.Text)
+	sql, args := searchStatement(query, terms, phrases)
+
+	wantSql := "SELECT * FROM direct_message WHERE (sender_id = ? OR receiver_id = ?)" +
+		" AND MATCH(content) AGAINST(? IN BOOLEAN MODE) AND (sender_id = ? OR receiver_id = ?)" +
+		" AND sender_id = ? AND content_type = ? AND created_at >= ? AND created_at <= ?" +
+		" ORDER BY created_at DESC LIMIT ?"
+	wantArgs := []interface{}{"alice", "alice", "+station*", "bob", "bob", "bob", "text", int64(1), int64(2), MaxLimit}
+	if sql != wantSql {
+		t.Errorf("sql = %q, want %q", sql, wantSql)
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+
+	sql, args = searchStatement(Query{UserId: "alice"}, nil, nil)
+	if sql != "SELECT * FROM direct_message WHERE (sender_id = ? OR receiver_id = ?) ORDER BY created_at DESC LIMIT ?" ||
+		!reflect.DeepEqual(args, []interface{}{"alice", "alice", DefaultLimit}) {
+		t.Errorf("unexpected statement without filters: %q %v", sql, args)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"urulink.com/message_service/models"
+)
+
+// DefaultLimit is the number of hits returned when a query does not set a limit
+const DefaultLimit = 50
+
+// MaxLimit caps the number of hits a single query can return
+const MaxLimit = 200
+
+// Query describes a message search. It is always scoped to conversations of UserId.
+type Query struct {
+	UserId      string // User performing the search; only their conversations are searched
+	Text        string // Free text, "quoted parts" are matched as phrases
+	PeerId      string // Optional: restrict the search to the conversation with this peer
+	SenderId    string // Optional: only messages sent by this user
+	ContentType string // Optional: only messages of this content type
+	From        int64  // Optional: only messages created at or after this unix time
+	To          int64  // Optional: only messages created at or before this unix time
+	Limit       int    // Maximum number of hits
+}
+
+// SearchIndex is implemented by the full-text backends able to answer message searches.
+type SearchIndex interface {
+	// Index makes a stored message searchable
+	Index(ctx context.Context, msg models.DirectMessage) error
+	// Search returns the messages matching the query, most recent first
+	Search(ctx context.Context, query Query) ([]models.SearchHit, error)
+}
+
+// ParseText splits search text into single terms and "quoted" phrases, lower-cased.
+func ParseText(text string) (terms []string, phrases []string) {
+	parts := strings.Split(text, "\"")
+	for i, part := range parts {
+		// Odd parts sit between quotes
+		if i%2 == 1 {
+			if phrase := strings.Join(strings.FieldsFunc(strings.ToLower(part), isSeparator), " "); phrase != "" {
+				phrases = append(phrases, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.FieldsFunc(strings.ToLower(part), isSeparator)...)
+	}
+	return terms, phrases
+}
+
+// isSeparator reports whether a rune separates words in search text
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_'
+}
+
+// scopeMatches reports whether a message satisfies the conversation scope and filters of a query
+func scopeMatches(msg models.DirectMessage, query Query) bool {
+	if msg.SenderID != query.UserId && msg.ReceiverID != query.UserId {
+		return false
+	}
+	if query.PeerId != "" && msg.SenderID != query.PeerId && msg.ReceiverID != query.PeerId {
+		return false
+	}
+	if query.SenderId != "" && msg.SenderID != query.SenderId {
+		return false
+	}
+	if query.ContentType != "" && msg.ContentType != query.ContentType {
+		return false
+	}
+	if query.From != 0 && msg.CreatedAt < query.From {
+		return false
+	}
+	if query.To != 0 && msg.CreatedAt > query.To {
+		return false
+	}
+	return true
+}
+
+// limitOrDefault clamps the query limit to [1, MaxLimit]
+func limitOrDefault(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
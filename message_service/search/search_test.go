@@ -0,0 +1,52 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	tests := []struct {
+		text    string
+		terms   []string
+		phrases []string
+	}{
+		{"Hello World", []string{"hello", "world"}, nil},
+		{`meet "at the  Station" now`, []string{"meet", "now"}, []string{"at the station"}},
+		{`+foo* -bar "baz`, []string{"foo", "bar"}, []string{"baz"}},
+		{`"" ()`, nil, nil},
+	}
+	for _, test := range tests {
+		terms, phrases := ParseText(test.text)
+		if !reflect.DeepEqual(terms, test.terms) || !reflect.DeepEqual(phrases, test.phrases) {
+			t.Errorf("ParseText(%q) = %q, %q, want %q, %q", test.text, terms, phrases, test.terms, test.phrases)
+		}
+	}
+}
+
+func TestLimitOrDefault(t *testing.T) {
+	for limit, want := range map[int]int{0: DefaultLimit, -1: DefaultLimit, 10: 10, MaxLimit + 1: MaxLimit} {
+		if got := limitOrDefault(limit); got != want {
+			t.Errorf("limitOrDefault(%d) = %d, want %d", limit, got, want)
+		}
+	}
+}
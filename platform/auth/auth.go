@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/platform/httpclient"
+)
+
+// UserKey is the key of the User in the locals of authenticated requests
+const UserKey = "userJwtInfo"
+
+// User is the account an access token belongs to, as reported by the auth service
+type User struct {
+	Uid      string `json:"uid"`
+	Username string `json:"username"`
+}
+
+// CheckLogin asks the auth service to validate an access token and returns the user it belongs to
+func CheckLogin(authUrl, accessToken string) (User, error) {
+	statusCode, body, err := httpclient.Call(fiber.MethodPost, authUrl+"/check-login", accessToken, nil)
+	if err != nil {
+		return User{}, err
+	}
+	if statusCode != 200 {
+		return User{}, fmt.Errorf("check-login failed with status %d", statusCode)
+	}
+	return httpclient.Decode[User](body)
+}
+
+// HttpAuth is a middleware function that checks the authorization of incoming requests and
+// stores the user in the context for later use
+func HttpAuth(authUrl string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := CheckLogin(authUrl, c.Get(fiber.HeaderAuthorization))
+		if err != nil {
+			return c.Status(401).SendString("Unauthorized requests")
+		}
+		c.Locals(UserKey, user)
+		return c.Next()
+	}
+}
+
+// ServiceAuth admits internal calls of the other urulink services, which carry the shared
+// secret token instead of a user login. All calls are refused when token is empty.
+func ServiceAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		presented := c.Get(httpclient.ServiceTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			return c.Status(401).SendString("Unauthorized requests")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"urulink.com/platform/httpclient"
+)
+
+// newAuthService fakes the check-login endpoint of the auth service, accepting only the "valid" token
+func newAuthService(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/check-login" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Header.Get(fiber.HeaderAuthorization) {
+		case "valid":
+			io.WriteString(w, `{"uid":"42","username":"mustafa"}`)
+		case "garbled":
+			io.WriteString(w, `{"uid":`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckLogin(t *testing.T) {
+	server := newAuthService(t)
+
+	user, err := CheckLogin(server.URL, "valid")
+	if err != nil {
+		t.Fatalf("CheckLogin(valid) error: %v", err)
+	}
+	if user != (User{Uid: "42", Username: "mustafa"}) {
+		t.Fatalf("CheckLogin(valid) = %+v", user)
+	}
+
+	for _, token := range []string{"", "expired", "garbled"} {
+		if _, err := CheckLogin(server.URL, token); err == nil {
+			t.Errorf("CheckLogin(%q) succeeded", token)
+		}
+	}
+
+	// An unreachable auth service fails the login instead of admitting the request
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	if _, err := CheckLogin(unreachable.URL, "valid"); err == nil {
+		t.Error("CheckLogin succeeded with the auth service down")
+	}
+}
+
+func TestHttpAuth(t *testing.T) {
+	server := newAuthService(t)
+	app := fiber.New()
+	app.Get("/me", HttpAuth(server.URL), func(c *fiber.Ctx) error {
+		user, ok := c.Locals(UserKey).(User)
+		if !ok {
+			return c.SendStatus(500)
+		}
+		return c.SendString(user.Uid + ":" + user.Username)
+	})
+
+	tests := []struct {
+		token  string
+		status int
+		body   string
+	}{
+		{"valid", 200, "42:mustafa"},
+		{"expired", 401, "Unauthorized requests"},
+		{"garbled", 401, "Unauthorized requests"},
+		{"", 401, "Unauthorized requests"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodGet, "/me", nil)
+		if tt.token != "" {
+			req.Header.Set(fiber.HeaderAuthorization, tt.token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("token %q: %v", tt.token, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("token %q: got %d %q, want %d %q", tt.token, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+}
+
+func TestServiceAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		presented string
+		status    int
+	}{
+		{"matching token", "s3cret", "s3cret", 200},
+		{"wrong token", "s3cret", "s3cre", 401},
+		{"missing token", "s3cret", "", 401},
+		{"unconfigured secret", "", "", 401},
+		{"unconfigured secret with token", "", "anything", 401},
+	}
+	for _, tt := range tests {
+		app := fiber.New()
+		app.Get("/internal", ServiceAuth(tt.secret), func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+		req := httptest.NewRequest(fiber.MethodGet, "/internal", nil)
+		if tt.presented != "" {
+			req.Header.Set(httpclient.ServiceTokenHeader, tt.presented)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+}
@@ -17,11 +17,28 @@
  * along with urulink. If not, see <http://www.gnu.org/licenses/>.
  */
 
-package response
+package config
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"log"
+	"os"
+)
 
-func HandleInformation(c *fiber.Ctx, statusCode int, data interface{}) error {
-	c.Status(statusCode)
-	return c.JSON(data)
+// Required loads an environment variable into target. If it is missing, it logs a fatal error.
+func Required(envVar string, target *string) {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		log.Fatalf("Environment variable %s not found", envVar) // Log fatal error if variable is missing
+	}
+	*target = value
+}
+
+// Optional loads an environment variable into target, falling back to a default value when it is
+// missing or empty
+func Optional(envVar string, target *string, defaultValue string) {
+	value, ok := os.LookupEnv(envVar)
+	if !ok || value == "" {
+		value = defaultValue
+	}
+	*target = value
 }
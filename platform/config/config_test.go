@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRequired(t *testing.T) {
+	t.Setenv("URULINK_TEST_REQUIRED", "value")
+	var target string
+	Required("URULINK_TEST_REQUIRED", &target)
+	if target != "value" {
+		t.Errorf("Required loaded %q", target)
+	}
+
+	// A variable that is set but empty is still present
+	t.Setenv("URULINK_TEST_REQUIRED", "")
+	target = "previous"
+	Required("URULINK_TEST_REQUIRED", &target)
+	if target != "" {
+		t.Errorf("Required loaded %q for an empty variable", target)
+	}
+}
+
+func TestRequiredMissing(t *testing.T) {
+	// Required exits the process, so it runs in a child copy of the test binary
+	if os.Getenv("URULINK_TEST_CHILD") == "1" {
+		var target string
+		Required("URULINK_TEST_MISSING", &target)
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRequiredMissing$")
+	cmd.Env = append(os.Environ(), "URULINK_TEST_CHILD=1")
+	output, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("Required did not exit on a missing variable: %v", err)
+	}
+	if !strings.Contains(string(output), "Environment variable URULINK_TEST_MISSING not found") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestOptional(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   bool
+		value string
+		want  string
+	}{
+		{"set", true, "value", "value"},
+		{"empty", true, "", "default"},
+		{"missing", false, "", "default"},
+	}
+	for _, tt := range tests {
+		if tt.set {
+			t.Setenv("URULINK_TEST_OPTIONAL", tt.value)
+		} else {
+			os.Unsetenv("URULINK_TEST_OPTIONAL")
+		}
+		var target string
+		Optional("URULINK_TEST_OPTIONAL", &target, "default")
+		if target != tt.want {
+			t.Errorf("%s: Optional loaded %q, want %q", tt.name, target, tt.want)
+		}
+	}
+}
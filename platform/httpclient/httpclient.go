@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ServiceTokenHeader carries the secret shared by the urulink services on calls made without a user
+const ServiceTokenHeader = "X-Service-Token"
+
+var client = &fiber.Client{}
+
+// Do sends an HTTP request with the given headers and, unless body is nil, a JSON body.
+// It returns the status code and body of the response.
+func Do(method, url string, headers map[string]string, body any) (int, []byte, error) {
+	var agent *fiber.Agent
+	switch method {
+	case fiber.MethodGet:
+		agent = client.Get(url)
+	case fiber.MethodPost:
+		agent = client.Post(url)
+	case fiber.MethodPut:
+		agent = client.Put(url)
+	case fiber.MethodPatch:
+		agent = client.Patch(url)
+	case fiber.MethodDelete:
+		agent = client.Delete(url)
+	default:
+		return 0, nil, fmt.Errorf("http method %s not allowed", method)
+	}
+
+	for key, value := range headers {
+		agent.Set(key, value)
+	}
+	if body != nil {
+		agent.JSON(body)
+	}
+	statusCode, responseBody, errs := agent.Bytes()
+	if len(errs) > 0 {
+		return 0, nil, fmt.Errorf("%s %s failed: %w", method, url, errors.Join(errs...))
+	}
+	return statusCode, responseBody, nil
+}
+
+// Call sends an HTTP request on behalf of a user, forwarding their access token as the
+// authorization header
+func Call(method, url, accessToken string, body any) (int, []byte, error) {
+	return Do(method, url, map[string]string{fiber.HeaderAuthorization: accessToken}, body)
+}
+
+// Decode unmarshals a JSON response body into the specified model type
+func Decode[t any](body []byte) (t, error) {
+	var model t
+	if err := json.Unmarshal(body, &model); err != nil {
+		return model, fmt.Errorf("failed to Unmarshal response: %v", err)
+	}
+	return model, nil
+}
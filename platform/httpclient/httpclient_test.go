@@ -0,0 +1,153 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package httpclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// request is what the echo server saw of a request
+type request struct {
+	Method        string `json:"method"`
+	Authorization string `json:"authorization"`
+	Token         string `json:"token"`
+	ContentType   string `json:"contentType"`
+	Body          string `json:"body"`
+}
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		json.NewEncoder(w).Encode(request{
+			Method:        r.Method,
+			Authorization: r.Header.Get(fiber.HeaderAuthorization),
+			Token:         r.Header.Get(ServiceTokenHeader),
+			ContentType:   r.Header.Get(fiber.HeaderContentType),
+			Body:          string(body),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDo(t *testing.T) {
+	server := newEchoServer(t)
+	methods := []string{fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete}
+	for _, method := range methods {
+		var body any
+		if method != fiber.MethodGet {
+			body = map[string]string{"name": "urulink"}
+		}
+		status, responseBody, err := Do(method, server.URL, map[string]string{ServiceTokenHeader: "s3cret"}, body)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if status != 200 {
+			t.Errorf("%s: status %d", method, status)
+		}
+		seen, err := Decode[request](responseBody)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if seen.Method != method || seen.Token != "s3cret" {
+			t.Errorf("%s: server saw %+v", method, seen)
+		}
+		if body == nil {
+			if seen.Body != "" {
+				t.Errorf("%s: sent body %q without one", method, seen.Body)
+			}
+		} else if seen.Body != `{"name":"urulink"}` || !strings.HasPrefix(seen.ContentType, fiber.MIMEApplicationJSON) {
+			t.Errorf("%s: sent body %q as %q", method, seen.Body, seen.ContentType)
+		}
+	}
+}
+
+func TestDoStatus(t *testing.T) {
+	server := newEchoServer(t)
+	status, _, err := Do(fiber.MethodGet, server.URL+"/missing", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 404 {
+		t.Errorf("status %d, want 404", status)
+	}
+}
+
+func TestDoErrors(t *testing.T) {
+	if _, _, err := Do("TRACE", "http://127.0.0.1", nil, nil); err == nil {
+		t.Error("Do accepted TRACE")
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	status, body, err := Do(fiber.MethodGet, server.URL, nil, nil)
+	if err == nil {
+		t.Fatal("Do succeeded against a closed server")
+	}
+	if status != 0 || body != nil {
+		t.Errorf("failed Do returned %d %q", status, body)
+	}
+}
+
+func TestCall(t *testing.T) {
+	server := newEchoServer(t)
+	_, responseBody, err := Call(fiber.MethodPost, server.URL, "Bearer abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen, err := Decode[request](responseBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen.Authorization != "Bearer abc" || seen.Token != "" {
+		t.Errorf("server saw %+v", seen)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	type user struct {
+		Uid string `json:"uid"`
+	}
+	got, err := Decode[user]([]byte(`{"uid":"42","extra":true}`))
+	if err != nil || got.Uid != "42" {
+		t.Errorf("Decode = %+v, %v", got, err)
+	}
+
+	list, err := Decode[[]int]([]byte(`[1,2,3]`))
+	if err != nil || len(list) != 3 {
+		t.Errorf("Decode list = %v, %v", list, err)
+	}
+
+	for _, body := range []string{"", "{", `{"uid":42}`, "null-ish"} {
+		if _, err := Decode[user]([]byte(body)); err == nil {
+			t.Errorf("Decode(%q) succeeded", body)
+		}
+	}
+}
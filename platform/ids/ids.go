@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ids
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// Alphabets of generated IDs
+const (
+	Digits       = "0123456789"
+	Alphanumeric = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// New generates a random ID of length characters of alphabet (at most 256 characters long). The
+// characters are drawn from a cryptographic source, so IDs generated at the same time differ.
+func New(alphabet string, length int) string {
+	// Bytes at or above limit are dropped so every character is equally likely
+	limit := 256 - 256%len(alphabet)
+	id := make([]byte, 0, length)
+	buffer := make([]byte, length)
+	for len(id) < length {
+		if _, err := rand.Read(buffer); err != nil {
+			panic("failed to read random bytes: " + err.Error())
+		}
+		for _, b := range buffer {
+			if int(b) < limit && len(id) < length {
+				id = append(id, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+	return string(id)
+}
+
+// Valid reports whether id is made of length characters of alphabet
+func Valid(id, alphabet string, length int) bool {
+	return len(id) == length && strings.Trim(id, alphabet) == ""
+}
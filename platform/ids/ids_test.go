@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ids
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		length   int
+	}{
+		{Digits, 6},
+		{Alphanumeric, 32},
+		{"ab", 100},
+		{Alphanumeric, 0},
+	}
+	for _, tt := range tests {
+		id := New(tt.alphabet, tt.length)
+		if len(id) != tt.length {
+			t.Errorf("New(%q, %d) has length %d", tt.alphabet, tt.length, len(id))
+		}
+		if strings.Trim(id, tt.alphabet) != "" {
+			t.Errorf("New(%q, %d) = %q has characters outside the alphabet", tt.alphabet, tt.length, id)
+		}
+		if !Valid(id, tt.alphabet, tt.length) {
+			t.Errorf("Valid rejects New(%q, %d) = %q", tt.alphabet, tt.length, id)
+		}
+	}
+}
+
+func TestNewUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := New(Alphanumeric, 16)
+		if seen[id] {
+			t.Fatalf("New generated %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewDistribution(t *testing.T) {
+	// Every digit shows up roughly equally often; 256%10 != 0 so a biased draw would favour 0-5
+	counts := make(map[rune]int)
+	const n = 100000
+	for _, r := range New(Digits, n) {
+		counts[r]++
+	}
+	for _, r := range Digits {
+		if counts[r] < n/10*9/10 || counts[r] > n/10*11/10 {
+			t.Errorf("digit %c drawn %d times out of %d", r, counts[r], n)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id       string
+		alphabet string
+		length   int
+		want     bool
+	}{
+		{"123456", Digits, 6, true},
+		{"12345", Digits, 6, false},
+		{"1234567", Digits, 6, false},
+		{"12345a", Digits, 6, false},
+		{"abc123", Alphanumeric, 6, true},
+		{"ABC123", Alphanumeric, 6, false},
+		{"abc-12", Alphanumeric, 6, false},
+		{"", Digits, 0, true},
+		{"", Digits, 6, false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.id, tt.alphabet, tt.length); got != tt.want {
+			t.Errorf("Valid(%q, %q, %d) = %v, want %v", tt.id, tt.alphabet, tt.length, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package logs
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Setup writes the log to a file, in addition to stdout
+func Setup(path string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetOutput(io.MultiWriter(f, os.Stdout)) // Log to both file and stdout
+}
+
+// Error logs an error with structured information for Fiber context
+func Error(c *fiber.Ctx, message string, err error) {
+	log.Printf("[ERROR] %s: %s%s", message, requestData(c), errorData(err))
+}
+
+// Info logs an informational message with structured data for Fiber context
+func Info(c *fiber.Ctx, message string, data map[string]interface{}) {
+	log.Printf("[INFO] %s: %s%s", message, requestData(c), fieldData(data))
+}
+
+// ConnError logs an error of a client connection, such as a WebSocket; remote is nil for errors
+// that happen outside of one
+func ConnError(remote net.Addr, message string, err error) {
+	logData := ""
+	if remote != nil {
+		logData = fmt.Sprintf("clientIp: %s", remote.String())
+	}
+	log.Printf("[ERROR] %s: %s%s", message, logData, errorData(err))
+}
+
+// Event logs an informational message with structured data outside of a request
+func Event(message string, data map[string]interface{}) {
+	log.Printf("[INFO] %s: %s", message, fieldData(data))
+}
+
+func requestData(c *fiber.Ctx) string {
+	return fmt.Sprintf("path: %s, method: %s, clientIp: %s, query: %s", c.Path(), c.Method(), c.IP(), c.OriginalURL())
+}
+
+func errorData(err error) string {
+	if err != nil {
+		return fmt.Sprintf(", error: %v", err)
+	}
+	return ", error: No error information"
+}
+
+func fieldData(data map[string]interface{}) string {
+	logData := ""
+	for k, v := range data {
+		logData += fmt.Sprintf(", %s: %v", k, v)
+	}
+	return logData
+}
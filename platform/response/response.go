@@ -19,12 +19,14 @@
 
 package response
 
-import (
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/websocket/v2"
-)
+import "github.com/gofiber/fiber/v2"
 
-func HandleWebSocketError(c *websocket.Conn, errMsg string) error {
+// JSONWriter is a connection JSON messages are written to, such as a WebSocket connection
+type JSONWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+func HandleWebSocketError(c JSONWriter, errMsg string) error {
 	return c.WriteJSON(fiber.Map{
 		"error": errMsg,
 	})
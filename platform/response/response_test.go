@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2024 Mustafa Naseer
+ *
+ * This file is part of urulink chat application.
+ *
+ * urulink is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation version 3 of the License .
+ *
+ *
+ * urulink is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with urulink. If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func get(t *testing.T, handler fiber.Handler) (int, string, string) {
+	app := fiber.New()
+	app.Get("/", handler)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), string(body)
+}
+
+func TestHandleError(t *testing.T) {
+	status, contentType, body := get(t, func(c *fiber.Ctx) error {
+		return HandleError(c, 404, "File not found")
+	})
+	if status != 404 || contentType != fiber.MIMEApplicationJSON || body != `{"error":"File not found"}` {
+		t.Errorf("got %d %q %s", status, contentType, body)
+	}
+}
+
+func TestHandleInformation(t *testing.T) {
+	status, contentType, body := get(t, func(c *fiber.Ctx) error {
+		return HandleInformation(c, 201, fiber.Map{"id": "abc", "size": 3})
+	})
+	if status != 201 || contentType != fiber.MIMEApplicationJSON {
+		t.Fatalf("got %d %q", status, contentType)
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["id"] != "abc" || data["size"] != float64(3) {
+		t.Errorf("body %s", body)
+	}
+}
+
+// recorder is a JSONWriter that keeps what was written to it
+type recorder struct {
+	written []any
+	err     error
+}
+
+func (r *recorder) WriteJSON(v interface{}) error {
+	r.written = append(r.written, v)
+	return r.err
+}
+
+func TestHandleWebSocketError(t *testing.T) {
+	conn := &recorder{}
+	if err := HandleWebSocketError(conn, "Invalid message"); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.written) != 1 {
+		t.Fatalf("wrote %d messages", len(conn.written))
+	}
+	message, _ := json.Marshal(conn.written[0])
+	if string(message) != `{"error":"Invalid message"}` {
+		t.Errorf("wrote %s", message)
+	}
+
+	// A failed write is reported to the caller
+	conn = &recorder{err: errors.New("connection closed")}
+	if err := HandleWebSocketError(conn, "Invalid message"); err == nil {
+		t.Error("write error was dropped")
+	}
+}